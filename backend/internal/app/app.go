@@ -2,22 +2,47 @@ package app
 
 import (
 	"TextVault/internal/config"
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/lib/passwordpolicy"
+	"TextVault/internal/lib/secretscan"
+	"TextVault/internal/lib/sharelink"
+	"TextVault/internal/mailer"
+	"TextVault/internal/metrics"
+	"TextVault/internal/middleware"
 	"TextVault/internal/router"
 	"TextVault/internal/storage/postgres"
 	"TextVault/internal/storage/redis"
 	"TextVault/internal/storage/s3"
+	"TextVault/internal/tracing"
+	"TextVault/pkg/passwordhash"
 	"context"
 	"log/slog"
+	"time"
 )
 
 type App struct {
 	Router *router.Router
 	log    *slog.Logger
+
+	postgres *postgres.Storage
+	redis    *redis.Storage
+	s3       *s3.Storage
+
+	shutdownTimeout time.Duration
+	shutdownTracing func(context.Context) error
 }
 
+// tracingShutdownTimeout bounds how long Close waits for the remaining spans to be exported.
+const tracingShutdownTimeout = 5 * time.Second
+
 func New(log *slog.Logger, cfg *config.Config) (*App, error) {
 	ctx := context.Background()
 
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+
 	s3Storage, err := s3.New(log, cfg.S3)
 	if err != nil {
 		return nil, err
@@ -48,9 +73,109 @@ func New(log *slog.Logger, cfg *config.Config) (*App, error) {
 
 	log.Info("Connected to redis")
 
-	router := router.New(storage, redisStorage, s3Storage, log)
+	mailer, err := mailer.New(log, &cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	hashParams := passwordhash.DefaultParams
+	hashParams.Memory = cfg.Password.HashMemory
+	hashParams.Iterations = cfg.Password.HashIterations
+	hashParams.Parallelism = cfg.Password.HashParallelism
+	passwordhash.SetDefault(passwordhash.NewHasher(hashParams))
+
+	passwordPolicy, err := passwordpolicy.New(&cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	secretPolicy, err := secretscan.NewPolicy(&cfg.SecretScan)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimiter, err := middleware.NewRateLimiter(log, redisStorage, &cfg.RateLimit, sharelink.NewSigner(cfg.ShareLinks.SigningKey))
+	if err != nil {
+		return nil, err
+	}
+
+	poolCollector := metrics.NewPoolCollector(storage.Stat)
+	metrics := metrics.New()
+	metrics.Register(poolCollector)
+
+	router := router.New(storage, redisStorage, s3Storage, mailer, passwordPolicy, secretPolicy, rateLimiter, metrics, cfg, log)
 	return &App{
-		Router: router,
-		log:    log,
+		Router:          router,
+		log:             log,
+		postgres:        storage,
+		redis:           redisStorage,
+		s3:              s3Storage,
+		shutdownTimeout: cfg.HTTP.ShutdownTimeout,
+		shutdownTracing: shutdownTracing,
 	}, nil
 }
+
+// Run serves HTTP requests until ctx is done, e.g. on SIGTERM. Then it stops accepting connections,
+// gives in-flight requests the configured shutdown timeout to finish and closes the storages.
+// It returns an error if the server fails or doesn't drain in time.
+func (a *App) Run(ctx context.Context) error {
+	const prefix = "internal.app.Run"
+	log := a.log.With(
+		slog.String("op", prefix),
+	)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- a.Router.Run()
+	}()
+
+	select {
+	case err := <-serveErr:
+		a.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info("Shutting down", slog.Duration("timeout", a.shutdownTimeout))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+
+	shutdownErr := a.Router.Shutdown(shutdownCtx)
+	if shutdownErr != nil {
+		log.Error("Failed to drain in-flight requests", sl.Err(shutdownErr))
+	}
+
+	if err := <-serveErr; err != nil {
+		log.Error("HTTP server failed", sl.Err(err))
+	}
+
+	// @NOTE: Storages are closed only after the server stopped, so no request can use a closed client
+	a.Close()
+
+	log.Info("Shutdown complete")
+
+	return shutdownErr
+}
+
+// Close closes the storages: first Postgres, then Redis, then S3. Finally it exports the remaining spans.
+func (a *App) Close() {
+	a.postgres.Close()
+	a.log.Info("Closed database connections")
+
+	if err := a.redis.Close(); err != nil {
+		a.log.Error("Failed to close redis connections", sl.Err(err))
+	} else {
+		a.log.Info("Closed redis connections")
+	}
+
+	a.s3.Close()
+	a.log.Info("Closed s3 connections")
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+
+	if err := a.shutdownTracing(ctx); err != nil {
+		a.log.Error("Failed to export remaining spans", sl.Err(err))
+	}
+}
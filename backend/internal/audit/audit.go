@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage/models"
+	"context"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxUserAgentLength = 512
+
+// Store is an interface that provides a method for appending events to the audit log.
+type Store interface {
+	SaveAuditEvent(ctx context.Context, event *models.AuditEvent) error
+}
+
+// Recorder records audit events of requests.
+type Recorder struct {
+	store Store
+	log   *slog.Logger
+}
+
+func New(log *slog.Logger, store Store) *Recorder {
+	return &Recorder{
+		store: store,
+		log:   log,
+	}
+}
+
+// Record appends the event to the audit log with the client IP and user agent of the request.
+// Without an actor, the authenticated caller is the actor. The outcome defaults to success.
+// Failures are logged only, because the audited action has already been decided.
+func (r *Recorder) Record(c *fiber.Ctx, event models.AuditEvent) {
+	if event.ActorID == nil {
+		if principal, _ := middleware.GetPrincipal(c); principal != nil {
+			event.ActorID = &principal.ID
+		}
+	}
+
+	if event.Outcome == "" {
+		event.Outcome = models.AuditSuccess
+	}
+
+	event.IP = c.IP()
+	event.UserAgent = c.Get(fiber.HeaderUserAgent)
+	if len(event.UserAgent) > maxUserAgentLength {
+		event.UserAgent = event.UserAgent[:maxUserAgentLength]
+	}
+
+	if err := r.store.SaveAuditEvent(c.UserContext(), &event); err != nil {
+		sl.FromContext(c.UserContext(), r.log).Error("Failed to record audit event",
+			slog.String("action", event.Action),
+			slog.String("outcome", event.Outcome),
+			sl.Err(err),
+		)
+	}
+}
@@ -5,16 +5,97 @@ package config
 import (
 	"flag"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Env      string         `yaml:"env" env-default:"local"`
-	Postgres PostgresConfig `yaml:"postgres"`
-	S3       S3Config       `yaml:"s3"`
-	Redis    RedisConfig    `yaml:"redis"`
-	TokenKey string         `yaml:"tokenKey" env-default:"secret_key"`
+	Env        string           `yaml:"env" env-default:"local"`
+	PublicURL  string           `yaml:"publicUrl" env-default:"http://localhost:8080"`
+	HTTP       HTTPConfig       `yaml:"http"`
+	Health     HealthConfig     `yaml:"health"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	RateLimit  RateLimitConfig  `yaml:"rateLimit"`
+	Postgres   PostgresConfig   `yaml:"postgres"`
+	S3         S3Config         `yaml:"s3"`
+	Redis      RedisConfig      `yaml:"redis"`
+	Mail       MailConfig       `yaml:"mail"`
+	Login      LoginConfig      `yaml:"login"`
+	Password   PasswordConfig   `yaml:"password"`
+	ShareLinks ShareLinkConfig  `yaml:"shareLinks"`
+	Challenge  ChallengeConfig  `yaml:"challenge"`
+	SecretScan SecretScanConfig `yaml:"secretScan"`
+	Moderation ModerationConfig `yaml:"moderation"`
+	TokenKey   string           `yaml:"tokenKey" env-default:"secret_key"`
+
+	// OIDC maps provider names, as used in the login URLs, to OpenID Connect providers.
+	OIDC map[string]OIDCProviderConfig `yaml:"oidc"`
+}
+
+// HTTPConfig configures the HTTP server. BodyLimit is in bytes. TrustedProxies lists the IPs or CIDRs
+// of reverse proxies whose X-Forwarded-For header is used as the client IP; without any, the header is ignored.
+// On shutdown, in-flight requests get ShutdownTimeout to finish.
+type HTTPConfig struct {
+	Address         string        `yaml:"address" env:"HTTP_ADDRESS" env-default:":8080"`
+	ReadTimeout     time.Duration `yaml:"readTimeout" env-default:"10s"`
+	WriteTimeout    time.Duration `yaml:"writeTimeout" env-default:"10s"`
+	IdleTimeout     time.Duration `yaml:"idleTimeout" env-default:"60s"`
+	BodyLimit       int           `yaml:"bodyLimit" env-default:"4194304"`
+	TrustedProxies  []string      `yaml:"trustedProxies"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env-default:"15s"`
+}
+
+// HealthConfig configures the readiness checks. Every check is bounded by CheckTimeout and results are
+// reused for CacheTTL. With RedisOptional, a Redis outage degrades readiness instead of failing it,
+// because Redis is only used as a cache.
+type HealthConfig struct {
+	CheckTimeout  time.Duration `yaml:"checkTimeout" env-default:"2s"`
+	CacheTTL      time.Duration `yaml:"cacheTtl" env-default:"5s"`
+	RedisOptional bool          `yaml:"redisOptional" env-default:"true"`
+}
+
+// MetricsConfig configures the Prometheus endpoint. It is served on the API address, so in production
+// it should only be reachable by the scraper.
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled" env-default:"true"`
+	Path    string `yaml:"path" env-default:"/metrics"`
+}
+
+// TracingConfig configures the OpenTelemetry tracing. Spans are exported over OTLP/HTTP to Endpoint,
+// a host and port such as a local collector's, and SampleRatio of the traces started here are recorded.
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled" env-default:"false"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" env-default:"localhost:4318"`
+	Insecure    bool    `yaml:"insecure" env-default:"false"`
+	ServiceName string  `yaml:"serviceName" env-default:"textvault"`
+	SampleRatio float64 `yaml:"sampleRatio" env-default:"1"`
+}
+
+// RateLimitConfig configures the rate limits of the route groups. Clients from the Allowlist,
+// a list of IPs and CIDRs, are never limited; admins are limited like other users. With FailOpen, requests are let through while Redis is down;
+// otherwise they are rejected.
+type RateLimitConfig struct {
+	Enabled   bool                       `yaml:"enabled" env-default:"true"`
+	FailOpen  bool                       `yaml:"failOpen" env-default:"true"`
+	Allowlist []string                   `yaml:"allowlist"`
+	Groups    map[string]RateLimitPolicy `yaml:"groups"`
+}
+
+// RateLimitPolicy sets the limits of a route group for each identity type: IP for anonymous clients,
+// User for authenticated users and Token for share links. An identity type without a limit isn't limited.
+type RateLimitPolicy struct {
+	IP    RateLimit `yaml:"ip"`
+	User  RateLimit `yaml:"user"`
+	Token RateLimit `yaml:"token"`
+}
+
+// RateLimit allows Requests per Period on average and up to Burst requests at once, Requests if unset.
+type RateLimit struct {
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+	Burst    int           `yaml:"burst"`
 }
 
 type PostgresConfig struct {
@@ -36,6 +117,93 @@ type RedisConfig struct {
 	DB       int    `yaml:"db"`
 }
 
+// MailConfig selects how emails are delivered. Driver is one of "smtp", "file" or "log".
+type MailConfig struct {
+	Driver   string `yaml:"driver" env-default:"log"`
+	From     string `yaml:"from" env-default:"TextVault <no-reply@textvault.local>"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port" env-default:"25"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password" env:"MAIL_PASSWORD"`
+	FilePath string `yaml:"filePath" env-default:"mail.log"`
+}
+
+// LoginConfig configures the brute-force protection of logins. Failed attempts are counted per user, whether
+// they log in with their username or email, and per client IP within FailureWindow. Once a counter reaches
+// its threshold, further logins are locked for BaseLockout, doubling with every further failure up to MaxLockout.
+type LoginConfig struct {
+	UserMaxAttempts int           `yaml:"userMaxAttempts" env-default:"5"`
+	IPMaxAttempts   int           `yaml:"ipMaxAttempts" env-default:"20"`
+	FailureWindow   time.Duration `yaml:"failureWindow" env-default:"1h"`
+	BaseLockout     time.Duration `yaml:"baseLockout" env-default:"1m"`
+	MaxLockout      time.Duration `yaml:"maxLockout" env-default:"1h"`
+}
+
+// PasswordConfig is the policy for new passwords and the argon2id parameters they are hashed with.
+// BreachedListPath optionally points to a file of SHA-1 hashes of breached passwords that are rejected.
+// Changing the hash parameters rehashes passwords on the next successful login.
+type PasswordConfig struct {
+	MinLength        int    `yaml:"minLength" env-default:"8"`
+	MaxLength        int    `yaml:"maxLength" env-default:"72"`
+	BreachedListPath string `yaml:"breachedListPath"`
+
+	HashMemory      uint32 `yaml:"hashMemory" env-default:"65536"`
+	HashIterations  uint32 `yaml:"hashIterations" env-default:"3"`
+	HashParallelism uint8  `yaml:"hashParallelism" env-default:"2"`
+}
+
+// ShareLinkConfig configures signed share links of pastes. Changing SigningKey invalidates every
+// existing link. Links expire after DefaultTTL unless a shorter or longer one, up to MaxTTL, is requested.
+type ShareLinkConfig struct {
+	SigningKey string        `yaml:"signingKey" env:"SHARE_LINK_KEY" env-default:"secret_share_link_key"`
+	DefaultTTL time.Duration `yaml:"defaultTtl" env-default:"24h"`
+	MaxTTL     time.Duration `yaml:"maxTtl" env-default:"720h"`
+}
+
+// ChallengeConfig configures the proof of work required for anonymous pastes. Difficulty is in leading zero
+// bits: it is BaseDifficulty while at most RateThreshold anonymous pastes are created per minute, and rises
+// by one bit each time the rate doubles beyond that, up to MaxDifficulty. Challenges expire after TTL.
+type ChallengeConfig struct {
+	Enabled        bool          `yaml:"enabled" env-default:"true"`
+	SigningKey     string        `yaml:"signingKey" env:"CHALLENGE_KEY" env-default:"secret_challenge_key"`
+	TTL            time.Duration `yaml:"ttl" env-default:"5m"`
+	BaseDifficulty int           `yaml:"baseDifficulty" env-default:"16"`
+	MaxDifficulty  int           `yaml:"maxDifficulty" env-default:"24"`
+	RateThreshold  int           `yaml:"rateThreshold" env-default:"30"`
+}
+
+// SecretScanConfig configures the detection of secrets, such as API keys and private keys, in new pastes.
+// Policy is the action taken on a finding: "warn" saves the paste unchanged, "redact" replaces the secret,
+// "private" forces private visibility and "reject" refuses the paste. Rules overrides it for single rules.
+type SecretScanConfig struct {
+	Enabled bool              `yaml:"enabled" env-default:"true"`
+	Policy  string            `yaml:"policy" env-default:"warn"`
+	Rules   map[string]string `yaml:"rules"`
+}
+
+// ModerationConfig configures paste reports. Once AutoHideThreshold users or anonymous clients have reported
+// a paste, it is hidden until a moderator reviews it; 0 disables this. Moderators are notified of reports and
+// hidden pastes by email to NotifyEmails and by posting the events to Webhooks, signed with WebhookSecret.
+type ModerationConfig struct {
+	AutoHideThreshold int           `yaml:"autoHideThreshold" env-default:"5"`
+	NotifyEmails      []string      `yaml:"notifyEmails"`
+	Webhooks          []string      `yaml:"webhooks"`
+	WebhookSecret     string        `yaml:"webhookSecret" env:"MODERATION_WEBHOOK_SECRET"`
+	WebhookTimeout    time.Duration `yaml:"webhookTimeout" env-default:"5s"`
+}
+
+// OIDCProviderConfig configures an OpenID Connect provider. The provider's endpoints and keys
+// are discovered from Issuer, so any compliant provider (or a local mock server) can be used.
+type OIDCProviderConfig struct {
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"clientId"`
+	ClientSecret string   `yaml:"clientSecret"`
+	Scopes       []string `yaml:"scopes"`
+
+	// AutoProvision creates a local account on the first login of an unknown identity.
+	AutoProvision bool `yaml:"autoProvision"`
+}
+
 func MustLoad() *Config {
 	configPath := fetchConfigPath()
 	if configPath == "" {
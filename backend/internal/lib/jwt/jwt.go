@@ -2,14 +2,32 @@ package jwt
 
 import (
 	"TextVault/internal/storage/models"
+	"TextVault/pkg/random"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	signingKey    = "secret_signing_key"
+	tokenIDLength = 16
+)
+
+// Purposes of single-purpose tokens. Such tokens are never accepted as session tokens.
+const (
+	PurposeVerifyEmail = "verify_email"
+	PurposeTwoFactor   = "two_factor"
+	PurposeReauth      = "reauth"
+)
+
+// UserClaims are the claims of a user token. Version is compared with the user's token version,
+// so bumping the latter revokes every session token issued before. TokenID tells tokens from
+// NewUserPurposeToken apart and is empty for other tokens.
 type UserClaims struct {
-	ID    int64  `json:"id"`
-	Email string `json:"email"`
+	ID      int64  `json:"id"`
+	Email   string `json:"email"`
+	Version int64  `json:"ver"`
+	TokenID string `json:"jti"`
 }
 
 func NewToken(user models.User) (string, error) {
@@ -18,9 +36,49 @@ func NewToken(user models.User) (string, error) {
 	claims := token.Claims.(jwt.MapClaims)
 	claims["id"] = user.ID
 	claims["email"] = user.Email
+	claims["ver"] = user.TokenVersion
 	claims["exp"] = time.Now().Add(time.Hour * 24).Unix()
 
-	tokenString, err := token.SignedString([]byte("secret_signing_key"))
+	tokenString, err := token.SignedString([]byte(signingKey))
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// NewPurposeToken creates a token that is only valid for the given purpose, e.g. an email verification link.
+func NewPurposeToken(userID int64, email, purpose string, ttl time.Duration) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+
+	claims := token.Claims.(jwt.MapClaims)
+	claims["id"] = userID
+	claims["email"] = email
+	claims["purpose"] = purpose
+	claims["exp"] = time.Now().Add(ttl).Unix()
+
+	tokenString, err := token.SignedString([]byte(signingKey))
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// NewUserPurposeToken creates a token of the user that is only valid for the given purpose, e.g. a login challenge.
+// Like a session token, it carries the user's token version, so bumping the version revokes it.
+func NewUserPurposeToken(user models.User, purpose string, ttl time.Duration) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+
+	claims := token.Claims.(jwt.MapClaims)
+	claims["id"] = user.ID
+	claims["email"] = user.Email
+	claims["ver"] = user.TokenVersion
+	claims["jti"] = random.String(tokenIDLength)
+	claims["purpose"] = purpose
+	claims["exp"] = time.Now().Add(ttl).Unix()
+
+	tokenString, err := token.SignedString([]byte(signingKey))
 	if err != nil {
 		return "", err
 	}
@@ -33,16 +91,45 @@ func ValidateToken(tokenString string) (*jwt.Token, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, jwt.ErrSignatureInvalid
 		}
-		return []byte("secret_signing_key"), nil
+		return []byte(signingKey), nil
 	})
 }
 
+// ValidatePurposeToken validates a token created by NewPurposeToken and returns its claims.
+// Tokens issued for another purpose are rejected.
+func ValidatePurposeToken(tokenString, purpose string) (*UserClaims, error) {
+	token, err := ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, jwt.ErrInvalidKey
+	}
+
+	if tokenPurpose, _ := claims["purpose"].(string); tokenPurpose != purpose {
+		return nil, jwt.ErrInvalidKey
+	}
+
+	return userClaims(claims)
+}
+
 func ExtractUserClaims(token *jwt.Token) (*UserClaims, error) {
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
 		return nil, jwt.ErrInvalidKey
 	}
 
+	// @NOTE: Single-purpose tokens must not be usable as session tokens
+	if _, ok := claims["purpose"]; ok {
+		return nil, jwt.ErrInvalidKey
+	}
+
+	return userClaims(claims)
+}
+
+func userClaims(claims jwt.MapClaims) (*UserClaims, error) {
 	id, ok := claims["id"].(float64)
 	if !ok {
 		return nil, jwt.ErrInvalidKey
@@ -53,8 +140,14 @@ func ExtractUserClaims(token *jwt.Token) (*UserClaims, error) {
 		return nil, jwt.ErrInvalidKey
 	}
 
+	// @NOTE: Tokens issued before versioning carry no version and match version 0
+	version, _ := claims["ver"].(float64)
+	tokenID, _ := claims["jti"].(string)
+
 	return &UserClaims{
-		ID:    int64(id),
-		Email: email,
+		ID:      int64(id),
+		Email:   email,
+		Version: int64(version),
+		TokenID: tokenID,
 	}, nil
 }
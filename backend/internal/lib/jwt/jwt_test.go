@@ -0,0 +1,55 @@
+package jwt
+
+import (
+	"TextVault/internal/storage/models"
+	"testing"
+	"time"
+)
+
+func TestNewUserPurposeToken(t *testing.T) {
+	user := models.User{ID: 42, Email: "user@example.com", TokenVersion: 3}
+
+	token, err := NewUserPurposeToken(user, PurposeTwoFactor, time.Minute)
+	if err != nil {
+		t.Fatalf("NewUserPurposeToken() error = %v", err)
+	}
+
+	claims, err := ValidatePurposeToken(token, PurposeTwoFactor)
+	if err != nil {
+		t.Fatalf("ValidatePurposeToken() error = %v", err)
+	}
+	if claims.ID != user.ID || claims.Email != user.Email || claims.Version != user.TokenVersion {
+		t.Errorf("ValidatePurposeToken() = %+v, want id %d, email %q, version %d", claims, user.ID, user.Email, user.TokenVersion)
+	}
+
+	other, err := NewUserPurposeToken(user, PurposeTwoFactor, time.Minute)
+	if err != nil {
+		t.Fatalf("NewUserPurposeToken() error = %v", err)
+	}
+	if otherClaims, _ := ValidatePurposeToken(other, PurposeTwoFactor); claims.TokenID == "" || otherClaims.TokenID == claims.TokenID {
+		t.Errorf("token IDs = %q and %q, want two distinct IDs", claims.TokenID, otherClaims.TokenID)
+	}
+
+	if _, err := ValidatePurposeToken(token, PurposeVerifyEmail); err == nil {
+		t.Error("ValidatePurposeToken() accepted a token of another purpose")
+	}
+
+	parsed, err := ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if _, err := ExtractUserClaims(parsed); err == nil {
+		t.Error("ExtractUserClaims() accepted a purpose token as a session token")
+	}
+}
+
+func TestValidatePurposeTokenExpired(t *testing.T) {
+	token, err := NewUserPurposeToken(models.User{ID: 1, Email: "user@example.com"}, PurposeTwoFactor, -time.Minute)
+	if err != nil {
+		t.Fatalf("NewUserPurposeToken() error = %v", err)
+	}
+
+	if _, err := ValidatePurposeToken(token, PurposeTwoFactor); err == nil {
+		t.Error("ValidatePurposeToken() accepted an expired token")
+	}
+}
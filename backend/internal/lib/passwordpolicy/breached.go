@@ -0,0 +1,80 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// prefixLength is the length of the k-anonymity prefix of a SHA-1 hash, as used by Have I Been Pwned.
+const prefixLength = 5
+
+// BreachedList is a set of SHA-1 hashes of breached passwords, bucketed by their 5 character prefix.
+type BreachedList struct {
+	buckets map[string]map[string]struct{}
+}
+
+// LoadBreachedList loads a breached-password list from a file. Every line holds an uppercase or lowercase
+// hex SHA-1 hash, optionally followed by ":count", as in the Have I Been Pwned downloads.
+// Empty lines and lines starting with "#" are ignored.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list := &BreachedList{buckets: make(map[string]map[string]struct{})}
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("%s:%d: invalid SHA-1 hash", path, line)
+		}
+
+		list.add(hash)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Contains reports whether the password is in the list.
+func (l *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	bucket, ok := l.buckets[hash[:prefixLength]]
+	if !ok {
+		return false
+	}
+
+	_, ok = bucket[hash[prefixLength:]]
+	return ok
+}
+
+func (l *BreachedList) add(hash string) {
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	bucket, ok := l.buckets[prefix]
+	if !ok {
+		bucket = make(map[string]struct{})
+		l.buckets[prefix] = bucket
+	}
+
+	bucket[suffix] = struct{}{}
+}
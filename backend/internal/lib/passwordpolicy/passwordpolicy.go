@@ -0,0 +1,75 @@
+package passwordpolicy
+
+import (
+	"TextVault/internal/config"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// minSimilarityLength is the shortest username or email name that passwords are checked against,
+// so very short names don't reject common passwords.
+const minSimilarityLength = 3
+
+// Policy validates new passwords against length limits, the user's names and a breached-password list.
+type Policy struct {
+	minLength int
+	maxLength int
+	breached  *BreachedList
+}
+
+// New creates a password policy. If a breached-password list is configured, it is loaded from the file.
+func New(cfg *config.PasswordConfig) (*Policy, error) {
+	policy := &Policy{
+		minLength: cfg.MinLength,
+		maxLength: cfg.MaxLength,
+	}
+
+	if cfg.BreachedListPath != "" {
+		breached, err := LoadBreachedList(cfg.BreachedListPath)
+		if err != nil {
+			return nil, err
+		}
+
+		policy.breached = breached
+	}
+
+	return policy, nil
+}
+
+// Validate returns every rule the password violates, or nil if it is acceptable.
+// Username and email may be empty if they aren't known, e.g. when resetting a password.
+func (p *Policy) Validate(password, username, email string) []string {
+	var problems []string
+
+	if utf8.RuneCountInString(password) < p.minLength {
+		problems = append(problems, fmt.Sprintf("password must be at least %d characters long", p.minLength))
+	}
+
+	// @NOTE: Legacy bcrypt hashes silently ignore everything after the first 72 bytes
+	if len(password) > p.maxLength {
+		problems = append(problems, fmt.Sprintf("password must be at most %d bytes long", p.maxLength))
+	}
+
+	emailName, _, _ := strings.Cut(email, "@")
+	if similarTo(password, username) || similarTo(password, emailName) {
+		problems = append(problems, "password must not contain your username or email")
+	}
+
+	if p.breached != nil && p.breached.Contains(password) {
+		problems = append(problems, "password appears in a known data breach")
+	}
+
+	return problems
+}
+
+func similarTo(password, name string) bool {
+	if utf8.RuneCountInString(name) < minSimilarityLength {
+		return false
+	}
+
+	password = strings.ToLower(password)
+	name = strings.ToLower(name)
+
+	return strings.Contains(password, name) || strings.Contains(name, password)
+}
@@ -0,0 +1,101 @@
+package passwordpolicy
+
+import (
+	"TextVault/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// breachedList holds the SHA-1 hashes of "password" and, lowercase with a count, "123456".
+const breachedList = `# Have I Been Pwned excerpt
+
+5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+7c4a8d09ca3762af61e59520943dc26494f8941b:37359195
+`
+
+func writeList(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write list: %v", err)
+	}
+
+	return path
+}
+
+func TestValidate(t *testing.T) {
+	policy, err := New(&config.PasswordConfig{MinLength: 8, MaxLength: 72, BreachedListPath: writeList(t, breachedList)})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		username string
+		email    string
+		want     []string
+	}{
+		{name: "acceptable", password: "correct horse battery", username: "alice", email: "alice@example.com"},
+		{name: "too short", password: "s3cr3t", want: []string{"password must be at least 8 characters long"}},
+		{name: "length counts characters", password: "пароль-пароль"},
+		{name: "too long", password: strings.Repeat("a", 73), want: []string{"password must be at most 72 bytes long"}},
+		{name: "max length counts bytes", password: strings.Repeat("ж", 37), want: []string{"password must be at most 72 bytes long"}},
+		{name: "contains username", password: "my-Alice-password", username: "alice", want: []string{"password must not contain your username or email"}},
+		{name: "contains email name", password: "bobsmith2024", email: "BobSmith@example.com", want: []string{"password must not contain your username or email"}},
+		{name: "contained in username", password: "longusername", username: "my-longusername-1", want: []string{"password must not contain your username or email"}},
+		{name: "short names are ignored", password: "abandoned ship", username: "ab", email: "ab@example.com"},
+		{name: "unknown names", password: "correct horse battery"},
+		{name: "breached", password: "password", want: []string{"password appears in a known data breach"}},
+		{name: "breached lowercase hash", password: "123456", want: []string{"password must be at least 8 characters long", "password appears in a known data breach"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.Validate(tt.password, tt.username, tt.email)
+			if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateWithoutBreachedList(t *testing.T) {
+	policy, err := New(&config.PasswordConfig{MinLength: 8, MaxLength: 72})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if problems := policy.Validate("password", "", ""); len(problems) != 0 {
+		t.Errorf("Validate() = %q, want no problems without a breached-password list", problems)
+	}
+}
+
+func TestLoadBreachedList(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", content: breachedList},
+		{name: "empty", content: ""},
+		{name: "short hash", content: "5BAA61E4C9B93F3F\n", wantErr: true},
+		{name: "long hash", content: "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8AA\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadBreachedList(writeList(t, tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadBreachedList() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := New(&config.PasswordConfig{BreachedListPath: filepath.Join(t.TempDir(), "missing.txt")}); err == nil {
+		t.Error("New() with a missing breached-password list error = nil, want an error")
+	}
+}
@@ -0,0 +1,113 @@
+package pow
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxNonceLength = 64
+
+var (
+	ErrInvalidChallenge = errors.New("invalid challenge")
+	ErrExpiredChallenge = errors.New("challenge has expired")
+	ErrInsufficientWork = errors.New("nonce doesn't meet the difficulty")
+)
+
+// Challenge is a hashcash-style proof-of-work challenge. A client solves it by finding a nonce for which
+// SHA-256(token + nonce) starts with at least Difficulty zero bits.
+type Challenge struct {
+	ID         string
+	Difficulty int
+	ExpiresAt  time.Time
+}
+
+// Issuer issues challenges as tokens signed with HMAC-SHA256, so no state is kept until one is solved.
+// A token has the form "id.expires.difficulty.signature".
+type Issuer struct {
+	key []byte
+}
+
+func NewIssuer(key string) *Issuer {
+	return &Issuer{key: []byte(key)}
+}
+
+// Token returns the signed token of the challenge.
+func (i *Issuer) Token(challenge Challenge) string {
+	payload := challenge.ID + "." + strconv.FormatInt(challenge.ExpiresAt.Unix(), 10) + "." + strconv.Itoa(challenge.Difficulty)
+
+	return payload + "." + hex.EncodeToString(i.mac(payload))
+}
+
+// Verify checks the token's signature and expiry and that the nonce solves it, and returns the challenge.
+// It doesn't check whether the challenge was solved before.
+func (i *Issuer) Verify(token, nonce string, now time.Time) (Challenge, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return Challenge{}, ErrInvalidChallenge
+	}
+
+	signature, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return Challenge{}, ErrInvalidChallenge
+	}
+
+	payload := parts[0] + "." + parts[1] + "." + parts[2]
+	if !hmac.Equal(signature, i.mac(payload)) {
+		return Challenge{}, ErrInvalidChallenge
+	}
+
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return Challenge{}, ErrInvalidChallenge
+	}
+
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return Challenge{}, ErrInvalidChallenge
+	}
+
+	challenge := Challenge{
+		ID:         parts[0],
+		Difficulty: difficulty,
+		ExpiresAt:  time.Unix(expires, 0),
+	}
+
+	if !now.Before(challenge.ExpiresAt) {
+		return Challenge{}, ErrExpiredChallenge
+	}
+
+	if nonce == "" || len(nonce) > maxNonceLength || LeadingZeroBits(token, nonce) < difficulty {
+		return Challenge{}, ErrInsufficientWork
+	}
+
+	return challenge, nil
+}
+
+// LeadingZeroBits returns the number of leading zero bits of SHA-256(token + nonce).
+func LeadingZeroBits(token, nonce string) int {
+	sum := sha256.Sum256([]byte(token + nonce))
+
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+
+		zeros += 8
+	}
+
+	return zeros
+}
+
+func (i *Issuer) mac(payload string) []byte {
+	h := hmac.New(sha256.New, i.key)
+	h.Write([]byte(payload))
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,87 @@
+package pow
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// solve finds the first nonce meeting the difficulty, or one missing it if miss is set.
+func solve(token string, difficulty int, miss bool) string {
+	for n := 0; ; n++ {
+		nonce := strconv.Itoa(n)
+		if (LeadingZeroBits(token, nonce) >= difficulty) != miss {
+			return nonce
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	issuer := NewIssuer("pow-key")
+	challenge := Challenge{ID: "challenge-id", Difficulty: 8, ExpiresAt: now.Add(time.Minute)}
+	token := issuer.Token(challenge)
+	nonce := solve(token, challenge.Difficulty, false)
+
+	parts := strings.Split(token, ".")
+	tamperedDifficulty := strings.Join([]string{parts[0], parts[1], "1", parts[3]}, ".")
+	tamperedID := strings.Join([]string{"other-id", parts[1], parts[2], parts[3]}, ".")
+
+	tests := []struct {
+		name    string
+		issuer  *Issuer
+		token   string
+		nonce   string
+		now     time.Time
+		wantErr error
+	}{
+		{name: "solved", issuer: issuer, token: token, nonce: nonce, now: now},
+		{name: "insufficient work", issuer: issuer, token: token, nonce: solve(token, challenge.Difficulty, true), now: now, wantErr: ErrInsufficientWork},
+		{name: "empty nonce", issuer: issuer, token: token, nonce: "", now: now, wantErr: ErrInsufficientWork},
+		{name: "nonce too long", issuer: issuer, token: token, nonce: strings.Repeat("0", maxNonceLength+1), now: now, wantErr: ErrInsufficientWork},
+		{name: "expired", issuer: issuer, token: token, nonce: nonce, now: challenge.ExpiresAt, wantErr: ErrExpiredChallenge},
+		{name: "tampered difficulty", issuer: issuer, token: tamperedDifficulty, nonce: solve(tamperedDifficulty, 1, false), now: now, wantErr: ErrInvalidChallenge},
+		{name: "tampered id", issuer: issuer, token: tamperedID, nonce: nonce, now: now, wantErr: ErrInvalidChallenge},
+		{name: "other key", issuer: NewIssuer("other-key"), token: token, nonce: nonce, now: now, wantErr: ErrInvalidChallenge},
+		{name: "malformed", issuer: issuer, token: "not-a-token", nonce: nonce, now: now, wantErr: ErrInvalidChallenge},
+		{name: "malformed signature", issuer: issuer, token: parts[0] + "." + parts[1] + "." + parts[2] + ".zz", nonce: nonce, now: now, wantErr: ErrInvalidChallenge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.issuer.Verify(tt.token, tt.nonce, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && (got.ID != challenge.ID || got.Difficulty != challenge.Difficulty || !got.ExpiresAt.Equal(challenge.ExpiresAt)) {
+				t.Errorf("Verify() = %+v, want %+v", got, challenge)
+			}
+		})
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	// @NOTE: SHA-256("abc") starts with 0xba, SHA-256("") with 0xe3
+	tests := []struct {
+		token string
+		nonce string
+		want  int
+	}{
+		{token: "ab", nonce: "c", want: 0},
+		{token: "", nonce: "", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := LeadingZeroBits(tt.token, tt.nonce); got != tt.want {
+			t.Errorf("LeadingZeroBits(%q, %q) = %d, want %d", tt.token, tt.nonce, got, tt.want)
+		}
+	}
+
+	token := NewIssuer("pow-key").Token(Challenge{ID: "id", Difficulty: 12, ExpiresAt: time.Unix(1700000000, 0)})
+	if got := LeadingZeroBits(token, solve(token, 12, false)); got < 12 {
+		t.Errorf("LeadingZeroBits() of a solution = %d, want at least 12", got)
+	}
+}
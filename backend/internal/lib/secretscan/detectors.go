@@ -0,0 +1,95 @@
+package secretscan
+
+import (
+	"math"
+	"regexp"
+)
+
+// Rule names of the built-in detectors.
+const (
+	RuleAWSAccessKeyID     = "aws_access_key_id"
+	RuleAWSSecretAccessKey = "aws_secret_access_key"
+	RuleGitHubToken        = "github_token"
+	RuleJWT                = "jwt"
+	RulePrivateKey         = "private_key"
+	RuleURLPassword        = "url_password"
+)
+
+// RegexDetector finds secrets matching a pattern. If the pattern has a capturing group, only the group
+// is the secret. With MinEntropy, matches whose secret has a lower Shannon entropy in bits per character
+// are ignored, which filters out placeholders such as "xxxx" or "changeme".
+type RegexDetector struct {
+	Rule       string
+	Pattern    *regexp.Regexp
+	MinEntropy float64
+}
+
+func (d *RegexDetector) Detect(content string) []Finding {
+	var findings []Finding
+
+	for _, match := range d.Pattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := match[0], match[1]
+		if len(match) >= 4 && match[2] >= 0 {
+			start, end = match[2], match[3]
+		}
+
+		if d.MinEntropy > 0 && entropy(content[start:end]) < d.MinEntropy {
+			continue
+		}
+
+		findings = append(findings, Finding{Rule: d.Rule, Start: start, End: end})
+	}
+
+	return findings
+}
+
+// DefaultDetectors returns the built-in detectors for AWS keys, GitHub tokens, JWTs, PEM private keys
+// and passwords in URLs.
+func DefaultDetectors() []Detector {
+	return []Detector{
+		&RegexDetector{
+			Rule:    RuleAWSAccessKeyID,
+			Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA)[0-9A-Z]{16}\b`),
+		},
+		&RegexDetector{
+			Rule:       RuleAWSSecretAccessKey,
+			Pattern:    regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|private).{0,20}?[\s'"=:]+([A-Za-z0-9/+]{40})\b`),
+			MinEntropy: 4,
+		},
+		&RegexDetector{
+			Rule:    RuleGitHubToken,
+			Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{82})\b`),
+		},
+		&RegexDetector{
+			Rule:    RuleJWT,
+			Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+		},
+		&RegexDetector{
+			Rule:    RulePrivateKey,
+			Pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY-----(?:[\s\S]*?-----END (?:[A-Z0-9]+ )*PRIVATE KEY-----)?`),
+		},
+		&RegexDetector{
+			Rule:       RuleURLPassword,
+			Pattern:    regexp.MustCompile(`\b[A-Za-z][A-Za-z0-9+.-]*://[^\s:@/]+:([^\s:@/]{3,})@`),
+			MinEntropy: 2,
+		},
+	}
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+
+	var bits float64
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		bits -= p * math.Log2(p)
+	}
+
+	return bits
+}
@@ -0,0 +1,110 @@
+package secretscan
+
+import (
+	"TextVault/internal/config"
+	"fmt"
+)
+
+// Actions a policy takes on a finding, from the mildest to the strictest.
+const (
+	ActionWarn    = "warn"
+	ActionRedact  = "redact"
+	ActionPrivate = "private"
+	ActionReject  = "reject"
+)
+
+// Policy decides what happens to content with secrets in it. Each rule has an action, the default
+// action unless the configuration overrides it.
+type Policy struct {
+	enabled       bool
+	scanner       *Scanner
+	defaultAction string
+	rules         map[string]string
+}
+
+// PolicyFinding is a finding along with the action taken on it.
+type PolicyFinding struct {
+	Finding
+	Action string
+}
+
+// Result is the outcome of checking content. Content is the content to save, with the secrets of redacted
+// findings replaced. Private is set if the content must only be saved privately, Rejected if it must not be saved.
+type Result struct {
+	Findings []PolicyFinding
+	Content  string
+	Private  bool
+	Rejected bool
+}
+
+// NewPolicy creates a policy that scans with the default detectors.
+// It returns an error if the configuration names an unknown action.
+func NewPolicy(cfg *config.SecretScanConfig) (*Policy, error) {
+	if !isAction(cfg.Policy) {
+		return nil, fmt.Errorf("unknown secret scan policy %q", cfg.Policy)
+	}
+
+	for rule, action := range cfg.Rules {
+		if !isAction(action) {
+			return nil, fmt.Errorf("unknown secret scan policy %q for rule %q", action, rule)
+		}
+	}
+
+	return &Policy{
+		enabled:       cfg.Enabled,
+		scanner:       NewScanner(DefaultDetectors()...),
+		defaultAction: cfg.Policy,
+		rules:         cfg.Rules,
+	}, nil
+}
+
+// Check scans the content and applies the actions of its findings. Content that can't be private,
+// e.g. that of an anonymous paste, is redacted in place of being forced private.
+func (p *Policy) Check(content string, canBePrivate bool) Result {
+	result := Result{Content: content}
+	if !p.enabled {
+		return result
+	}
+
+	var redacted []Finding
+	for _, finding := range p.scanner.Scan(content) {
+		action := p.action(finding.Rule)
+		if action == ActionPrivate && !canBePrivate {
+			action = ActionRedact
+		}
+
+		switch action {
+		case ActionRedact:
+			redacted = append(redacted, finding)
+		case ActionPrivate:
+			result.Private = true
+		case ActionReject:
+			result.Rejected = true
+		}
+
+		result.Findings = append(result.Findings, PolicyFinding{Finding: finding, Action: action})
+	}
+
+	if len(redacted) > 0 {
+		result.Content = Redact(content, redacted)
+	}
+
+	return result
+}
+
+func (p *Policy) action(rule string) string {
+	if action, ok := p.rules[rule]; ok {
+		return action
+	}
+
+	return p.defaultAction
+}
+
+func isAction(action string) bool {
+	switch action {
+	case ActionWarn, ActionRedact, ActionPrivate, ActionReject:
+		return true
+	}
+
+	return false
+}
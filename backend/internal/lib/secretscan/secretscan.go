@@ -0,0 +1,82 @@
+package secretscan
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Finding is a secret found in a text. Start and End are byte offsets of the secret itself, e.g. only the
+// password of a URL. Line and Column are 1-based, and Column counts characters, not bytes.
+type Finding struct {
+	Rule   string
+	Start  int
+	End    int
+	Line   int
+	Column int
+}
+
+// Detector finds one kind of secret. Findings need only Rule, Start and End; Scanner fills in the rest.
+type Detector interface {
+	Detect(content string) []Finding
+}
+
+// Scanner runs a pipeline of detectors over a text.
+type Scanner struct {
+	detectors []Detector
+}
+
+func NewScanner(detectors ...Detector) *Scanner {
+	return &Scanner{detectors: detectors}
+}
+
+// Scan returns the findings of every detector in the order they appear in the content. A secret found
+// by several detectors is only reported by the first one.
+func (s *Scanner) Scan(content string) []Finding {
+	var findings []Finding
+	for _, detector := range s.detectors {
+		findings = append(findings, detector.Detect(content)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Start < findings[j].Start
+	})
+
+	result := findings[:0]
+	for _, finding := range findings {
+		if len(result) > 0 && finding.Start < result[len(result)-1].End {
+			continue
+		}
+
+		finding.Line, finding.Column = position(content, finding.Start)
+		result = append(result, finding)
+	}
+
+	return result
+}
+
+// Redact replaces the secrets of the findings, which must come from Scan, with a placeholder naming the rule.
+func Redact(content string, findings []Finding) string {
+	var b strings.Builder
+	b.Grow(len(content))
+
+	last := 0
+	for _, finding := range findings {
+		b.WriteString(content[last:finding.Start])
+		b.WriteString("[REDACTED:" + finding.Rule + "]")
+		last = finding.End
+	}
+	b.WriteString(content[last:])
+
+	return b.String()
+}
+
+// position returns the 1-based line and column of the byte offset.
+func position(content string, offset int) (int, int) {
+	before := content[:offset]
+
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1
+
+	return line, column
+}
@@ -0,0 +1,43 @@
+package sharelink
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	signer := NewSigner("share-key")
+	expires := time.Unix(1700000000, 0)
+	signature := signer.Sign("link-id", "paste-id", expires)
+
+	tests := []struct {
+		name      string
+		signer    *Signer
+		linkID    string
+		pasteID   string
+		expires   time.Time
+		signature string
+		want      bool
+	}{
+		{name: "valid", signer: signer, linkID: "link-id", pasteID: "paste-id", expires: expires, signature: signature, want: true},
+		{name: "uppercase signature", signer: signer, linkID: "link-id", pasteID: "paste-id", expires: expires, signature: strings.ToUpper(signature), want: true},
+		{name: "sub-second expiry", signer: signer, linkID: "link-id", pasteID: "paste-id", expires: expires.Add(500 * time.Millisecond), signature: signature, want: true},
+		{name: "tampered link id", signer: signer, linkID: "other-link", pasteID: "paste-id", expires: expires, signature: signature},
+		{name: "tampered paste", signer: signer, linkID: "link-id", pasteID: "other-paste", expires: expires, signature: signature},
+		{name: "extended expiry", signer: signer, linkID: "link-id", pasteID: "paste-id", expires: expires.Add(time.Second), signature: signature},
+		{name: "shifted fields", signer: signer, linkID: "link-id\npaste-id", pasteID: "", expires: expires, signature: signature},
+		{name: "other key", signer: NewSigner("other-key"), linkID: "link-id", pasteID: "paste-id", expires: expires, signature: signature},
+		{name: "truncated signature", signer: signer, linkID: "link-id", pasteID: "paste-id", expires: expires, signature: signature[:len(signature)-2]},
+		{name: "not hex", signer: signer, linkID: "link-id", pasteID: "paste-id", expires: expires, signature: "zz"},
+		{name: "empty signature", signer: signer, linkID: "link-id", pasteID: "paste-id", expires: expires},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.signer.Verify(tt.linkID, tt.pasteID, tt.expires, tt.signature); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	UsernameMinLength = 3
+	UsernameMaxLength = 32
+	EmailMaxLength    = 100
+
+	OrganizationNameMinLength = 3
+	OrganizationNameMaxLength = 50
+)
+
+// reservedUsernames can't be registered, because they could be mistaken for the service or its staff.
+var reservedUsernames = map[string]struct{}{
+	"account":       {},
+	"admin":         {},
+	"administrator": {},
+	"anonymous":     {},
+	"api":           {},
+	"moderator":     {},
+	"null":          {},
+	"pastes":        {},
+	"root":          {},
+	"security":      {},
+	"support":       {},
+	"system":        {},
+	"textvault":     {},
+}
+
+// Username returns every rule the username violates, or nil if it is valid. Usernames are 3 to 32 characters
+// of ASCII letters, digits, "_", "-" and ".", start with a letter or digit and must not be reserved.
+func Username(username string) []string {
+	var problems []string
+
+	length := utf8.RuneCountInString(username)
+	if length < UsernameMinLength || length > UsernameMaxLength {
+		problems = append(problems, fmt.Sprintf("username must be %d to %d characters long", UsernameMinLength, UsernameMaxLength))
+	}
+
+	for _, r := range username {
+		if !isUsernameRune(r) {
+			problems = append(problems, "username may only contain letters, digits, \"_\", \"-\" and \".\"")
+			break
+		}
+	}
+
+	if username != "" && !isAlphanumeric(rune(username[0])) {
+		problems = append(problems, "username must start with a letter or digit")
+	}
+
+	if _, ok := reservedUsernames[strings.ToLower(username)]; ok {
+		problems = append(problems, "username is reserved")
+	}
+
+	return problems
+}
+
+// Email returns every rule the email violates, or nil if it is valid. The email must be a bare
+// RFC 5322 address, without a display name, of at most 100 characters.
+func Email(email string) []string {
+	var problems []string
+
+	if len(email) > EmailMaxLength {
+		problems = append(problems, fmt.Sprintf("email must be at most %d characters long", EmailMaxLength))
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		problems = append(problems, "email is not a valid address")
+	}
+
+	return problems
+}
+
+// OrganizationName returns every rule the organization name violates, or nil if it is valid. Names are
+// 3 to 50 characters of ASCII letters, digits, spaces, "_", "-" and ".", and start with a letter or digit.
+func OrganizationName(name string) []string {
+	var problems []string
+
+	length := utf8.RuneCountInString(name)
+	if length < OrganizationNameMinLength || length > OrganizationNameMaxLength {
+		problems = append(problems, fmt.Sprintf("name must be %d to %d characters long", OrganizationNameMinLength, OrganizationNameMaxLength))
+	}
+
+	for _, r := range name {
+		if !isUsernameRune(r) && r != ' ' {
+			problems = append(problems, "name may only contain letters, digits, spaces, \"_\", \"-\" and \".\"")
+			break
+		}
+	}
+
+	if name != "" && !isAlphanumeric(rune(name[0])) {
+		problems = append(problems, "name must start with a letter or digit")
+	}
+
+	return problems
+}
+
+// SanitizeUsername turns an arbitrary name, e.g. one from an identity provider, into a valid username
+// by dropping unsupported characters. It returns an empty string if no valid username remains.
+func SanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if isUsernameRune(r) && (b.Len() > 0 || isAlphanumeric(r)) {
+			b.WriteRune(r)
+		}
+	}
+
+	username := b.String()
+	if len(username) > UsernameMaxLength {
+		username = username[:UsernameMaxLength]
+	}
+
+	if len(Username(username)) > 0 {
+		return ""
+	}
+
+	return username
+}
+
+func isUsernameRune(r rune) bool {
+	return isAlphanumeric(r) || r == '_' || r == '-' || r == '.'
+}
+
+func isAlphanumeric(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
@@ -0,0 +1,119 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	usernameLength = "username must be 3 to 32 characters long"
+	usernameChars  = "username may only contain letters, digits, \"_\", \"-\" and \".\""
+	usernameStart  = "username must start with a letter or digit"
+	usernameTaken  = "username is reserved"
+	emailLength    = "email must be at most 100 characters long"
+	emailInvalid   = "email is not a valid address"
+	nameLength     = "name must be 3 to 50 characters long"
+	nameChars      = "name may only contain letters, digits, spaces, \"_\", \"-\" and \".\""
+	nameStart      = "name must start with a letter or digit"
+)
+
+func assertProblems(t *testing.T, got, want []string) {
+	t.Helper()
+
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("problems = %q, want %q", got, want)
+	}
+}
+
+func TestUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		want     []string
+	}{
+		{username: "alice"},
+		{username: "a.b_c-d"},
+		{username: "007"},
+		{username: strings.Repeat("a", UsernameMaxLength)},
+		{username: "", want: []string{usernameLength}},
+		{username: "ab", want: []string{usernameLength}},
+		{username: strings.Repeat("a", UsernameMaxLength+1), want: []string{usernameLength}},
+		{username: "alice smith", want: []string{usernameChars}},
+		{username: "алиса", want: []string{usernameChars, usernameStart}},
+		{username: "_alice", want: []string{usernameStart}},
+		{username: ".a", want: []string{usernameLength, usernameStart}},
+		{username: "Admin", want: []string{usernameTaken}},
+		{username: "textvault", want: []string{usernameTaken}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			assertProblems(t, Username(tt.username), tt.want)
+		})
+	}
+}
+
+func TestEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  []string
+	}{
+		{email: "alice@example.com"},
+		{email: "alice+paste@mail.example.co.uk"},
+		{email: "", want: []string{emailInvalid}},
+		{email: "alice", want: []string{emailInvalid}},
+		{email: "alice@", want: []string{emailInvalid}},
+		{email: "Alice <alice@example.com>", want: []string{emailInvalid}},
+		{email: "<alice@example.com>", want: []string{emailInvalid}},
+		{email: " alice@example.com", want: []string{emailInvalid}},
+		{email: strings.Repeat("a", EmailMaxLength) + "@example.com", want: []string{emailLength}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			assertProblems(t, Email(tt.email), tt.want)
+		})
+	}
+}
+
+func TestOrganizationName(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "Acme Corp."},
+		{name: "R&D", want: []string{nameChars}},
+		{name: "ab", want: []string{nameLength}},
+		{name: strings.Repeat("a", OrganizationNameMaxLength+1), want: []string{nameLength}},
+		{name: " Acme", want: []string{nameStart}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertProblems(t, OrganizationName(tt.name), tt.want)
+		})
+	}
+}
+
+func TestSanitizeUsername(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "alice", want: "alice"},
+		{name: "Alice Smith", want: "AliceSmith"},
+		{name: "__alice__", want: "alice__"},
+		{name: "José Núñez", want: "JosNez"},
+		{name: strings.Repeat("a", 40), want: strings.Repeat("a", UsernameMaxLength)},
+		{name: "ab", want: ""},
+		{name: "Иван", want: ""},
+		{name: "admin", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeUsername(tt.name); got != tt.want {
+				t.Errorf("SanitizeUsername(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+// File appends every email to a file instead of sending it.
+type File struct {
+	mu   sync.Mutex
+	path string
+	from string
+}
+
+func NewFile(path, from string) *File {
+	return &File{
+		path: path,
+		from: from,
+	}
+}
+
+func (m *File) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(buildMessage(m.from, msg), "\r\n\r\n"...))
+	return err
+}
@@ -0,0 +1,26 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Log writes every email to the logger instead of sending it. It is meant for local development.
+type Log struct {
+	log *slog.Logger
+}
+
+func NewLog(log *slog.Logger) *Log {
+	return &Log{log: log}
+}
+
+func (m *Log) Send(ctx context.Context, msg Message) error {
+	m.log.Info("Sending email",
+		slog.String("op", "internal.mailer.Log.Send"),
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"TextVault/internal/config"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	driverSMTP = "smtp"
+	driverFile = "file"
+	driverLog  = "log"
+)
+
+// Message is an email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is an interface that provides a method for sending emails.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the Mailer selected by the driver in the config.
+func New(log *slog.Logger, cfg *config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case driverSMTP:
+		return NewSMTP(cfg), nil
+	case driverFile:
+		return NewFile(cfg.FilePath, cfg.From), nil
+	case driverLog:
+		return NewLog(log), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver: %s", cfg.Driver)
+	}
+}
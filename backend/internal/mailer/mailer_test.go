@@ -0,0 +1,206 @@
+package mailer
+
+import (
+	"TextVault/internal/config"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// smtpServer is a local SMTP stand-in that accepts every email and records its envelope and data.
+type smtpServer struct {
+	listener net.Listener
+	done     chan struct{}
+
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &smtpServer{listener: listener, done: make(chan struct{})}
+	go s.serve()
+
+	return s
+}
+
+func (s *smtpServer) config() *config.MailConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+
+	return &config.MailConfig{Driver: driverSMTP, From: "TextVault <no-reply@textvault.test>", Host: host, Port: port}
+}
+
+// serve handles a single session and closes done once it ends.
+func (s *smtpServer) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	_ = text.PrintfLine("220 textvault.test ESMTP")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			_ = text.PrintfLine("250-textvault.test")
+			_ = text.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			_, credentials, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(credentials)
+			s.auth = string(decoded)
+			_ = text.PrintfLine("235 authenticated")
+		case "MAIL":
+			s.from = strings.TrimPrefix(arg, "FROM:")
+			_ = text.PrintfLine("250 ok")
+		case "RCPT":
+			s.to = append(s.to, strings.TrimPrefix(arg, "TO:"))
+			_ = text.PrintfLine("250 ok")
+		case "DATA":
+			_ = text.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			s.data = string(data)
+			_ = text.PrintfLine("250 queued")
+		case "QUIT":
+			_ = text.PrintfLine("221 bye")
+			return
+		default:
+			_ = text.PrintfLine("250 ok")
+		}
+	}
+}
+
+func TestSMTPSend(t *testing.T) {
+	server := newSMTPServer(t)
+
+	msg := Message{To: "alice@example.com", Subject: "Verify your email", Body: "Hello,\nclick the link."}
+	if err := NewSMTP(server.config()).Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	<-server.done
+
+	if server.from != "<no-reply@textvault.test>" {
+		t.Errorf("envelope sender = %q, want the bare From address", server.from)
+	}
+	if len(server.to) != 1 || server.to[0] != "<alice@example.com>" {
+		t.Errorf("envelope recipients = %q, want only alice@example.com", server.to)
+	}
+	if server.auth != "" {
+		t.Errorf("client authenticated as %q without credentials", server.auth)
+	}
+
+	// @NOTE: The dot reader turns CRLF into LF
+	for _, want := range []string{
+		"From: TextVault <no-reply@textvault.test>\n",
+		"To: alice@example.com\n",
+		"Subject: Verify your email\n",
+		"Content-Type: text/plain; charset=\"utf-8\"\n",
+		"\n\nHello,\nclick the link.",
+	} {
+		if !strings.Contains(server.data, want) {
+			t.Errorf("message = %q, want it to contain %q", server.data, want)
+		}
+	}
+}
+
+func TestSMTPSendAuthenticates(t *testing.T) {
+	server := newSMTPServer(t)
+
+	cfg := server.config()
+	cfg.Username, cfg.Password = "mailer", "hunter22"
+
+	if err := NewSMTP(cfg).Send(context.Background(), Message{To: "alice@example.com", Subject: "Hi", Body: "Hi"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	<-server.done
+
+	if server.auth != "\x00mailer\x00hunter22" {
+		t.Errorf("PLAIN credentials = %q, want the configured username and password", server.auth)
+	}
+}
+
+func TestSMTPSendCanceled(t *testing.T) {
+	server := newSMTPServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := NewSMTP(server.config()).Send(ctx, Message{To: "alice@example.com"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Send() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFileSend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	m := NewFile(path, "TextVault <no-reply@textvault.test>")
+
+	for _, to := range []string{"alice@example.com", "bob@example.com"} {
+		if err := m.Send(context.Background(), Message{To: to, Subject: "Hi", Body: "Hi"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read mail file: %v", err)
+	}
+
+	if got := strings.Count(string(data), "From: TextVault <no-reply@textvault.test>\r\n"); got != 2 {
+		t.Errorf("mail file holds %d messages, want 2", got)
+	}
+	if !strings.Contains(string(data), "To: bob@example.com\r\n") {
+		t.Errorf("mail file = %q, want the second message appended", data)
+	}
+}
+
+func TestNew(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		driver  string
+		wantErr bool
+	}{
+		{driver: driverSMTP},
+		{driver: driverFile},
+		{driver: driverLog},
+		{driver: "sendmail", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			m, err := New(log, &config.MailConfig{Driver: tt.driver})
+			if (err != nil) != tt.wantErr || (err == nil && m == nil) {
+				t.Errorf("New() = %v, %v, want error %v", m, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"TextVault/internal/config"
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+)
+
+// SMTP sends emails through an SMTP server. Any local SMTP stand-in (e.g. MailHog) can be used in tests.
+type SMTP struct {
+	addr     string
+	from     string
+	envelope string
+	auth     smtp.Auth
+}
+
+func NewSMTP(cfg *config.MailConfig) *SMTP {
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	// @NOTE: The envelope sender must be a bare address, while the From header may carry a display name
+	envelope := cfg.From
+	if addr, err := mail.ParseAddress(cfg.From); err == nil {
+		envelope = addr.Address
+	}
+
+	return &SMTP{
+		addr:     net.JoinHostPort(cfg.Host, cfg.Port),
+		from:     cfg.From,
+		envelope: envelope,
+		auth:     auth,
+	}
+}
+
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return smtp.SendMail(m.addr, m.auth, m.envelope, []string{msg.To}, buildMessage(m.from, msg))
+}
+
+func buildMessage(from string, msg Message) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "textvault"
+
+// unmatchedRoute labels requests that matched no route, so unknown paths don't create new series.
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the Prometheus collectors of the service in its own registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests    *prometheus.CounterVec
+	httpDuration    *prometheus.HistogramVec
+	storageDuration *prometheus.HistogramVec
+	storageErrors   *prometheus.CounterVec
+	cacheLookups    *prometheus.CounterVec
+	pasteSize       prometheus.Histogram
+}
+
+// New creates the collectors and registers them along with the Go runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Latency of storage operations by storage and operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"storage", "operation"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_errors_total",
+			Help:      "Number of failed storage operations by storage and operation.",
+		}, []string{"storage", "operation"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "paste_cache_lookups_total",
+			Help:      "Number of paste cache lookups by result, hit or miss.",
+		}, []string{"result"}),
+		pasteSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "paste_size_bytes",
+			Help:      "Size of the content of saved pastes.",
+			// @NOTE: 64 B to 16 MiB, which covers the request body limit
+			Buckets: prometheus.ExponentialBuckets(64, 4, 10),
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.storageDuration,
+		m.storageErrors,
+		m.cacheLookups,
+		m.pasteSize,
+	)
+
+	return m
+}
+
+// Register adds further collectors, e.g. the connection pool stats, to the registry.
+func (m *Metrics) Register(collector prometheus.Collector) {
+	m.registry.MustRegister(collector)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// Middleware counts every request and records its latency by method, route pattern and status.
+// Routes are labelled by their pattern, e.g. "/pastes/:hash", so the number of series stays bounded.
+func (m *Metrics) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		route := c.Route().Path
+
+		// @NOTE: Errors are turned into responses by the error handler only after the middleware returns
+		if err != nil {
+			status = fiber.StatusInternalServerError
+
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+
+			if status == fiber.StatusNotFound {
+				route = unmatchedRoute
+			}
+		}
+
+		labels := prometheus.Labels{
+			"method": c.Method(),
+			"route":  route,
+			"status": strconv.Itoa(status),
+		}
+
+		m.httpRequests.With(labels).Inc()
+		m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
+
+// ObserveStorage records the latency of a storage operation started at start, and counts it as failed if err is not nil.
+func (m *Metrics) ObserveStorage(storage, operation string, start time.Time, err error) {
+	m.storageDuration.WithLabelValues(storage, operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		m.storageErrors.WithLabelValues(storage, operation).Inc()
+	}
+}
+
+// ObserveCacheLookup counts a paste cache lookup as a hit or a miss.
+func (m *Metrics) ObserveCacheLookup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	m.cacheLookups.WithLabelValues(result).Inc()
+}
+
+// ObservePasteSize records the content size of a saved paste.
+func (m *Metrics) ObservePasteSize(size int) {
+	m.pasteSize.Observe(float64(size))
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type principalKey struct{}
+
+// Principal is the authenticated caller of a request. It is stored in the fiber.Ctx locals
+// by RequireAuth and OptionalAuth and can be read by handlers with GetPrincipal.
+type Principal struct {
+	ID         int64
+	Username   string
+	Email      string
+	IsAdmin    bool
+	IsVerified bool
+}
+
+// UserProvider is an interface that provides a method for loading the user behind a token.
+type UserProvider interface {
+	GetUserByID(ctx context.Context, id int64) (models.User, error)
+}
+
+type Auth struct {
+	userProvider UserProvider
+	log          *slog.Logger
+}
+
+// NewAuth creates a new authentication middleware.
+func NewAuth(log *slog.Logger, userProvider UserProvider) *Auth {
+	return &Auth{
+		userProvider: userProvider,
+		log:          log,
+	}
+}
+
+// RequireAuth resolves the caller from the authorization header and stores it in the request locals.
+// If the header is missing or the token is invalid, it returns a 401 Unauthorized status with an error message.
+// If the user is banned, it returns a 403 Forbidden status with an error message.
+func (a *Auth) RequireAuth(c *fiber.Ctx) error {
+	const prefix = "internal.middleware.RequireAuth"
+
+	tokenString, err := ExtractToken(c)
+	if err != nil {
+		return unauthorizedResponse(c)
+	}
+
+	return a.authenticate(c, prefix, tokenString)
+}
+
+// OptionalAuth resolves the caller the same way as RequireAuth, but lets requests without
+// an authorization header through as anonymous. A header carrying an invalid token is still rejected.
+func (a *Auth) OptionalAuth(c *fiber.Ctx) error {
+	const prefix = "internal.middleware.OptionalAuth"
+
+	tokenString, err := ExtractToken(c)
+	if errors.Is(err, ErrMissingAuthorizationHeader) {
+		return c.Next()
+	}
+	if err != nil {
+		return unauthorizedResponse(c)
+	}
+
+	return a.authenticate(c, prefix, tokenString)
+}
+
+func (a *Auth) authenticate(c *fiber.Ctx, prefix, tokenString string) error {
+	log := sl.FromContext(c.UserContext(), a.log).With(
+		slog.String("op", prefix),
+	)
+
+	claims, err := GetUserClaimsFromToken(tokenString)
+	if err != nil {
+		log.Warn("Failed to extract user claims from token", sl.Err(err))
+
+		return unauthorizedResponse(c)
+	}
+
+	user, err := a.userProvider.GetUserByID(c.UserContext(), claims.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("Token belongs to unknown user", slog.Int64("user_id", claims.ID))
+
+			return unauthorizedResponse(c)
+		}
+
+		log.Error("Failed to get user", sl.Err(err))
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	if claims.Version != user.TokenVersion {
+		log.Warn("Revoked token rejected", slog.Int64("user_id", user.ID))
+
+		return unauthorizedResponse(c)
+	}
+
+	if user.IsBanned {
+		log.Warn("Banned user rejected", slog.Int64("user_id", user.ID))
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":  "user is banned",
+			"reason": user.BanReason,
+			"until":  user.BannedUntil,
+		})
+	}
+
+	c.Locals(principalKey{}, &Principal{
+		ID:         user.ID,
+		Username:   user.Username,
+		Email:      user.Email,
+		IsAdmin:    user.IsAdmin,
+		IsVerified: user.IsVerified,
+	})
+
+	return c.Next()
+}
+
+// RequireAdmin rejects callers that are not admins with a 403 Forbidden status.
+// It must be registered after RequireAuth.
+func (a *Auth) RequireAdmin(c *fiber.Ctx) error {
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		return unauthorizedResponse(c)
+	}
+
+	if !principal.IsAdmin {
+		sl.FromContext(c.UserContext(), a.log).Warn("Non-admin user rejected",
+			slog.String("op", "internal.middleware.RequireAdmin"),
+			slog.Int64("user_id", principal.ID),
+		)
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "admin access required",
+		})
+	}
+
+	return c.Next()
+}
+
+// GetPrincipal returns the caller resolved by RequireAuth or OptionalAuth.
+// The second return value is false if the request is anonymous.
+func GetPrincipal(c *fiber.Ctx) (*Principal, bool) {
+	principal, ok := c.Locals(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+func unauthorizedResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": "unauthorized",
+	})
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"TextVault/internal/lib/jwt"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// fakeUserProvider looks up the users of the auth tests by ID.
+type fakeUserProvider map[int64]models.User
+
+func (f fakeUserProvider) GetUserByID(_ context.Context, id int64) (models.User, error) {
+	user, ok := f[id]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func TestAuth(t *testing.T) {
+	alice := models.User{ID: 1, Username: "alice", Email: "alice@example.com", TokenVersion: 2, IsVerified: true}
+	banned := models.User{ID: 2, Username: "bob", Email: "bob@example.com", IsBanned: true, BanReason: "spam"}
+	unknown := models.User{ID: 3, Email: "carol@example.com"}
+
+	token := func(user models.User) string {
+		t.Helper()
+
+		token, err := jwt.NewToken(user)
+		if err != nil {
+			t.Fatalf("NewToken() error = %v", err)
+		}
+
+		return "Bearer " + token
+	}
+
+	revoked := alice
+	revoked.TokenVersion = 1
+
+	tests := []struct {
+		name     string
+		header   string
+		wantAuth int
+		wantOpt  int
+		wantUser int64
+	}{
+		{name: "no header", wantAuth: http.StatusUnauthorized, wantOpt: http.StatusOK},
+		{name: "not a bearer token", header: "Basic YWxpY2U6cGFzcw==", wantAuth: http.StatusUnauthorized, wantOpt: http.StatusUnauthorized},
+		{name: "malformed token", header: "Bearer not-a-token", wantAuth: http.StatusUnauthorized, wantOpt: http.StatusUnauthorized},
+		{name: "valid token", header: token(alice), wantAuth: http.StatusOK, wantOpt: http.StatusOK, wantUser: alice.ID},
+		{name: "revoked token version", header: token(revoked), wantAuth: http.StatusUnauthorized, wantOpt: http.StatusUnauthorized},
+		{name: "unknown user", header: token(unknown), wantAuth: http.StatusUnauthorized, wantOpt: http.StatusUnauthorized},
+		{name: "banned user", header: token(banned), wantAuth: http.StatusForbidden, wantOpt: http.StatusForbidden},
+	}
+
+	auth := NewAuth(slog.New(slog.NewTextHandler(io.Discard, nil)), fakeUserProvider{alice.ID: alice, banned.ID: banned})
+
+	// @NOTE: The handler answers with the ID of the principal, 0 for anonymous callers
+	app := fiber.New()
+	whoami := func(c *fiber.Ctx) error {
+		var id int64
+		if principal, ok := GetPrincipal(c); ok {
+			id = principal.ID
+		}
+
+		return c.SendString(strconv.FormatInt(id, 10))
+	}
+	app.Get("/required", auth.RequireAuth, whoami)
+	app.Get("/optional", auth.OptionalAuth, whoami)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for target, want := range map[string]int{"/required": tt.wantAuth, "/optional": tt.wantOpt} {
+				req := httptest.NewRequest(http.MethodGet, target, nil)
+				if tt.header != "" {
+					req.Header.Set("Authorization", tt.header)
+				}
+
+				resp, err := app.Test(req, -1)
+				if err != nil {
+					t.Fatalf("GET %s error = %v", target, err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+
+				if resp.StatusCode != want {
+					t.Errorf("GET %s status = %d, want %d", target, resp.StatusCode, want)
+					continue
+				}
+				if want == http.StatusOK && string(body) != strconv.FormatInt(tt.wantUser, 10) {
+					t.Errorf("GET %s principal = %s, want %d", target, body, tt.wantUser)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	admin := models.User{ID: 1, Email: "admin@example.com", IsAdmin: true}
+	user := models.User{ID: 2, Email: "user@example.com"}
+
+	auth := NewAuth(slog.New(slog.NewTextHandler(io.Discard, nil)), fakeUserProvider{admin.ID: admin, user.ID: user})
+
+	app := fiber.New()
+	app.Get("/admin", auth.RequireAuth, auth.RequireAdmin, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	tests := []struct {
+		name string
+		user models.User
+		want int
+	}{
+		{name: "admin", user: admin, want: http.StatusOK},
+		{name: "user", user: user, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := jwt.NewToken(tt.user)
+			if err != nil {
+				t.Fatalf("NewToken() error = %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("GET error = %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
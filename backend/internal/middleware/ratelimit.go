@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"TextVault/internal/config"
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/lib/sharelink"
+	"TextVault/internal/storage/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Identity types a rate limit applies to.
+const (
+	identityIP    = "ip"
+	identityUser  = "user"
+	identityToken = "token"
+)
+
+const maxTokenIdentityLength = 64
+
+// RateLimitStore is an interface that provides a method for taking a request from a rate limit.
+type RateLimitStore interface {
+	AllowRate(ctx context.Context, key string, interval time.Duration, burst int) (models.RateLimitResult, error)
+}
+
+type RateLimiter struct {
+	store     RateLimitStore
+	cfg       config.RateLimitConfig
+	allowlist []*net.IPNet
+	signer    *sharelink.Signer
+	log       *slog.Logger
+}
+
+// rateLimitCheck is one rate limit a request is counted against.
+type rateLimitCheck struct {
+	key   string
+	limit config.RateLimit
+}
+
+// NewRateLimiter creates a new rate limiting middleware. The signer verifies share links before they are
+// used as an identity. It returns an error if an allowlist entry is neither an IP nor a CIDR.
+func NewRateLimiter(log *slog.Logger, store RateLimitStore, cfg *config.RateLimitConfig, signer *sharelink.Signer) (*RateLimiter, error) {
+	allowlist := make([]*net.IPNet, 0, len(cfg.Allowlist))
+	for _, entry := range cfg.Allowlist {
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit allowlist entry %q: %w", entry, err)
+		}
+
+		allowlist = append(allowlist, network)
+	}
+
+	return &RateLimiter{
+		store:     store,
+		cfg:       *cfg,
+		allowlist: allowlist,
+		signer:    signer,
+		log:       log,
+	}, nil
+}
+
+// Limit returns a handler that applies the policy of the route group to each request. Authenticated users
+// are limited by their ID, anonymous clients by their IP. A request signed with a valid share link is limited
+// by its link as well, so a leaked link can't be hammered from many IPs. It must be registered after
+// RequireAuth or OptionalAuth for users to be recognised; before them, every client is limited by IP.
+// Admins are limited like other users; only clients from the allowlist are exempt.
+// The outcome is reported in the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+// A limited request gets a 429 Too Many Requests status with a Retry-After header and an error message.
+// If Redis is down and the limiter doesn't fail open, it returns a 503 Service Unavailable status.
+func (r *RateLimiter) Limit(group string) fiber.Handler {
+	policy, ok := r.cfg.Groups[group]
+	if !r.cfg.Enabled || !ok {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return func(c *fiber.Ctx) error {
+		const prefix = "internal.middleware.RateLimiter.Limit"
+
+		if r.isExempt(c) {
+			return c.Next()
+		}
+
+		log := sl.FromContext(c.UserContext(), r.log).With(
+			slog.String("op", prefix),
+			slog.String("group", group),
+		)
+
+		var reported *models.RateLimitResult
+		var reportedLimit config.RateLimit
+
+		for _, check := range r.checks(c, group, policy) {
+			result, err := r.store.AllowRate(c.UserContext(), check.key, check.limit.Period/time.Duration(check.limit.Requests), burst(check.limit))
+			if err != nil {
+				if r.cfg.FailOpen {
+					log.Warn("Rate limiter is unavailable, letting request through", sl.Err(err))
+
+					return c.Next()
+				}
+
+				log.Error("Rate limiter is unavailable", sl.Err(err))
+
+				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+					"error": "service is temporarily unavailable",
+				})
+			}
+
+			if !result.Allowed {
+				setRateLimitHeaders(c, check.limit, result)
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds(result.RetryAfter)))
+
+				log.Warn("Request rate limited", slog.String("key", check.key))
+
+				return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+					"error": "too many requests",
+				})
+			}
+
+			// @NOTE: The most restrictive limit is reported
+			if reported == nil || result.Remaining < reported.Remaining {
+				reported = &result
+				reportedLimit = check.limit
+			}
+		}
+
+		if reported != nil {
+			setRateLimitHeaders(c, reportedLimit, *reported)
+		}
+
+		return c.Next()
+	}
+}
+
+// checks returns the limits of the policy that apply to the caller.
+func (r *RateLimiter) checks(c *fiber.Ctx, group string, policy config.RateLimitPolicy) []rateLimitCheck {
+	var checks []rateLimitCheck
+
+	add := func(identityType, identity string, limit config.RateLimit) {
+		if limit.Requests <= 0 || limit.Period <= 0 {
+			return
+		}
+
+		checks = append(checks, rateLimitCheck{
+			key:   fmt.Sprintf("ratelimit:%s:%s:%s", group, identityType, identity),
+			limit: limit,
+		})
+	}
+
+	if principal, ok := GetPrincipal(c); ok {
+		add(identityUser, strconv.FormatInt(principal.ID, 10), policy.User)
+
+		return checks
+	}
+
+	add(identityIP, c.IP(), policy.IP)
+
+	if link, ok := r.shareLink(c); ok {
+		add(identityToken, link, policy.Token)
+	}
+
+	return checks
+}
+
+// shareLink returns the ID of the share link the request is signed with. It reports false for a forged or
+// expired link, so made up link IDs can't be used to spread requests over many rate limits.
+func (r *RateLimiter) shareLink(c *fiber.Ctx) (string, bool) {
+	link := c.Query("link")
+	if link == "" || len(link) > maxTokenIdentityLength {
+		return "", false
+	}
+
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		return "", false
+	}
+
+	expiresAt := time.Unix(expires, 0)
+	if time.Now().After(expiresAt) || !r.signer.Verify(link, c.Params("hash"), expiresAt, c.Query("sig")) {
+		return "", false
+	}
+
+	return link, true
+}
+
+// isExempt reports whether the caller comes from an allowlisted network. Admins aren't exempt, so a stolen
+// admin token can't be used to send requests without limit.
+func (r *RateLimiter) isExempt(c *fiber.Ctx) bool {
+	ip := net.ParseIP(c.IP())
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range r.allowlist {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func setRateLimitHeaders(c *fiber.Ctx, limit config.RateLimit, result models.RateLimitResult) {
+	c.Set("RateLimit-Limit", strconv.Itoa(burst(limit)))
+	c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Set("RateLimit-Reset", strconv.Itoa(seconds(result.ResetAfter)))
+	c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, seconds(limit.Period)))
+}
+
+// burst returns the number of requests allowed at once.
+func burst(limit config.RateLimit) int {
+	if limit.Burst > 0 {
+		return limit.Burst
+	}
+
+	return limit.Requests
+}
+
+// seconds rounds up, so a client waiting that long is never rejected again.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
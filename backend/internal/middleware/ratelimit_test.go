@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"TextVault/internal/config"
+	"TextVault/internal/lib/sharelink"
+	"TextVault/internal/storage/models"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// fakeRateLimitStore allows every request and records the keys it was asked about.
+type fakeRateLimitStore struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (f *fakeRateLimitStore) AllowRate(_ context.Context, key string, _ time.Duration, burst int) (models.RateLimitResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.keys = append(f.keys, key)
+
+	return models.RateLimitResult{Allowed: true, Remaining: burst - 1}, nil
+}
+
+func TestRateLimiterIdentities(t *testing.T) {
+	signer := sharelink.NewSigner("share-link-key")
+	limit := config.RateLimit{Requests: 10, Period: time.Minute}
+	cfg := &config.RateLimitConfig{
+		Enabled: true,
+		Groups: map[string]config.RateLimitPolicy{
+			"pastes": {IP: limit, User: limit, Token: limit},
+		},
+	}
+
+	signed := func(linkID, pasteID string, expiresAt time.Time) url.Values {
+		return url.Values{
+			"link":    {linkID},
+			"expires": {strconv.FormatInt(expiresAt.Unix(), 10)},
+			"sig":     {signer.Sign(linkID, pasteID, expiresAt)},
+		}
+	}
+
+	valid := signed("link1", "paste1", time.Now().Add(time.Hour))
+	forged := signed("link1", "paste1", time.Now().Add(time.Hour))
+	forged.Set("link", "link2")
+	unsigned := url.Values{"link": {"link1"}}
+	expired := signed("link1", "paste1", time.Now().Add(-time.Minute))
+	otherPaste := signed("link1", "paste2", time.Now().Add(time.Hour))
+
+	const ipKey = "ratelimit:pastes:ip:0.0.0.0"
+
+	tests := []struct {
+		name  string
+		user  *Principal
+		query url.Values
+		want  []string
+	}{
+		{name: "anonymous", want: []string{ipKey}},
+		{name: "valid share link", query: valid, want: []string{ipKey, "ratelimit:pastes:token:link1"}},
+		{name: "forged share link", query: forged, want: []string{ipKey}},
+		{name: "unsigned share link", query: unsigned, want: []string{ipKey}},
+		{name: "expired share link", query: expired, want: []string{ipKey}},
+		{name: "share link of another paste", query: otherPaste, want: []string{ipKey}},
+		{name: "user", user: &Principal{ID: 5}, query: valid, want: []string{"ratelimit:pastes:user:5"}},
+		{name: "admin", user: &Principal{ID: 7, IsAdmin: true}, want: []string{"ratelimit:pastes:user:7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeRateLimitStore{}
+
+			limiter, err := NewRateLimiter(slog.New(slog.NewTextHandler(io.Discard, nil)), store, cfg, signer)
+			if err != nil {
+				t.Fatalf("NewRateLimiter() error = %v", err)
+			}
+
+			app := fiber.New()
+			app.Get("/pastes/:hash", func(c *fiber.Ctx) error {
+				if tt.user != nil {
+					c.Locals(principalKey{}, tt.user)
+				}
+
+				return c.Next()
+			}, limiter.Limit("pastes"), func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/pastes/paste1?"+tt.query.Encode(), nil), -1)
+			if err != nil {
+				t.Fatalf("GET error = %v", err)
+			}
+			resp.Body.Close()
+
+			if !slices.Equal(store.keys, tt.want) {
+				t.Errorf("rate limit keys = %v, want %v", store.keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterExemptions(t *testing.T) {
+	limit := config.RateLimit{Requests: 10, Period: time.Minute}
+	groups := map[string]config.RateLimitPolicy{"admin": {User: limit}}
+	admin := &Principal{ID: 7, IsAdmin: true}
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		want      []string
+	}{
+		{name: "admin", want: []string{"ratelimit:admin:user:7"}},
+		{name: "allowlisted admin", allowlist: []string{"0.0.0.0/8"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeRateLimitStore{}
+			cfg := &config.RateLimitConfig{Enabled: true, Allowlist: tt.allowlist, Groups: groups}
+
+			limiter, err := NewRateLimiter(slog.New(slog.NewTextHandler(io.Discard, nil)), store, cfg, sharelink.NewSigner("share-link-key"))
+			if err != nil {
+				t.Fatalf("NewRateLimiter() error = %v", err)
+			}
+
+			app := fiber.New()
+			app.Get("/admin/users", func(c *fiber.Ctx) error {
+				c.Locals(principalKey{}, admin)
+
+				return c.Next()
+			}, limiter.Limit("admin"), func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/admin/users", nil), -1)
+			if err != nil {
+				t.Fatalf("GET error = %v", err)
+			}
+			resp.Body.Close()
+
+			if !slices.Equal(store.keys, tt.want) {
+				t.Errorf("rate limit keys = %v, want %v", store.keys, tt.want)
+			}
+		})
+	}
+}
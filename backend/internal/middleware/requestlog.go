@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/pkg/random"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	HeaderRequestID = "X-Request-ID"
+
+	requestIDLength    = 20
+	maxRequestIDLength = 128
+)
+
+type requestIDKey struct{}
+
+// RequestLogger assigns every request an ID, taken from the X-Request-ID header if the caller sent a valid one,
+// and echoes it in the response. It puts a logger carrying the request and trace IDs into the user context,
+// where handlers get it with sl.FromContext, and writes one access log line per request.
+// It must be registered after the tracing middleware, so the trace IDs are known.
+func RequestLogger(log *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		requestID := c.Get(HeaderRequestID)
+		if !isValidRequestID(requestID) {
+			requestID = random.String(requestIDLength)
+		}
+
+		c.Locals(requestIDKey{}, requestID)
+		c.Set(HeaderRequestID, requestID)
+
+		requestLog := log.With(slog.String("request_id", requestID))
+		if spanContext := trace.SpanContextFromContext(c.UserContext()); spanContext.IsValid() {
+			requestLog = requestLog.With(slog.String("trace_id", spanContext.TraceID().String()))
+		}
+
+		c.SetUserContext(sl.NewContext(c.UserContext(), requestLog))
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+
+		// @NOTE: Errors are turned into responses by the error handler only after the middleware returns
+		if err != nil {
+			status = fiber.StatusInternalServerError
+
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		// @NOTE: Only the path is logged, as query strings carry secrets such as verification tokens and share link signatures
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("route", c.Route().Path),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int("bytes", len(c.Response().Body())),
+			slog.String("ip", c.IP()),
+		}
+
+		if principal, ok := GetPrincipal(c); ok {
+			attrs = append(attrs, slog.Int64("user_id", principal.ID))
+		}
+
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		requestLog.LogAttrs(c.UserContext(), level, "Request handled", attrs...)
+
+		return err
+	}
+}
+
+// GetRequestID returns the ID that RequestLogger assigned to the request.
+func GetRequestID(c *fiber.Ctx) string {
+	requestID, _ := c.Locals(requestIDKey{}).(string)
+
+	return requestID
+}
+
+// isValidRequestID accepts IDs of letters, digits, "-", "_" and ".", so a caller can't inject into the logs.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '-' && r != '_' && r != '.' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// logLines decodes the JSON log lines written to buf.
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var decoded map[string]any
+		if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+			t.Fatalf("decode log line %q: %v", line, err)
+		}
+		lines = append(lines, decoded)
+	}
+
+	return lines
+}
+
+func TestRequestLoggerRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "missing", incoming: ""},
+		{name: "valid", incoming: "abc-123_DEF.456", keep: true},
+		{name: "log injection", incoming: "abc\" level=ERROR msg=forged"},
+		{name: "too long", incoming: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+			var handlerID string
+			app := fiber.New()
+			app.Get("/pastes/:hash", RequestLogger(log), func(c *fiber.Ctx) error {
+				handlerID = GetRequestID(c)
+				sl.FromContext(c.UserContext(), nil).Info("Handler ran")
+
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/pastes/paste1", nil)
+			if tt.incoming != "" {
+				req.Header.Set(HeaderRequestID, tt.incoming)
+			}
+
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("GET error = %v", err)
+			}
+			resp.Body.Close()
+
+			got := resp.Header.Get(HeaderRequestID)
+			switch {
+			case tt.keep && got != tt.incoming:
+				t.Errorf("response request ID = %q, want %q", got, tt.incoming)
+			case !tt.keep && (got == tt.incoming || len(got) != requestIDLength):
+				t.Errorf("response request ID = %q, want a generated one", got)
+			}
+			if handlerID != got {
+				t.Errorf("GetRequestID() = %q, want %q", handlerID, got)
+			}
+
+			// @NOTE: Both the handler's log line and the access log line carry the ID
+			for _, line := range logLines(t, &buf) {
+				if line["request_id"] != got {
+					t.Errorf("log line %v request_id = %v, want %q", line["msg"], line["request_id"], got)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestLoggerAccessLog(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      *Principal
+		handler   fiber.Handler
+		wantLevel string
+		wantCode  float64
+	}{
+		{
+			name:      "anonymous",
+			handler:   func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) },
+			wantLevel: "INFO",
+			wantCode:  http.StatusOK,
+		},
+		{
+			name:      "user",
+			user:      &Principal{ID: 5},
+			handler:   func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNotFound) },
+			wantLevel: "INFO",
+			wantCode:  http.StatusNotFound,
+		},
+		{
+			name:      "fiber error",
+			handler:   func(c *fiber.Ctx) error { return fiber.ErrBadRequest },
+			wantLevel: "INFO",
+			wantCode:  http.StatusBadRequest,
+		},
+		{
+			name:      "error",
+			handler:   func(c *fiber.Ctx) error { return io.ErrUnexpectedEOF },
+			wantLevel: "ERROR",
+			wantCode:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+			app := fiber.New()
+			app.Get("/pastes/:hash", RequestLogger(log), func(c *fiber.Ctx) error {
+				if tt.user != nil {
+					c.Locals(principalKey{}, tt.user)
+				}
+
+				return c.Next()
+			}, tt.handler)
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/pastes/paste1?link=l1&sig=secret", nil), -1)
+			if err != nil {
+				t.Fatalf("GET error = %v", err)
+			}
+			resp.Body.Close()
+
+			lines := logLines(t, &buf)
+			if len(lines) != 1 {
+				t.Fatalf("logged %d lines, want one access log line", len(lines))
+			}
+			line := lines[0]
+
+			if line["level"] != tt.wantLevel || line["status"] != tt.wantCode {
+				t.Errorf("access log level, status = %v, %v, want %s, %v", line["level"], line["status"], tt.wantLevel, tt.wantCode)
+			}
+			if line["route"] != "/pastes/:hash" || line["path"] != "/pastes/paste1" {
+				t.Errorf("access log route, path = %v, %v, want the route and the path without query", line["route"], line["path"])
+			}
+			if strings.Contains(buf.String(), "secret") {
+				t.Errorf("access log %s contains the query string", buf.String())
+			}
+
+			userID, ok := line["user_id"]
+			if tt.user == nil && ok {
+				t.Errorf("anonymous access log user_id = %v, want none", userID)
+			}
+			if tt.user != nil && userID != float64(tt.user.ID) {
+				t.Errorf("access log user_id = %v, want %d", userID, tt.user.ID)
+			}
+		})
+	}
+}
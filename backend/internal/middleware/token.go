@@ -28,16 +28,11 @@ func ExtractToken(c *fiber.Ctx) (string, error) {
 	return tokenString, nil
 }
 
-func GetUserIDFromToken(tokenString string) (int64, error) {
+func GetUserClaimsFromToken(tokenString string) (*jwt.UserClaims, error) {
 	token, err := jwt.ValidateToken(tokenString)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	claims, err := jwt.ExtractUserClaims(token)
-	if err != nil {
-		return 0, err
-	}
-
-	return claims.ID, nil
+	return jwt.ExtractUserClaims(token)
 }
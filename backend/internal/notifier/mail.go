@@ -0,0 +1,52 @@
+package notifier
+
+import (
+	"TextVault/internal/mailer"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Mail notifies moderators by email.
+type Mail struct {
+	mailer    mailer.Mailer
+	publicURL string
+	to        []string
+}
+
+func NewMail(mailer mailer.Mailer, publicURL string, to []string) *Mail {
+	return &Mail{
+		mailer:    mailer,
+		publicURL: publicURL,
+		to:        to,
+	}
+}
+
+func (n *Mail) Notify(ctx context.Context, event Event) error {
+	url := fmt.Sprintf("%s/pastes/%s", n.publicURL, event.PasteID)
+
+	var subject, body string
+	switch event.Type {
+	case EventPasteHidden:
+		subject = "Paste hidden after reports"
+		body = fmt.Sprintf("The paste %s was hidden automatically after %d reports.\n"+
+			"Review the moderation queue to hide, delete or restore it.", url, event.Reports)
+	default:
+		subject = "Paste reported"
+		body = fmt.Sprintf("The paste %s was reported as %s. It has %d open reports.", url, event.Category, event.Reports)
+	}
+
+	var errs []error
+	for _, to := range n.to {
+		err := n.mailer.Send(ctx, mailer.Message{
+			To:      to,
+			Subject: subject,
+			Body:    body,
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
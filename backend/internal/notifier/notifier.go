@@ -0,0 +1,63 @@
+package notifier
+
+import (
+	"TextVault/internal/config"
+	"TextVault/internal/mailer"
+	"context"
+	"errors"
+	"time"
+)
+
+// Types of moderation events.
+const (
+	EventPasteReported = "paste.reported"
+	EventPasteHidden   = "paste.hidden"
+)
+
+// Event is a moderation event that moderators are notified of. Reports is the number of open reports
+// of the paste at the time of the event.
+type Event struct {
+	Type     string    `json:"type"`
+	PasteID  string    `json:"pasteId"`
+	ReportID int64     `json:"reportId,omitempty"`
+	Category string    `json:"category,omitempty"`
+	Reports  int64     `json:"reports"`
+	Time     time.Time `json:"time"`
+}
+
+// Notifier is an interface that provides a method for notifying moderators of an event.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Multi notifies every one of its notifiers. Without any, it does nothing.
+type Multi []Notifier
+
+// New returns the notifiers configured for moderation: an email to every address in NotifyEmails
+// and a request to every URL in Webhooks.
+func New(mailer mailer.Mailer, publicURL string, cfg *config.ModerationConfig) Multi {
+	var notifiers Multi
+
+	if len(cfg.NotifyEmails) > 0 {
+		notifiers = append(notifiers, NewMail(mailer, publicURL, cfg.NotifyEmails))
+	}
+
+	for _, url := range cfg.Webhooks {
+		notifiers = append(notifiers, NewWebhook(url, cfg.WebhookSecret, cfg.WebhookTimeout))
+	}
+
+	return notifiers
+}
+
+// Notify notifies every notifier, even if some of them fail, and returns their joined errors.
+func (m Multi) Notify(ctx context.Context, event Event) error {
+	var errs []error
+
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -1,83 +1,227 @@
 package router
 
 import (
+	"TextVault/internal/audit"
+	"TextVault/internal/config"
+	"TextVault/internal/lib/passwordpolicy"
+	"TextVault/internal/lib/secretscan"
+	"TextVault/internal/mailer"
+	"TextVault/internal/metrics"
+	"TextVault/internal/middleware"
+	"TextVault/internal/notifier"
 	"TextVault/internal/router/services/account"
+	"TextVault/internal/router/services/admin"
+	"TextVault/internal/router/services/health"
+	"TextVault/internal/router/services/orgs"
 	"TextVault/internal/router/services/pastes"
+	"TextVault/internal/storage/instrumented"
 	"TextVault/internal/storage/postgres"
 	"TextVault/internal/storage/redis"
 	"TextVault/internal/storage/s3"
-	"fmt"
+	"TextVault/internal/tracing"
+	"context"
 	"log/slog"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type Router struct {
-	app *fiber.App
-	log *slog.Logger
+	app     *fiber.App
+	log     *slog.Logger
+	address string
 
+	metrics       *metrics.Metrics
+	metricsConfig config.MetricsConfig
+
+	auth           *middleware.Auth
+	limiter        *middleware.RateLimiter
 	accountService *account.Service
 	pasteService   *pastes.Service
+	adminService   *admin.Service
+	orgService     *orgs.Service
+	healthService  *health.Service
 }
 
 func New(postgres *postgres.Storage,
 	redis *redis.Storage,
 	S3 *s3.Storage,
+	mailer mailer.Mailer,
+	passwordPolicy *passwordpolicy.Policy,
+	secretPolicy *secretscan.Policy,
+	rateLimiter *middleware.RateLimiter,
+	metrics *metrics.Metrics,
+	cfg *config.Config,
 	log *slog.Logger,
 ) *Router {
 	app := fiber.New(fiber.Config{
 		AppName:               "TextVault API",
 		DisableStartupMessage: true,
+		ReadTimeout:           cfg.HTTP.ReadTimeout,
+		WriteTimeout:          cfg.HTTP.WriteTimeout,
+		IdleTimeout:           cfg.HTTP.IdleTimeout,
+		BodyLimit:             cfg.HTTP.BodyLimit,
+		// @NOTE: X-Forwarded-For is only honoured when the request comes from a trusted proxy
+		EnableTrustedProxyCheck: len(cfg.HTTP.TrustedProxies) > 0,
+		TrustedProxies:          cfg.HTTP.TrustedProxies,
+		ProxyHeader:             proxyHeader(cfg.HTTP.TrustedProxies),
 	})
 
-	accountService := account.New(log, postgres, postgres)
-	pasteService := pastes.New(log, postgres, postgres, S3, redis)
+	// @NOTE: Services use the storages through these wrappers, so their operations are measured
+	pasteStore := instrumented.NewPasteStore(postgres, metrics)
+	contentStore := instrumented.NewContentStore(S3, metrics)
+	cache := instrumented.NewCache(redis, metrics)
+
+	auth := middleware.NewAuth(log, postgres)
+	auditRecorder := audit.New(log, postgres)
+	accountService := account.New(log, postgres, postgres, contentStore, cache, mailer, passwordPolicy, auditRecorder, postgres, cfg)
+	moderationNotifier := notifier.New(mailer, cfg.PublicURL, &cfg.Moderation)
+	pasteService := pastes.New(log, pasteStore, pasteStore, contentStore, cache, postgres, postgres, postgres, postgres, postgres, secretPolicy, postgres, moderationNotifier, auditRecorder, metrics, cfg)
+	adminService := admin.New(log, postgres, postgres, contentStore, cache, postgres, postgres, auditRecorder, postgres)
+	orgService := orgs.New(log, postgres, postgres, contentStore, cache, auditRecorder)
+	healthService := health.New(log, cfg.Health,
+		health.Check{Name: "postgres", Run: postgres.Ping},
+		health.Check{Name: "redis", Run: redis.Ping, Optional: cfg.Health.RedisOptional},
+		health.Check{Name: "s3", Run: S3.BucketExists},
+	)
 
 	return &Router{
 		app:            app,
 		log:            log,
+		address:        cfg.HTTP.Address,
+		metrics:        metrics,
+		metricsConfig:  cfg.Metrics,
+		auth:           auth,
+		limiter:        rateLimiter,
 		accountService: accountService,
 		pasteService:   pasteService,
+		adminService:   adminService,
+		orgService:     orgService,
+		healthService:  healthService,
 	}
 }
 
 func (r *Router) setupAccountRoutes(app *fiber.App) {
 	accountApi := app.Group("/account")
-	accountApi.Post("/register", r.accountService.Register)
-	accountApi.Post("/login", r.accountService.Login)
-	accountApi.Get("/pastes", r.accountService.GetUserPastes)
+	// @NOTE: The limiter runs after RequireAuth, so logged in users are limited by their ID rather than by IP
+	limit := r.limiter.Limit("account")
+	accountApi.Patch("/", r.auth.RequireAuth, limit, r.accountService.UpdateProfile)
+	accountApi.Delete("/", r.auth.RequireAuth, limit, r.accountService.DeleteAccount)
+	accountApi.Post("/register", limit, r.accountService.Register)
+	accountApi.Post("/login", limit, r.accountService.Login)
+	accountApi.Post("/login/2fa", limit, r.accountService.LoginTwoFactor)
+	accountApi.Get("/oidc/:provider/login", limit, r.accountService.OIDCLogin)
+	accountApi.Get("/oidc/:provider/callback", limit, r.accountService.OIDCCallback)
+	accountApi.Post("/oidc/:provider/link", r.auth.RequireAuth, limit, r.accountService.OIDCLink)
+	accountApi.Post("/oidc/:provider/reauth", r.auth.RequireAuth, limit, r.accountService.OIDCReauth)
+	accountApi.Get("/verify", limit, r.accountService.VerifyEmail)
+	accountApi.Post("/verify/resend", r.auth.RequireAuth, limit, r.accountService.ResendVerification)
+	accountApi.Post("/password", r.auth.RequireAuth, limit, r.accountService.ChangePassword)
+	accountApi.Post("/2fa/enroll", r.auth.RequireAuth, limit, r.accountService.EnrollTwoFactor)
+	accountApi.Post("/2fa/confirm", r.auth.RequireAuth, limit, r.accountService.ConfirmTwoFactor)
+	accountApi.Delete("/2fa", r.auth.RequireAuth, limit, r.accountService.DisableTwoFactor)
+	accountApi.Post("/password/forgot", limit, r.accountService.ForgotPassword)
+	accountApi.Post("/password/reset", limit, r.accountService.ResetPassword)
+	accountApi.Get("/pastes", r.auth.RequireAuth, limit, r.accountService.GetUserPastes)
+	accountApi.Get("/audit", r.auth.RequireAuth, limit, r.accountService.ListAuditEvents)
 }
 
 func (r *Router) setupPastesRoutes(app *fiber.App) {
 	pasteApi := app.Group("/pastes")
-	pasteApi.Post("/", r.pasteService.SavePaste)
-	pasteApi.Get("/:hash", r.pasteService.GetPaste)
-	pasteApi.Delete("/:hash", r.pasteService.DeletePaste)
+	limit := r.limiter.Limit("pastes")
+	pasteApi.Post("/", r.auth.OptionalAuth, r.limiter.Limit("pasteCreate"), r.pasteService.SavePaste)
+	pasteApi.Get("/challenge", limit, r.pasteService.GetChallenge)
+	pasteApi.Get("/transfers", r.auth.RequireAuth, limit, r.pasteService.ListIncomingTransfers)
+	pasteApi.Get("/:hash", r.auth.OptionalAuth, limit, r.pasteService.GetPaste)
+	pasteApi.Patch("/:hash", r.auth.RequireAuth, limit, r.pasteService.UpdatePaste)
+	pasteApi.Delete("/:hash", r.auth.RequireAuth, limit, r.pasteService.DeletePaste)
+	pasteApi.Get("/:hash/grants", r.auth.RequireAuth, limit, r.pasteService.ListGrants)
+	pasteApi.Post("/:hash/grants", r.auth.RequireAuth, limit, r.pasteService.GrantAccess)
+	pasteApi.Delete("/:hash/grants/:id", r.auth.RequireAuth, limit, r.pasteService.RevokeAccess)
+	pasteApi.Get("/:hash/share-links", r.auth.RequireAuth, limit, r.pasteService.ListShareLinks)
+	pasteApi.Post("/:hash/share-links", r.auth.RequireAuth, limit, r.pasteService.CreateShareLink)
+	pasteApi.Get("/:hash/share-links/:id/uses", r.auth.RequireAuth, limit, r.pasteService.ListShareLinkUses)
+	pasteApi.Delete("/:hash/share-links/:id", r.auth.RequireAuth, limit, r.pasteService.RevokeShareLink)
+	pasteApi.Post("/:hash/report", r.auth.OptionalAuth, limit, r.pasteService.ReportPaste)
+	pasteApi.Post("/:hash/claim", r.auth.RequireAuth, limit, r.pasteService.ClaimPaste)
+	pasteApi.Post("/:hash/transfer", r.auth.RequireAuth, limit, r.pasteService.TransferPaste)
+	pasteApi.Delete("/:hash/transfer", r.auth.RequireAuth, limit, r.pasteService.CancelTransfer)
+	pasteApi.Post("/:hash/transfer/accept", r.auth.RequireAuth, limit, r.pasteService.AcceptTransfer)
+	pasteApi.Post("/:hash/transfer/decline", r.auth.RequireAuth, limit, r.pasteService.DeclineTransfer)
+}
+
+func (r *Router) setupAdminRoutes(app *fiber.App) {
+	adminApi := app.Group("/admin", r.auth.RequireAuth, r.auth.RequireAdmin, r.limiter.Limit("admin"))
+	adminApi.Get("/users", r.adminService.ListUsers)
+	adminApi.Post("/users/:id/ban", r.adminService.BanUser)
+	adminApi.Delete("/users/:id/ban", r.adminService.UnbanUser)
+	adminApi.Delete("/users/:id/pastes", r.adminService.PurgeUserPastes)
+	adminApi.Delete("/users/:id/2fa", r.adminService.ResetTwoFactor)
+	adminApi.Delete("/pastes/:hash", r.adminService.DeletePaste)
+	adminApi.Get("/reports", r.adminService.ListReports)
+	adminApi.Post("/reports/:id/resolve", r.adminService.ResolveReport)
+	adminApi.Get("/audit", r.adminService.ListAuditEvents)
+}
+
+func (r *Router) setupOrgRoutes(app *fiber.App) {
+	orgApi := app.Group("/orgs", r.auth.RequireAuth, r.limiter.Limit("orgs"))
+	orgApi.Post("/", r.orgService.CreateOrganization)
+	orgApi.Get("/", r.orgService.ListOrganizations)
+	orgApi.Get("/:id", r.orgService.GetOrganization)
+	orgApi.Delete("/:id", r.orgService.DeleteOrganization)
+	orgApi.Get("/:id/pastes", r.orgService.GetOrganizationPastes)
+	orgApi.Post("/:id/members", r.orgService.AddMember)
+	orgApi.Patch("/:id/members/:userId", r.orgService.UpdateMember)
+	orgApi.Delete("/:id/members/:userId", r.orgService.RemoveMember)
+}
+
+func (r *Router) setupHealthRoutes(app *fiber.App) {
+	app.Get("/healthz", r.healthService.Liveness)
+	app.Get("/readyz", r.healthService.Readiness)
 }
 
 func (r *Router) setupRoutes() {
+	r.app.Use(tracing.Middleware())
+	r.app.Use(middleware.RequestLogger(r.log))
+
+	if r.metricsConfig.Enabled {
+		r.app.Use(r.metrics.Middleware())
+		r.app.Get(r.metricsConfig.Path, r.metrics.Handler())
+	}
+
+	r.setupHealthRoutes(r.app)
 	r.setupAccountRoutes(r.app)
 	r.setupPastesRoutes(r.app)
+	r.setupAdminRoutes(r.app)
+	r.setupOrgRoutes(r.app)
 }
 
-func (r *Router) MustRun() {
-	const prefix = "internal.router.MustRun"
+// Run sets up the routes and serves HTTP requests until Shutdown is called.
+// It returns nil after a shutdown and the error otherwise.
+func (r *Router) Run() error {
+	const prefix = "internal.router.Run"
 	log := r.log.With(
 		slog.String("op", prefix),
 	)
 
-	log.Info("Setupping routes")
+	log.Info("Setting up routes")
 
 	r.setupRoutes()
 
-	log.Info("Starting router")
-	if err := r.run(); err != nil {
-		panic(err)
-	}
+	log.Info("Starting HTTP server", slog.String("address", r.address))
+
+	return r.app.Listen(r.address)
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests to finish until ctx is done.
+func (r *Router) Shutdown(ctx context.Context) error {
+	return r.app.ShutdownWithContext(ctx)
 }
 
-func (r *Router) run() error {
-	fmt.Println("Server started on port 8080")
+// proxyHeader returns the header with the client IP, which is only set by trusted proxies.
+func proxyHeader(trustedProxies []string) string {
+	if len(trustedProxies) == 0 {
+		return ""
+	}
 
-	return r.app.Listen(":8080")
+	return fiber.HeaderXForwardedFor
 }
@@ -1,32 +1,91 @@
 package account
 
 import (
+	"TextVault/internal/config"
 	"TextVault/internal/lib/jwt"
 	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/lib/passwordpolicy"
+	"TextVault/internal/lib/validate"
+	"TextVault/internal/mailer"
 	"TextVault/internal/middleware"
 	"TextVault/internal/storage"
 	"TextVault/internal/storage/models"
 	"TextVault/pkg/passwordhash"
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+const verificationTokenTTL = 24 * time.Hour
+
+// dummyPasswordHash is validated against when a login names an unknown user.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := passwordhash.New("textvault-dummy-password")
+	return hash
+})
+
 type Service struct {
-	accountSaver  AccountSaver
-	accountGetter AccountGetter
-	log           *slog.Logger
+	accountSaver   AccountSaver
+	accountGetter  AccountGetter
+	pasteProvider  PasteProvider
+	cacheProvider  CacheProvider
+	mailer         mailer.Mailer
+	publicURL      string
+	oidcProviders  map[string]*oidcProvider
+	loginPolicy    config.LoginConfig
+	passwordPolicy *passwordpolicy.Policy
+	auditRecorder  AuditRecorder
+	auditGetter    AuditGetter
+	log            *slog.Logger
 }
 
 type AccountSaver interface {
 	SaveUser(ctx context.Context, username, email, password string) (int64, error)
+	SaveUserWithIdentity(ctx context.Context, user *models.User, identity *models.Identity) (int64, error)
+	SaveIdentity(ctx context.Context, identity *models.Identity) error
+	UpdateUser(ctx context.Context, user *models.User) error
+	UpdatePassword(ctx context.Context, id int64, passwordHash string) (int64, error)
+	RehashPassword(ctx context.Context, id int64, oldHash, newHash string) error
+	DeleteUser(ctx context.Context, id int64, anonymisePastes bool) ([]string, error)
+	VerifyUser(ctx context.Context, id int64, email string) error
+	SavePasswordReset(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int64, error)
+	SetTOTPSecret(ctx context.Context, id int64, secret string) error
+	EnableTOTP(ctx context.Context, id int64, recoveryCodeHashes []string) error
+	DisableTOTP(ctx context.Context, id int64) error
+	UseTOTPStep(ctx context.Context, id int64, step int64) error
+	UseRecoveryCode(ctx context.Context, id int64, codeHash string) error
 }
 
 type AccountGetter interface {
 	GetUser(ctx context.Context, username string) (models.User, error)
+	GetUserByID(ctx context.Context, id int64) (models.User, error)
 	GetUserPastes(ctx context.Context, userID int64) ([]models.Paste, error)
+	GetUserByIdentity(ctx context.Context, provider, subject string) (models.User, error)
+	GetUserByResetToken(ctx context.Context, tokenHash string) (models.User, error)
+}
+
+// PasteProvider is an interface that provides a method for deleting paste content from s3 storage.
+type PasteProvider interface {
+	DeletePaste(ctx context.Context, objectKey string) error
+}
+
+// CacheProvider is an interface that provides methods for counting attempts within a time window,
+// keeping short-lived login state and evicting cached pastes.
+type CacheProvider interface {
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	SetWithTTL(ctx context.Context, key string, value string, ttl time.Duration) error
+	GetDel(ctx context.Context, key string) (string, error)
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Delete(ctx context.Context, key string) error
 }
 
 type loginRequest struct {
@@ -40,34 +99,64 @@ type registerRequest struct {
 	Password string `json:"p"`
 }
 
-func New(log *slog.Logger, accountSaver AccountSaver, accountGetter AccountGetter) *Service {
+func New(log *slog.Logger,
+	accountSaver AccountSaver,
+	accountGetter AccountGetter,
+	pasteProvider PasteProvider,
+	cacheProvider CacheProvider,
+	mailer mailer.Mailer,
+	passwordPolicy *passwordpolicy.Policy,
+	auditRecorder AuditRecorder,
+	auditGetter AuditGetter,
+	cfg *config.Config,
+) *Service {
+	oidcProviders := make(map[string]*oidcProvider, len(cfg.OIDC))
+	for name, providerConfig := range cfg.OIDC {
+		oidcProviders[name] = newOIDCProvider(name, providerConfig, cfg.PublicURL)
+	}
+
 	return &Service{
-		accountSaver:  accountSaver,
-		accountGetter: accountGetter,
-		log:           log,
+		accountSaver:   accountSaver,
+		accountGetter:  accountGetter,
+		pasteProvider:  pasteProvider,
+		cacheProvider:  cacheProvider,
+		mailer:         mailer,
+		publicURL:      cfg.PublicURL,
+		oidcProviders:  oidcProviders,
+		loginPolicy:    cfg.Login,
+		passwordPolicy: passwordPolicy,
+		auditRecorder:  auditRecorder,
+		auditGetter:    auditGetter,
+		log:            log,
 	}
 }
 
 // Login authenticates a user by validating their username and password.
 // It requires a valid username and password in the request body.
 // If the request body is invalid, it returns a 400 Bad Request status with an error message.
-// If the username or password is incorrect, it returns a 401 Unauthorized status with an error message.
+// If the username or password is incorrect, it returns a 401 Unauthorized status with an error message,
+// the same one whether or not the user exists.
+// After repeated failures for the user or the client IP, logins are locked for an exponentially
+// growing time and it returns a 429 Too Many Requests status with a Retry-After header.
+// If the user is banned, it returns a 403 Forbidden status with the ban reason and expiry.
 // If any other error occurs during authentication, it returns a 500 Internal Server Error status with an error message.
 // On successful authentication, it returns a 200 OK status with a JWT token in the response.
+// If the user has 2FA enabled, the response carries a short-lived challenge token instead,
+// which has to be exchanged for a JWT token by LoginTwoFactor.
 func (s *Service) Login(c *fiber.Ctx) error {
 	const prefix = "internal.router.services.account.Login"
 
 	p := new(loginRequest)
 
 	if err := c.BodyParser(p); err != nil {
-		s.log.Error("Failed to parse login request", sl.Err(err))
+		sl.FromContext(c.UserContext(), s.log).Error("Failed to parse login request", sl.Err(err))
 
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "email/username/password is required",
 		})
 	}
 
-	log := s.log.With(
+	log := sl.FromContext(c.UserContext(), s.log).With(
 		slog.String("op", prefix),
 		slog.String("username", p.Username),
 	)
@@ -80,35 +169,127 @@ func (s *Service) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	user, err := s.accountGetter.GetUser(c.Context(), p.Username)
+	user, err := s.accountGetter.GetUser(c.UserContext(), p.Username)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		log.Error("Failed to get user", sl.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	userFound := err == nil
+
+	var knownUser *models.User
+	if userFound {
+		knownUser = &user
+	}
+
+	subjects := loginSubjects(s.loginPolicy, knownUser, p.Username, c.IP())
+
+	// @NOTE: The lockout fails open, so a cache outage doesn't lock everybody out
+	lockout, err := s.loginLockout(c.UserContext(), subjects)
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			s.log.Warn("Failed to find user")
+		log.Error("Failed to check login lockout", sl.Err(err))
+	}
 
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "user not found",
-			})
-		}
+	if lockout > 0 {
+		log.Warn("Login attempt while locked")
 
-		s.log.Error("Failed to get user", sl.Err(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Internal server error",
+		s.recordFailedLogin(c, knownUser, p.Username, "locked")
+
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(lockout.Seconds()))))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "too many failed login attempts, try again later",
 		})
 	}
 
-	if !passwordhash.Validate(p.Password, user.PasswordHash) {
-		s.log.Info("invalid credentials")
+	// @NOTE: An unknown user, or one without a password, gets the same response after the same amount
+	// of hashing work, so the response doesn't reveal whether the user exists
+	hasPassword := userFound && user.HasPassword()
+
+	passwordHash := user.PasswordHash
+	if !hasPassword {
+		passwordHash = dummyPasswordHash()
+	}
+
+	if !passwordhash.Validate(p.Password, passwordHash) || !hasPassword {
+		log.Info("invalid credentials")
+
+		s.recordLoginFailure(c, subjects, log)
+		s.recordFailedLogin(c, knownUser, p.Username, "invalid credentials")
 
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "invalid credentials",
 		})
 	}
 
+	s.resetLoginFailures(c.UserContext(), subjects, log)
+
+	if passwordhash.NeedsRehash(user.PasswordHash) {
+		s.rehashPassword(c.UserContext(), user, p.Password, log)
+	}
+
+	return s.completeLogin(c, user, "password", log)
+}
+
+// rehashPassword transparently upgrades an outdated password hash after a successful login.
+// Failures are only logged, because the old hash keeps working.
+func (s *Service) rehashPassword(ctx context.Context, user models.User, password string, log *slog.Logger) {
+	passwordHash, err := passwordhash.New(password)
+	if err != nil {
+		log.Error("Failed to rehash password", sl.Err(err))
+		return
+	}
+
+	if err := s.accountSaver.RehashPassword(ctx, user.ID, user.PasswordHash, passwordHash); err != nil {
+		log.Error("Failed to save rehashed password", sl.Err(err))
+		return
+	}
+
+	log.Info("Password rehashed with current parameters")
+}
+
+// completeLogin finishes the login of an authenticated user. Banned users are rejected, users with 2FA
+// enabled get a challenge token, and everybody else gets a JWT token. Method names how the user
+// authenticated in the audit log.
+func (s *Service) completeLogin(c *fiber.Ctx, user models.User, method string, log *slog.Logger) error {
+	if user.IsBanned {
+		log.Warn("Banned user attempted to login")
+
+		s.recordFailedLogin(c, &user, user.Username, "banned")
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":  "user is banned",
+			"reason": user.BanReason,
+			"until":  user.BannedUntil,
+		})
+	}
+
+	if user.TOTPEnabled {
+		challenge, err := jwt.NewUserPurposeToken(user, jwt.PurposeTwoFactor, twoFactorChallengeTTL)
+		if err != nil {
+			log.Error("failed to generate challenge token", sl.Err(err))
+
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "failed to create token",
+			})
+		}
+
+		log.Info("Credentials accepted, two-factor code required")
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"twoFactorRequired": true,
+			"challenge":         challenge,
+		})
+	}
+
 	log.Info("Successfully logged in user")
 
-	token, err := jwt.NewToken(user)
+	s.recordUserEvent(c, models.AuditLogin, user.ID, method)
+
+	token, err := s.newSessionToken(c, user, models.AuditLogin)
 	if err != nil {
-		s.log.Error("failed to generate token", sl.Err(err))
+		log.Error("failed to generate token", sl.Err(err))
 
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "failed to create token",
@@ -122,8 +303,10 @@ func (s *Service) Login(c *fiber.Ctx) error {
 
 // Register creates a new user in the database and returns the user ID as a JSON response.
 // It requires a valid username, email and password in the request body.
+// A verification link is sent to the email; until it is opened the account can't create public pastes.
 // If the request body is invalid, it returns a 400 Bad Request status with an error message.
-// If the password is empty, it returns a 400 Bad Request status with an error message.
+// If the username, email or password is invalid, it returns a 400 Bad Request status with field-level errors.
+// If the username or email is taken, it returns a 409 Conflict status naming the field.
 // If any other error occurs during registration, it returns a 500 Internal Server Error status with an error message.
 // On successful registration, it returns a 200 OK status with the user ID in the response.
 func (s *Service) Register(c *fiber.Ctx) error {
@@ -132,19 +315,31 @@ func (s *Service) Register(c *fiber.Ctx) error {
 	p := new(registerRequest)
 
 	if err := c.BodyParser(p); err != nil {
-		s.log.Error("Failed to parse register request", sl.Err(err))
-		return err
+		sl.FromContext(c.UserContext(), s.log).Error("Failed to parse register request", sl.Err(err))
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "email/username/password is required",
+		})
 	}
 
-	log := s.log.With(
+	log := sl.FromContext(c.UserContext(), s.log).With(
 		slog.String("op", prefix),
 		slog.String("username", p.Username),
 	)
 
-	if len(p.Password) == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "password is required",
-		})
+	fields := make(map[string][]string)
+	if problems := validate.Username(p.Username); len(problems) > 0 {
+		fields["u"] = problems
+	}
+	if problems := validate.Email(p.Mail); len(problems) > 0 {
+		fields["m"] = problems
+	}
+	if problems := s.passwordPolicy.Validate(p.Password, p.Username, p.Mail); len(problems) > 0 {
+		fields["p"] = problems
+	}
+
+	if len(fields) > 0 {
+		return s.validationErrorResponse(c, fields)
 	}
 
 	passwordHash, err := passwordhash.New(p.Password)
@@ -156,13 +351,16 @@ func (s *Service) Register(c *fiber.Ctx) error {
 		})
 	}
 
-	id, err := s.accountSaver.SaveUser(c.Context(), p.Username, p.Mail, passwordHash)
+	id, err := s.accountSaver.SaveUser(c.UserContext(), p.Username, p.Mail, passwordHash)
 	if err != nil {
-		log.Error("failed to save user", sl.Err(err))
+		return s.handleSaveUserError(c, err, log)
+	}
 
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "failed to save user",
-		})
+	s.recordUserEvent(c, models.AuditRegister, id, "")
+
+	// @NOTE: The account is created even if the email can't be sent; the link can be requested again
+	if err := s.sendVerificationEmail(c.UserContext(), id, p.Mail); err != nil {
+		log.Error("Failed to send verification email", sl.Err(err))
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -170,38 +368,121 @@ func (s *Service) Register(c *fiber.Ctx) error {
 	})
 }
 
+// VerifyEmail marks the email of a user as verified using the token from the "token" query parameter.
+// If the token is invalid, expired or was issued for an email the user no longer has,
+// it returns a 400 Bad Request status with an error message.
+// On success, it returns a 200 OK status with an empty response body.
+func (s *Service) VerifyEmail(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.VerifyEmail"
+
+	claims, err := jwt.ValidatePurposeToken(c.Query("token"), jwt.PurposeVerifyEmail)
+	if err != nil {
+		sl.FromContext(c.UserContext(), s.log).Warn("Invalid verification token", slog.String("op", prefix), sl.Err(err))
+
+		return s.invalidVerificationResponse(c)
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int64("user_id", claims.ID),
+	)
+
+	err = s.accountSaver.VerifyUser(c.UserContext(), claims.ID, claims.Email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("Verification token doesn't match user")
+
+			return s.invalidVerificationResponse(c)
+		}
+
+		log.Error("Failed to verify user", sl.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	log.Info("Email verified")
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ResendVerification sends a new verification link to the email of the authenticated user.
+// If the email is already verified, it returns a 409 Conflict status with an error message.
+func (s *Service) ResendVerification(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.ResendVerification"
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return s.unauthorizedResponse(c)
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int64("user_id", principal.ID),
+	)
+
+	if principal.IsVerified {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "email is already verified",
+		})
+	}
+
+	if err := s.sendVerificationEmail(c.UserContext(), principal.ID, principal.Email); err != nil {
+		log.Error("Failed to send verification email", sl.Err(err))
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to send verification email",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// sendVerificationEmail sends a signed, expiring verification link for the given user and email.
+func (s *Service) sendVerificationEmail(ctx context.Context, userID int64, email string) error {
+	token, err := jwt.NewPurposeToken(userID, email, jwt.PurposeVerifyEmail, verificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/account/verify?token=%s", s.publicURL, url.QueryEscape(token))
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your TextVault email",
+		Body: fmt.Sprintf("Open the link below to verify your email. It expires in %s.\n\n%s\n",
+			verificationTokenTTL, link),
+	})
+}
+
 // GetUserPastes retrieves all pastes created by a specific user from the database.
-// It requires a valid authorization token to authenticate the user and extract their user ID.
-// If the token is invalid or missing, it returns a 401 Unauthorized status with an error message.
+// It requires the request to be authenticated by RequireAuth.
+// If the caller is not resolved, it returns a 401 Unauthorized status with an error message.
 // If the user does not have any pastes, it returns a 401 Unauthorized status with a specific error message.
 // If any other error occurs during retrieval, it returns a 500 Internal Server Error status with an error message.
 // On successful retrieval, it returns a 200 OK status with the pastes in the response.
 func (s *Service) GetUserPastes(c *fiber.Ctx) error {
 	const prefix = "internal.router.services.account.GetUserPastes"
 
-	tokenString, err := middleware.ExtractToken(c)
-	if err != nil {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
 		return s.unauthorizedResponse(c)
 	}
+	userID := principal.ID
 
-	userID, err := middleware.GetUserIDFromToken(tokenString)
-	if err != nil {
-		return s.unauthorizedResponse(c)
-	}
-
-	log := s.log.With(
+	log := sl.FromContext(c.UserContext(), s.log).With(
 		slog.String("op", prefix),
 		slog.Int64("user_id", userID),
 	)
 
 	log.Info("Attempting to get pastes by user")
 
-	pastes, err := s.accountGetter.GetUserPastes(c.Context(), userID)
+	pastes, err := s.accountGetter.GetUserPastes(c.UserContext(), userID)
 	if err != nil {
 		return s.handleGetPastesError(c, err, log)
 	}
 
-	s.log.Info("Successfully got pastes by user", slog.Int("count", len(pastes)), slog.Int64("user_id", userID))
+	sl.FromContext(c.UserContext(), s.log).Info("Successfully got pastes by user", slog.Int("count", len(pastes)), slog.Int64("user_id", userID))
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"pastes": pastes,
@@ -217,6 +498,44 @@ func (s *Service) unauthorizedResponse(c *fiber.Ctx) error {
 	})
 }
 
+// handleSaveUserError responds to a failed insert or update of a user. A taken username or email
+// returns a 409 Conflict status naming the field; anything else is an internal error.
+func (s *Service) handleSaveUserError(c *fiber.Ctx, err error, log *slog.Logger) error {
+	switch {
+	case errors.Is(err, storage.ErrUsernameTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "username is already taken",
+			"field": "u",
+		})
+	case errors.Is(err, storage.ErrEmailTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "email is already taken",
+			"field": "m",
+		})
+	default:
+		log.Error("failed to save user", sl.Err(err))
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to save user",
+		})
+	}
+}
+
+// validationErrorResponse returns a 400 Bad Request status with the problems of every invalid request field.
+// The keys of fields are the JSON names of the request fields.
+func (s *Service) validationErrorResponse(c *fiber.Ctx, fields map[string][]string) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":  "validation failed",
+		"fields": fields,
+	})
+}
+
+func (s *Service) invalidVerificationResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "invalid or expired verification token",
+	})
+}
+
 func (s *Service) handleGetPastesError(c *fiber.Ctx, err error, log *slog.Logger) error {
 	switch {
 	case errors.Is(err, storage.ErrUserDontHavePastes):
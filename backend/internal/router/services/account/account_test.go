@@ -0,0 +1,457 @@
+package account
+
+import (
+	"TextVault/internal/config"
+	"TextVault/internal/lib/jwt"
+	"TextVault/internal/lib/passwordpolicy"
+	"TextVault/internal/mailer"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"TextVault/pkg/passwordhash"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func init() {
+	// @NOTE: Cheap hashes keep the tests fast; the parameters don't matter to them
+	passwordhash.SetDefault(passwordhash.NewHasher(passwordhash.Params{
+		Memory:      64,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}))
+}
+
+// fakeStore holds the accounts, linked identities and recovery codes the account handlers work on.
+// AccountSaver and AccountGetter are embedded for the storage methods no account test reaches.
+type fakeStore struct {
+	AccountSaver
+	AccountGetter
+
+	mu         sync.Mutex
+	nextID     int64
+	users      map[int64]*models.User
+	identities map[string]int64
+	deleted    []int64
+
+	// recoveryCodes maps the hashes of unused recovery codes to their users.
+	recoveryCodes map[string]int64
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		users:         map[int64]*models.User{},
+		identities:    map[string]int64{},
+		recoveryCodes: map[string]int64{},
+	}
+}
+
+// enableTwoFactor turns on 2FA for the user with the recovery codes. The TOTP secret is never used,
+// so the tests sign in with recovery codes.
+func (f *fakeStore) enableTwoFactor(id int64, recoveryCodes ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.users[id].TOTPEnabled = true
+	f.users[id].TOTPSecret = "JBSWY3DPEHPK3PXP"
+
+	for _, code := range recoveryCodes {
+		f.recoveryCodes[hashToken(strings.ReplaceAll(code, "-", ""))] = id
+	}
+}
+
+// addUser saves a user with the password, which may be empty for a password-less account.
+func (f *fakeStore) addUser(t *testing.T, username, email, password string) models.User {
+	t.Helper()
+
+	var hash string
+	if password != "" {
+		var err error
+		if hash, err = passwordhash.New(password); err != nil {
+			t.Fatalf("hash password: %v", err)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	user := &models.User{ID: f.nextID, Username: username, Email: email, PasswordHash: hash, IsVerified: true}
+	f.users[user.ID] = user
+
+	return *user
+}
+
+func (f *fakeStore) user(id int64) models.User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return *f.users[id]
+}
+
+func (f *fakeStore) GetUser(_ context.Context, username string) (models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, user := range f.users {
+		if strings.EqualFold(user.Username, username) || strings.EqualFold(user.Email, username) {
+			return *user, nil
+		}
+	}
+
+	return models.User{}, storage.ErrUserNotFound
+}
+
+func (f *fakeStore) GetUserByID(_ context.Context, id int64) (models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user, ok := f.users[id]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return *user, nil
+}
+
+func (f *fakeStore) GetUserByIdentity(_ context.Context, provider, subject string) (models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id, ok := f.identities[provider+"/"+subject]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return *f.users[id], nil
+}
+
+func (f *fakeStore) SaveUserWithIdentity(_ context.Context, user *models.User, identity *models.Identity) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, other := range f.users {
+		if strings.EqualFold(other.Email, user.Email) {
+			return 0, storage.ErrEmailTaken
+		}
+	}
+
+	f.nextID++
+	saved := *user
+	saved.ID = f.nextID
+	f.users[saved.ID] = &saved
+	f.identities[identity.Provider+"/"+identity.Subject] = saved.ID
+
+	return saved.ID, nil
+}
+
+func (f *fakeStore) SaveIdentity(_ context.Context, identity *models.Identity) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := identity.Provider + "/" + identity.Subject
+	if _, ok := f.identities[key]; ok {
+		return storage.ErrIdentityTaken
+	}
+
+	f.identities[key] = identity.UserID
+
+	return nil
+}
+
+func (f *fakeStore) UpdateUser(_ context.Context, user *models.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	saved := *user
+	f.users[user.ID] = &saved
+
+	return nil
+}
+
+func (f *fakeStore) UpdatePassword(_ context.Context, id int64, passwordHash string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user := f.users[id]
+	user.PasswordHash = passwordHash
+	user.TokenVersion++
+
+	return user.TokenVersion, nil
+}
+
+func (f *fakeStore) UseRecoveryCode(_ context.Context, id int64, codeHash string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if owner, ok := f.recoveryCodes[codeHash]; !ok || owner != id {
+		return storage.ErrInvalidTwoFactor
+	}
+
+	delete(f.recoveryCodes, codeHash)
+
+	return nil
+}
+
+func (f *fakeStore) DeleteUser(_ context.Context, id int64, _ bool) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.users, id)
+	f.deleted = append(f.deleted, id)
+
+	return nil, nil
+}
+
+// fakeCache keeps login attempts, OIDC states and one-time tokens without expiring them. TTL reports
+// a minute left for every present key.
+type fakeCache struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{values: map[string]string{}}
+}
+
+func (f *fakeCache) Incr(_ context.Context, key string, _ time.Duration) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n, _ := strconv.ParseInt(f.values[key], 10, 64)
+	n++
+	f.values[key] = strconv.FormatInt(n, 10)
+
+	return n, nil
+}
+
+func (f *fakeCache) SetWithTTL(_ context.Context, key, value string, _ time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.values[key] = value
+
+	return nil
+}
+
+func (f *fakeCache) GetDel(_ context.Context, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	value, ok := f.values[key]
+	if !ok {
+		return "", storage.ErrCacheMiss
+	}
+	delete(f.values, key)
+
+	return value, nil
+}
+
+func (f *fakeCache) TTL(_ context.Context, key string) (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.values[key]; ok {
+		return time.Minute, nil
+	}
+
+	return -2, nil
+}
+
+func (f *fakeCache) Delete(_ context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.values, key)
+
+	return nil
+}
+
+func (f *fakeCache) DeletePaste(context.Context, string) error {
+	return nil
+}
+
+// fakeMailer records the sent messages.
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (f *fakeMailer) Send(_ context.Context, msg mailer.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sent = append(f.sent, msg)
+
+	return nil
+}
+
+// fakeAudit keeps the account events; listing them is left to the storage tests.
+type fakeAudit struct {
+	mu     sync.Mutex
+	events []models.AuditEvent
+}
+
+func (f *fakeAudit) Record(_ *fiber.Ctx, event models.AuditEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.events = append(f.events, event)
+}
+
+func (f *fakeAudit) GetAuditEvents(context.Context, models.AuditFilter, int, int) ([]models.AuditEvent, error) {
+	return nil, nil
+}
+
+// actions returns the recorded actions with the outcome.
+func (f *fakeAudit) actions(outcome string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var actions []string
+	for _, event := range f.events {
+		if event.Outcome == outcome || (outcome == models.AuditSuccess && event.Outcome == "") {
+			actions = append(actions, event.Action)
+		}
+	}
+
+	return actions
+}
+
+// testEnv is an account service on in-memory fakes, served like the router serves it. Its requests share
+// cookies like the requests of one browser.
+type testEnv struct {
+	app     *fiber.App
+	store   *fakeStore
+	cache   *fakeCache
+	mailer  *fakeMailer
+	audit   *fakeAudit
+	cookies map[string]string
+}
+
+func newTestEnv(t *testing.T, cfg *config.Config) *testEnv {
+	t.Helper()
+
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	cfg.PublicURL = "https://textvault.test"
+	cfg.Login = config.LoginConfig{
+		UserMaxAttempts: 5,
+		IPMaxAttempts:   20,
+		FailureWindow:   time.Hour,
+		BaseLockout:     time.Minute,
+		MaxLockout:      time.Hour,
+	}
+
+	policy, err := passwordpolicy.New(&config.PasswordConfig{MinLength: 8, MaxLength: 72})
+	if err != nil {
+		t.Fatalf("passwordpolicy.New() error = %v", err)
+	}
+
+	env := &testEnv{
+		store:   newFakeStore(),
+		cache:   newFakeCache(),
+		mailer:  &fakeMailer{},
+		audit:   &fakeAudit{},
+		cookies: map[string]string{},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := New(log, env.store, env.store, env.cache, env.cache, env.mailer, policy, env.audit, env.audit, cfg)
+	auth := middleware.NewAuth(log, env.store)
+
+	env.app = fiber.New()
+	accountApi := env.app.Group("/account")
+	accountApi.Patch("/", auth.RequireAuth, service.UpdateProfile)
+	accountApi.Delete("/", auth.RequireAuth, service.DeleteAccount)
+	accountApi.Post("/login", service.Login)
+	accountApi.Post("/login/2fa", service.LoginTwoFactor)
+	accountApi.Get("/oidc/:provider/login", service.OIDCLogin)
+	accountApi.Get("/oidc/:provider/callback", service.OIDCCallback)
+	accountApi.Post("/oidc/:provider/link", auth.RequireAuth, service.OIDCLink)
+	accountApi.Post("/oidc/:provider/reauth", auth.RequireAuth, service.OIDCReauth)
+	accountApi.Post("/password", auth.RequireAuth, service.ChangePassword)
+	accountApi.Delete("/2fa", auth.RequireAuth, service.DisableTwoFactor)
+
+	return env
+}
+
+// response is a response of the test app with its decoded JSON body, if any.
+type response struct {
+	status   int
+	location string
+	body     map[string]any
+}
+
+func (r response) string(key string) string {
+	value, _ := r.body[key].(string)
+	return value
+}
+
+// do sends a request with the JSON body, authenticated as the user if it is not nil.
+func (env *testEnv) do(t *testing.T, method, target string, user *models.User, body any) response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		reader = strings.NewReader(string(must(json.Marshal(body))))
+	}
+
+	req := httptest.NewRequest(method, target, reader)
+	req.Header.Set("Content-Type", "application/json")
+
+	if user != nil {
+		token, err := jwt.NewToken(env.store.user(user.ID))
+		if err != nil {
+			t.Fatalf("NewToken() error = %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	for name, value := range env.cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	resp, err := env.app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, target, err)
+	}
+	defer resp.Body.Close()
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Value == "" || (!cookie.Expires.IsZero() && cookie.Expires.Before(time.Now())) {
+			delete(env.cookies, cookie.Name)
+		} else {
+			env.cookies[cookie.Name] = cookie.Value
+		}
+	}
+
+	r := response{status: resp.StatusCode, location: resp.Header.Get("Location")}
+	if data, _ := io.ReadAll(resp.Body); len(data) > 0 {
+		_ = json.Unmarshal(data, &r.body)
+	}
+
+	return r
+}
+
+func must[T any](value T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+
+	return value
+}
@@ -0,0 +1,149 @@
+package account
+
+import (
+	"TextVault/internal/lib/jwt"
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage/models"
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+
+	// maxAuditUsernameLength bounds the unvalidated usernames of failed logins kept in the audit log.
+	maxAuditUsernameLength = 100
+)
+
+// AuditRecorder is an interface that provides a method for recording security-relevant events.
+type AuditRecorder interface {
+	Record(c *fiber.Ctx, event models.AuditEvent)
+}
+
+// AuditGetter is an interface that provides a method for querying the audit log.
+type AuditGetter interface {
+	GetAuditEvents(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]models.AuditEvent, error)
+}
+
+// auditEventResponse is a struct that represents an audit event in responses.
+type auditEventResponse struct {
+	ID         int64     `json:"id"`
+	Action     string    `json:"action"`
+	Outcome    string    `json:"outcome"`
+	ActorID    *int64    `json:"actorId,omitempty"`
+	TargetType string    `json:"targetType,omitempty"`
+	TargetID   string    `json:"targetId,omitempty"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	Details    string    `json:"details,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// ListAuditEvents returns the audit events of the authenticated user: their own actions and the events targeting
+// their account, such as failed logins. The "action" query parameter filters by action.
+// The result is paginated with the "limit" and "offset" query parameters, newest first.
+// On success, it returns a 200 OK status with the events in the response.
+func (s *Service) ListAuditEvents(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.ListAuditEvents"
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return s.unauthorizedResponse(c)
+	}
+
+	limit := c.QueryInt("limit", defaultAuditLimit)
+	offset := c.QueryInt("offset", 0)
+
+	if limit <= 0 || limit > maxAuditLimit {
+		limit = defaultAuditLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int64("user_id", principal.ID),
+	)
+
+	filter := models.AuditFilter{
+		UserID: &principal.ID,
+		Action: c.Query("action"),
+	}
+
+	events, err := s.auditGetter.GetAuditEvents(c.UserContext(), filter, limit, offset)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	response := make([]auditEventResponse, 0, len(events))
+	for _, event := range events {
+		response = append(response, auditEventResponse{
+			ID:         event.ID,
+			Action:     event.Action,
+			Outcome:    event.Outcome,
+			ActorID:    event.ActorID,
+			TargetType: event.TargetType,
+			TargetID:   event.TargetID,
+			IP:         event.IP,
+			UserAgent:  event.UserAgent,
+			Details:    event.Details,
+			CreatedAt:  event.CreatedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"events": response,
+	})
+}
+
+// recordUserEvent records an event of the user's account. The user is both its actor and its target.
+func (s *Service) recordUserEvent(c *fiber.Ctx, action string, userID int64, details string) {
+	s.auditRecorder.Record(c, models.AuditEvent{
+		Action:     action,
+		ActorID:    &userID,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.FormatInt(userID, 10),
+		Details:    details,
+	})
+}
+
+// recordFailedLogin records a failed login of the user, or of the username if no such user exists.
+func (s *Service) recordFailedLogin(c *fiber.Ctx, user *models.User, username, reason string) {
+	if len(username) > maxAuditUsernameLength {
+		username = username[:maxAuditUsernameLength]
+	}
+
+	event := models.AuditEvent{
+		Action:     models.AuditLogin,
+		Outcome:    models.AuditFailure,
+		TargetType: models.AuditTargetUsername,
+		TargetID:   username,
+		Details:    reason,
+	}
+
+	if user != nil {
+		event.TargetType = models.AuditTargetUser
+		event.TargetID = strconv.FormatInt(user.ID, 10)
+	}
+
+	s.auditRecorder.Record(c, event)
+}
+
+// newSessionToken issues a JWT token for the user and records its issuance for the given reason.
+func (s *Service) newSessionToken(c *fiber.Ctx, user models.User, reason string) (string, error) {
+	token, err := jwt.NewToken(user)
+	if err != nil {
+		return "", err
+	}
+
+	s.recordUserEvent(c, models.AuditTokenIssue, user.ID, reason)
+
+	return token, nil
+}
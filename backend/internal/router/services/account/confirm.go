@@ -0,0 +1,90 @@
+package account
+
+import (
+	"TextVault/internal/lib/jwt"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"TextVault/pkg/passwordhash"
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	errPasswordRequired     = errors.New("password is required")
+	errReauthRequired       = errors.New("reauthentication token is required")
+	errReauthOrCodeRequired = errors.New("reauthentication token or two-factor code is required")
+	errInvalidConfirmation  = errors.New("invalid credentials")
+)
+
+// confirmation are the credentials confirming a sensitive action of the authenticated user.
+type confirmation struct {
+	Password string
+	Reauth   string
+	Code     string
+}
+
+// confirmUser checks that the caller really is the user before a sensitive action. Users with a password
+// confirm with it. Users without one confirm with a reauthentication token from a fresh login at their
+// OpenID Connect provider (see OIDCReauth) or, if allowCode is set and they have 2FA enabled, with a 2FA code,
+// which is consumed and limited by checkTwoFactorCode. It returns an error naming the missing credentials,
+// errInvalidConfirmation, ErrInvalidTwoFactor or errTwoFactorLocked.
+func (s *Service) confirmUser(c *fiber.Ctx, user models.User, confirm confirmation, allowCode bool, log *slog.Logger) error {
+	if user.HasPassword() {
+		if confirm.Password == "" {
+			return errPasswordRequired
+		}
+
+		if !passwordhash.Validate(confirm.Password, user.PasswordHash) {
+			return errInvalidConfirmation
+		}
+
+		return nil
+	}
+
+	codeAllowed := allowCode && user.TOTPEnabled
+
+	switch {
+	case confirm.Reauth != "":
+		claims, err := jwt.ValidatePurposeToken(confirm.Reauth, jwt.PurposeReauth)
+		if err != nil || claims.ID != user.ID || claims.Version != user.TokenVersion {
+			return errInvalidConfirmation
+		}
+
+		return nil
+	case codeAllowed && confirm.Code != "":
+		return s.checkTwoFactorCode(c, user, confirm.Code, log)
+	case codeAllowed:
+		return errReauthOrCodeRequired
+	default:
+		return errReauthRequired
+	}
+}
+
+// confirmationErrorResponse writes the response for a failed confirmUser.
+func (s *Service) confirmationErrorResponse(c *fiber.Ctx, err error, log *slog.Logger) error {
+	switch {
+	case errors.Is(err, errPasswordRequired), errors.Is(err, errReauthRequired), errors.Is(err, errReauthOrCodeRequired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, errInvalidConfirmation):
+		log.Info("invalid credentials")
+
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid credentials",
+		})
+	case errors.Is(err, storage.ErrInvalidTwoFactor):
+		return s.invalidTwoFactorResponse(c)
+	case errors.Is(err, errTwoFactorLocked):
+		return s.twoFactorLockedResponse(c)
+	default:
+		return s.handleInternalServerError(c, err, log)
+	}
+}
+
+// isConfirmationFailure reports whether confirmUser failed because of wrong credentials.
+func isConfirmationFailure(err error) bool {
+	return errors.Is(err, errInvalidConfirmation) || errors.Is(err, storage.ErrInvalidTwoFactor)
+}
@@ -0,0 +1,166 @@
+package account
+
+import (
+	"TextVault/internal/config"
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/storage/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Kinds of login subjects.
+const (
+	subjectUser      = "user"
+	subjectUsername  = "username"
+	subjectIP        = "ip"
+	subjectTwoFactor = "two_factor"
+)
+
+// loginSubject is something failed logins are counted for: a user, a username that matches no user,
+// a client IP, or the 2FA codes a user confirms actions with.
+type loginSubject struct {
+	kind        string
+	value       string
+	maxAttempts int
+}
+
+func (subject loginSubject) failuresKey() string {
+	return "login_failures:" + subject.kind + ":" + subject.value
+}
+
+func (subject loginSubject) lockKey() string {
+	return "login_lock:" + subject.kind + ":" + subject.value
+}
+
+// loginSubjects returns the subjects of a login attempt. An existing user is counted by ID, so failures
+// with their username and with their email add up. Unknown usernames are compared case-insensitively,
+// because GetUser also matches emails.
+func loginSubjects(policy config.LoginConfig, user *models.User, username, ip string) []loginSubject {
+	subject := loginSubject{kind: subjectUsername, value: strings.ToLower(username), maxAttempts: policy.UserMaxAttempts}
+	if user != nil {
+		subject = loginSubject{kind: subjectUser, value: strconv.FormatInt(user.ID, 10), maxAttempts: policy.UserMaxAttempts}
+	}
+
+	return []loginSubject{
+		subject,
+		{kind: subjectIP, value: ip, maxAttempts: policy.IPMaxAttempts},
+	}
+}
+
+// twoFactorSubjects returns the subject of a 2FA code confirming an action of the logged-in user.
+// Such codes are counted apart from logins, so a correct password doesn't reset them.
+func twoFactorSubjects(policy config.LoginConfig, user models.User) []loginSubject {
+	return []loginSubject{
+		{kind: subjectTwoFactor, value: strconv.FormatInt(user.ID, 10), maxAttempts: policy.UserMaxAttempts},
+	}
+}
+
+// loginLockout returns how long logins for any of the subjects are still locked, or zero if they aren't.
+func (s *Service) loginLockout(ctx context.Context, subjects []loginSubject) (time.Duration, error) {
+	var lockout time.Duration
+
+	for _, subject := range subjects {
+		ttl, err := s.cacheProvider.TTL(ctx, subject.lockKey())
+		if err != nil {
+			return 0, err
+		}
+
+		lockout = max(lockout, ttl)
+	}
+
+	return lockout, nil
+}
+
+// recordLoginFailure counts a failed login for every subject. A subject that reached its threshold is locked
+// for an exponentially growing duration, and the lockout is recorded in the audit log.
+func (s *Service) recordLoginFailure(c *fiber.Ctx, subjects []loginSubject, log *slog.Logger) {
+	ctx := c.UserContext()
+
+	for _, subject := range subjects {
+		failures, err := s.cacheProvider.Incr(ctx, subject.failuresKey(), s.loginPolicy.FailureWindow)
+		if err != nil {
+			log.Error("Failed to count login failure", slog.String("subject", subject.kind), sl.Err(err))
+			continue
+		}
+
+		if failures < int64(subject.maxAttempts) {
+			continue
+		}
+
+		lockout := s.lockoutDuration(failures - int64(subject.maxAttempts))
+
+		if err := s.cacheProvider.SetWithTTL(ctx, subject.lockKey(), "1", lockout); err != nil {
+			log.Error("Failed to lock login", slog.String("subject", subject.kind), sl.Err(err))
+			continue
+		}
+
+		log.Warn("Login locked after repeated failures",
+			slog.String("subject", subject.kind),
+			slog.String("value", subject.value),
+			slog.Int64("failures", failures),
+			slog.Duration("lockout", lockout),
+		)
+
+		s.recordLoginLockout(c, subject, failures, lockout)
+	}
+}
+
+// recordLoginLockout records the lockout of a subject. A lockout of an existing user targets them, so it shows
+// up in their own audit log.
+func (s *Service) recordLoginLockout(c *fiber.Ctx, subject loginSubject, failures int64, lockout time.Duration) {
+	event := models.AuditEvent{
+		Action:     models.AuditLoginLockout,
+		Outcome:    models.AuditFailure,
+		TargetType: models.AuditTargetIP,
+		TargetID:   subject.value,
+		Details:    fmt.Sprintf("%d failed logins, locked for %s", failures, lockout),
+	}
+
+	switch subject.kind {
+	case subjectUser:
+		event.TargetType = models.AuditTargetUser
+	case subjectTwoFactor:
+		event.TargetType = models.AuditTargetUser
+		event.Details = fmt.Sprintf("%d invalid two-factor codes, locked for %s", failures, lockout)
+	case subjectUsername:
+		event.TargetType = models.AuditTargetUsername
+		if len(event.TargetID) > maxAuditUsernameLength {
+			event.TargetID = event.TargetID[:maxAuditUsernameLength]
+		}
+	}
+
+	s.auditRecorder.Record(c, event)
+}
+
+// resetLoginFailures clears the failure counters of the subjects after a successful login.
+func (s *Service) resetLoginFailures(ctx context.Context, subjects []loginSubject, log *slog.Logger) {
+	for _, subject := range subjects {
+		// @NOTE: A successful login from a shared IP must not unlock other users behind it
+		if subject.kind == subjectIP {
+			continue
+		}
+
+		if err := s.cacheProvider.Delete(ctx, subject.failuresKey()); err != nil {
+			log.Error("Failed to reset login failures", slog.String("subject", subject.kind), sl.Err(err))
+		}
+	}
+}
+
+// lockoutDuration returns BaseLockout doubled for every failure beyond the threshold, capped at MaxLockout.
+func (s *Service) lockoutDuration(excessFailures int64) time.Duration {
+	factor := math.Pow(2, float64(excessFailures))
+	lockout := time.Duration(float64(s.loginPolicy.BaseLockout) * factor)
+
+	if lockout <= 0 || lockout > s.loginPolicy.MaxLockout {
+		return s.loginPolicy.MaxLockout
+	}
+
+	return lockout
+}
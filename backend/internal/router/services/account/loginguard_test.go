@@ -0,0 +1,85 @@
+package account
+
+import (
+	"TextVault/internal/storage/models"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// lockouts returns the recorded lockout events.
+func (f *fakeAudit) lockouts() []models.AuditEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var events []models.AuditEvent
+	for _, event := range f.events {
+		if event.Action == models.AuditLoginLockout {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+func TestLoginLockoutCountsUserAcrossUsernameAndEmail(t *testing.T) {
+	env := newTestEnv(t, nil)
+	alice := env.store.addUser(t, "alice", "alice@example.com", "correct horse battery")
+
+	// @NOTE: Alternating the username and the email must not double the attempts an attacker gets
+	logins := []string{"alice", "alice@example.com", "ALICE", "Alice@Example.com", "alice"}
+	for i, login := range logins {
+		resp := env.do(t, http.MethodPost, "/account/login", nil, map[string]string{"u": login, "p": "wrong password"})
+		if resp.status != http.StatusUnauthorized {
+			t.Fatalf("failed login %d status = %d, want %d", i+1, resp.status, http.StatusUnauthorized)
+		}
+	}
+
+	resp := env.do(t, http.MethodPost, "/account/login", nil, map[string]string{"u": "alice@example.com", "p": "correct horse battery"})
+	if resp.status != http.StatusTooManyRequests {
+		t.Fatalf("login after %d failures status = %d, want %d", len(logins), resp.status, http.StatusTooManyRequests)
+	}
+
+	lockouts := env.audit.lockouts()
+	if len(lockouts) != 1 {
+		t.Fatalf("recorded lockouts = %+v, want one", lockouts)
+	}
+
+	lockout := lockouts[0]
+	if lockout.Outcome != models.AuditFailure || lockout.TargetType != models.AuditTargetUser || lockout.TargetID != strconv.FormatInt(alice.ID, 10) {
+		t.Errorf("lockout event = %+v, want a failure targeting user %d", lockout, alice.ID)
+	}
+}
+
+func TestLoginLockoutOfUnknownUsername(t *testing.T) {
+	env := newTestEnv(t, nil)
+
+	for i := 0; i < 5; i++ {
+		env.do(t, http.MethodPost, "/account/login", nil, map[string]string{"u": "Nobody", "p": "wrong password"})
+	}
+
+	resp := env.do(t, http.MethodPost, "/account/login", nil, map[string]string{"u": "nobody", "p": "wrong password"})
+	if resp.status != http.StatusTooManyRequests {
+		t.Fatalf("login after 5 failures status = %d, want %d", resp.status, http.StatusTooManyRequests)
+	}
+
+	lockouts := env.audit.lockouts()
+	if len(lockouts) != 1 || lockouts[0].TargetType != models.AuditTargetUsername || lockouts[0].TargetID != "nobody" {
+		t.Errorf("recorded lockouts = %+v, want one targeting the username", lockouts)
+	}
+}
+
+func TestLoginLockoutDoesNotAffectOtherUsers(t *testing.T) {
+	env := newTestEnv(t, nil)
+	env.store.addUser(t, "alice", "alice@example.com", "correct horse battery")
+	env.store.addUser(t, "bob", "bob@example.com", "correct horse battery")
+
+	for i := 0; i < 5; i++ {
+		env.do(t, http.MethodPost, "/account/login", nil, map[string]string{"u": "alice", "p": "wrong password"})
+	}
+
+	resp := env.do(t, http.MethodPost, "/account/login", nil, map[string]string{"u": "bob", "p": "correct horse battery"})
+	if resp.status != http.StatusOK {
+		t.Errorf("login of another user status = %d %v, want %d", resp.status, resp.body, http.StatusOK)
+	}
+}
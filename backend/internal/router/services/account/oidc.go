@@ -0,0 +1,641 @@
+package account
+
+import (
+	"TextVault/internal/config"
+	"TextVault/internal/lib/jwt"
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/lib/validate"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"TextVault/pkg/random"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcStateTTL         = 10 * time.Minute
+	oidcDiscoveryTimeout = 10 * time.Second
+	oidcStateLength      = 32
+	oidcNonceLength      = 32
+	oidcBindingLength    = 32
+	oidcUsernameAttempts = 3
+	oidcUsernameSuffix   = 8
+
+	// oidcReauthMaxAge is how recent the provider login confirming a sensitive action must be,
+	// and reauthTokenTTL how long its reauthentication token confirms them.
+	oidcReauthMaxAge = 5 * time.Minute
+	reauthTokenTTL   = 5 * time.Minute
+
+	// oidcBindingCookie binds a login state to the browser that started the login, and oidcCookiePath
+	// limits it to the provider routes.
+	oidcBindingCookie = "oidc_binding"
+	oidcCookiePath    = "/account/oidc"
+)
+
+// Modes of a provider login. The login state carries the mode, as all of them share the callback URL.
+const (
+	oidcModeLogin  = ""
+	oidcModeLink   = "link"
+	oidcModeReauth = "reauth"
+)
+
+// oidcProvider is an OpenID Connect provider. Discovery happens on first use, so an unreachable
+// provider doesn't prevent the server from starting.
+type oidcProvider struct {
+	name          string
+	cfg           config.OIDCProviderConfig
+	redirectURL   string
+	autoProvision bool
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// oidcState is the login state kept in the cache between the redirect to the provider and the callback.
+// Binding must match the cookie of the browser completing the login, so a callback URL can't be handed to
+// someone else. Links and reauthentications are started by a logged-in user, whose ID and token version
+// are kept, so a session revoked in between can't complete them.
+type oidcState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Binding  string `json:"binding"`
+	Mode     string `json:"mode,omitempty"`
+	UserID   int64  `json:"userId,omitempty"`
+	Version  int64  `json:"ver,omitempty"`
+}
+
+// oidcClaims are the ID token claims used to link and provision accounts.
+type oidcClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	AuthTime          int64  `json:"auth_time"`
+}
+
+// oidcIdentity is an external identity whose ID token was verified by the callback.
+type oidcIdentity struct {
+	state   oidcState
+	subject string
+	claims  oidcClaims
+}
+
+func newOIDCProvider(name string, cfg config.OIDCProviderConfig, publicURL string) *oidcProvider {
+	return &oidcProvider{
+		name:          name,
+		cfg:           cfg,
+		redirectURL:   fmt.Sprintf("%s/account/oidc/%s/callback", publicURL, name),
+		autoProvision: cfg.AutoProvision,
+	}
+}
+
+// discover fetches the provider metadata and signing keys once and caches the result.
+func (p *oidcProvider) discover() (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth2 != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	// @NOTE: The context outlives the request, because the provider keeps using it to refresh its keys
+	ctx := oidc.ClientContext(context.Background(), &http.Client{Timeout: oidcDiscoveryTimeout})
+
+	provider, err := oidc.NewProvider(ctx, p.cfg.Issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.redirectURL,
+		Scopes:       scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+
+	return p.oauth2, p.verifier, nil
+}
+
+// OIDCLogin starts a login with the OpenID Connect provider given by the "provider" path parameter.
+// It redirects to the provider with a fresh state, nonce and PKCE challenge.
+// If the provider is not configured, it returns a 404 Not Found status with an error message.
+func (s *Service) OIDCLogin(c *fiber.Ctx) error {
+	authURL := s.startOIDCLogin(c, "internal.router.services.account.OIDCLogin", oidcState{Mode: oidcModeLogin})
+	if authURL == "" {
+		return nil
+	}
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCLink starts linking an identity at the OpenID Connect provider given by the "provider" path parameter
+// to the authenticated user, who can log in with it afterwards. Accounts are never linked by email,
+// so this is also how an existing account gets a provider login.
+// If the provider is not configured, it returns a 404 Not Found status with an error message.
+// On success, it returns a 200 OK status with the provider URL to open in the response; the callback
+// then links the identity. The URL only works in the browser that received the response.
+func (s *Service) OIDCLink(c *fiber.Ctx) error {
+	return s.startAuthenticatedOIDCLogin(c, "internal.router.services.account.OIDCLink", oidcModeLink)
+}
+
+// OIDCReauth starts a fresh login of the authenticated user at the OpenID Connect provider given by the
+// "provider" path parameter, with an identity already linked to the user. Accounts without a password
+// confirm sensitive actions this way. The provider is asked to authenticate the user again.
+// If the provider is not configured, it returns a 404 Not Found status with an error message.
+// On success, it returns a 200 OK status with the provider URL to open in the response; the callback
+// then returns a short-lived reauthentication token.
+func (s *Service) OIDCReauth(c *fiber.Ctx) error {
+	return s.startAuthenticatedOIDCLogin(c, "internal.router.services.account.OIDCReauth", oidcModeReauth)
+}
+
+// startAuthenticatedOIDCLogin starts a provider login on behalf of the authenticated user.
+func (s *Service) startAuthenticatedOIDCLogin(c *fiber.Ctx, prefix, mode string) error {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return s.unauthorizedResponse(c)
+	}
+
+	user, err := s.accountGetter.GetUserByID(c.UserContext(), principal.ID)
+	if err != nil {
+		return s.handleInternalServerError(c, err, sl.FromContext(c.UserContext(), s.log).With(slog.String("op", prefix)))
+	}
+
+	authURL := s.startOIDCLogin(c, prefix, oidcState{
+		Mode:    mode,
+		UserID:  user.ID,
+		Version: user.TokenVersion,
+	})
+	if authURL == "" {
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"url": authURL,
+	})
+}
+
+// startOIDCLogin saves a fresh login state with a nonce and PKCE verifier, binds it to the browser with
+// a cookie and returns the provider URL to authenticate at. If the provider is unknown or unavailable,
+// it writes an error response and returns an empty URL.
+func (s *Service) startOIDCLogin(c *fiber.Ctx, prefix string, loginState oidcState) string {
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("provider", c.Params("provider")),
+	)
+
+	provider, ok := s.oidcProviders[c.Params("provider")]
+	if !ok {
+		_ = s.unknownProviderResponse(c)
+		return ""
+	}
+
+	oauth2Config, _, err := provider.discover()
+	if err != nil {
+		log.Error("Failed to discover provider", sl.Err(err))
+
+		_ = s.providerUnavailableResponse(c)
+		return ""
+	}
+
+	state := random.String(oidcStateLength)
+	loginState.Provider = provider.name
+	loginState.Nonce = random.String(oidcNonceLength)
+	loginState.Verifier = oauth2.GenerateVerifier()
+	loginState.Binding = random.String(oidcBindingLength)
+
+	stateData, err := json.Marshal(loginState)
+	if err != nil {
+		_ = s.handleInternalServerError(c, err, log)
+		return ""
+	}
+
+	if err := s.cacheProvider.SetWithTTL(c.UserContext(), "oidc_state:"+state, string(stateData), oidcStateTTL); err != nil {
+		_ = s.handleInternalServerError(c, err, log)
+		return ""
+	}
+
+	// @NOTE: Lax still sends the cookie on the top-level redirect back from the provider
+	s.setOIDCBindingCookie(c, loginState.Binding, time.Now().Add(oidcStateTTL))
+
+	options := []oauth2.AuthCodeOption{
+		oauth2.S256ChallengeOption(loginState.Verifier),
+		oidc.Nonce(loginState.Nonce),
+	}
+
+	// @NOTE: An existing session at the provider must not confirm a sensitive action, so it has to ask again
+	if loginState.Mode == oidcModeReauth {
+		options = append(options,
+			oauth2.SetAuthURLParam("prompt", "login"),
+			oauth2.SetAuthURLParam("max_age", strconv.Itoa(int(oidcReauthMaxAge.Seconds()))),
+		)
+	}
+
+	return oauth2Config.AuthCodeURL(state, options...)
+}
+
+// OIDCCallback completes a login with an OpenID Connect provider. The state is single-use, the code is
+// exchanged with the PKCE verifier, and the ID token's signature, issuer, audience, expiry and nonce are verified.
+// The external identity is linked to a local user; an unknown identity gets a new account if the provider
+// allows auto-provisioning. It responds like Login.
+// Logins started by OIDCLink and OIDCReauth are completed by linkOIDCIdentity and reauthenticateOIDC instead.
+func (s *Service) OIDCCallback(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.OIDCCallback"
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("provider", c.Params("provider")),
+	)
+
+	provider, ok := s.oidcProviders[c.Params("provider")]
+	if !ok {
+		return s.unknownProviderResponse(c)
+	}
+
+	identity := s.verifyOIDCCallback(c, provider, log)
+	if identity == nil {
+		return nil
+	}
+
+	log = log.With(slog.String("subject", identity.subject))
+
+	switch identity.state.Mode {
+	case oidcModeLink:
+		return s.linkOIDCIdentity(c, provider, identity, log)
+	case oidcModeReauth:
+		return s.reauthenticateOIDC(c, provider, identity, log)
+	}
+
+	user, err := s.accountGetter.GetUserByIdentity(c.UserContext(), provider.name, identity.subject)
+	if err == nil {
+		return s.completeLogin(c, user, "oidc:"+provider.name, log)
+	}
+
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if !provider.autoProvision {
+		log.Warn("Unknown identity and auto-provisioning is disabled")
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "no account is linked to this identity",
+		})
+	}
+
+	user, err = s.provisionOIDCUser(c.UserContext(), provider.name, identity.subject, identity.claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, errOIDCEmailMissing):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, errOIDCEmailTaken):
+			log.Warn("Email of the identity belongs to a local account")
+
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return s.handleInternalServerError(c, err, log)
+		}
+	}
+
+	log.Info("Provisioned user for external identity", slog.Int64("user_id", user.ID))
+
+	s.recordUserEvent(c, models.AuditRegister, user.ID, "oidc:"+provider.name)
+
+	return s.completeLogin(c, user, "oidc:"+provider.name, log)
+}
+
+// verifyOIDCCallback consumes the login state of a callback, exchanges its code and verifies the ID token.
+// If the callback is invalid, it writes an error response and returns nil.
+func (s *Service) verifyOIDCCallback(c *fiber.Ctx, provider *oidcProvider, log *slog.Logger) *oidcIdentity {
+	if errorCode := c.Query("error"); errorCode != "" {
+		log.Warn("Provider returned an error", slog.String("error", errorCode))
+
+		_ = s.unauthorizedResponse(c)
+		return nil
+	}
+
+	binding := c.Cookies(oidcBindingCookie)
+	s.setOIDCBindingCookie(c, "", time.Unix(0, 0))
+
+	stateData, err := s.cacheProvider.GetDel(c.UserContext(), "oidc_state:"+c.Query("state"))
+	if err != nil {
+		log.Warn("Unknown or expired login state")
+
+		_ = s.invalidOIDCLoginResponse(c)
+		return nil
+	}
+
+	var loginState oidcState
+	if err := json.Unmarshal([]byte(stateData), &loginState); err != nil {
+		_ = s.handleInternalServerError(c, err, log)
+		return nil
+	}
+
+	// @NOTE: Otherwise a callback URL sent to someone else would log them into the sender's account,
+	// or link their identity to it
+	if binding == "" || subtle.ConstantTimeCompare([]byte(binding), []byte(loginState.Binding)) != 1 {
+		log.Warn("Login state belongs to another browser")
+
+		_ = s.invalidOIDCLoginResponse(c)
+		return nil
+	}
+
+	if loginState.Provider != provider.name {
+		log.Warn("Login state belongs to another provider")
+
+		_ = s.invalidOIDCLoginResponse(c)
+		return nil
+	}
+
+	oauth2Config, verifier, err := provider.discover()
+	if err != nil {
+		log.Error("Failed to discover provider", sl.Err(err))
+
+		_ = s.providerUnavailableResponse(c)
+		return nil
+	}
+
+	token, err := oauth2Config.Exchange(c.UserContext(), c.Query("code"), oauth2.VerifierOption(loginState.Verifier))
+	if err != nil {
+		log.Warn("Failed to exchange code", sl.Err(err))
+
+		_ = s.invalidOIDCLoginResponse(c)
+		return nil
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		log.Warn("Token response has no ID token")
+
+		_ = s.invalidOIDCLoginResponse(c)
+		return nil
+	}
+
+	idToken, err := verifier.Verify(c.UserContext(), rawIDToken)
+	if err != nil {
+		log.Warn("Failed to verify ID token", sl.Err(err))
+
+		_ = s.invalidOIDCLoginResponse(c)
+		return nil
+	}
+
+	if idToken.Nonce != loginState.Nonce {
+		log.Warn("ID token nonce mismatch")
+
+		_ = s.invalidOIDCLoginResponse(c)
+		return nil
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		log.Warn("Failed to parse ID token claims", sl.Err(err))
+
+		_ = s.invalidOIDCLoginResponse(c)
+		return nil
+	}
+
+	return &oidcIdentity{
+		state:   loginState,
+		subject: idToken.Subject,
+		claims:  claims,
+	}
+}
+
+// setOIDCBindingCookie sets the cookie binding a login state to the browser, or clears it with a past expiry.
+func (s *Service) setOIDCBindingCookie(c *fiber.Ctx, binding string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcBindingCookie,
+		Value:    binding,
+		Path:     oidcCookiePath,
+		Expires:  expires,
+		Secure:   strings.HasPrefix(s.publicURL, "https://"),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// stateUser loads the user who started a link or reauthentication. If the user is gone or their sessions
+// were revoked since, it writes a 401 Unauthorized response and returns nil.
+func (s *Service) stateUser(c *fiber.Ctx, state oidcState, log *slog.Logger) *models.User {
+	user, err := s.accountGetter.GetUserByID(c.UserContext(), state.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			_ = s.invalidOIDCLoginResponse(c)
+			return nil
+		}
+
+		_ = s.handleInternalServerError(c, err, log)
+		return nil
+	}
+
+	if user.TokenVersion != state.Version {
+		log.Warn("Sessions were revoked since the login started", slog.Int64("user_id", user.ID))
+
+		_ = s.invalidOIDCLoginResponse(c)
+		return nil
+	}
+
+	return &user
+}
+
+// linkOIDCIdentity completes OIDCLink by linking the verified identity to the user who started it.
+// If the identity is linked to another account, it returns a 409 Conflict status with an error message.
+// On success, it returns a 200 OK status with the provider name in the response.
+func (s *Service) linkOIDCIdentity(c *fiber.Ctx, provider *oidcProvider, identity *oidcIdentity, log *slog.Logger) error {
+	user := s.stateUser(c, identity.state, log)
+	if user == nil {
+		return nil
+	}
+
+	log = log.With(slog.Int64("user_id", user.ID))
+
+	linked, err := s.accountGetter.GetUserByIdentity(c.UserContext(), provider.name, identity.subject)
+	if err == nil && linked.ID != user.ID {
+		log.Warn("Identity is linked to another account")
+
+		return s.identityTakenResponse(c)
+	}
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if err != nil {
+		err = s.accountSaver.SaveIdentity(c.UserContext(), &models.Identity{
+			UserID:   user.ID,
+			Provider: provider.name,
+			Subject:  identity.subject,
+			Email:    identity.claims.Email,
+		})
+		if errors.Is(err, storage.ErrIdentityTaken) {
+			return s.identityTakenResponse(c)
+		}
+		if err != nil {
+			return s.handleInternalServerError(c, err, log)
+		}
+
+		s.recordUserEvent(c, models.AuditIdentityLink, user.ID, "oidc:"+provider.name)
+
+		log.Info("External identity linked")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"provider": provider.name,
+	})
+}
+
+// reauthenticateOIDC completes OIDCReauth. The verified identity must be linked to the user who started it,
+// and the provider must have authenticated them within oidcReauthMaxAge.
+// If the identity is linked to another account or none, it returns a 403 Forbidden status with an error message.
+// On success, it returns a 200 OK status with a reauthentication token for confirmUser in the response.
+func (s *Service) reauthenticateOIDC(c *fiber.Ctx, provider *oidcProvider, identity *oidcIdentity, log *slog.Logger) error {
+	user := s.stateUser(c, identity.state, log)
+	if user == nil {
+		return nil
+	}
+
+	log = log.With(slog.Int64("user_id", user.ID))
+
+	linked, err := s.accountGetter.GetUserByIdentity(c.UserContext(), provider.name, identity.subject)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if err != nil || linked.ID != user.ID {
+		log.Warn("Reauthentication with an identity of another account")
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "this identity is not linked to your account",
+		})
+	}
+
+	authTime := time.Unix(identity.claims.AuthTime, 0)
+	if identity.claims.AuthTime == 0 || time.Since(authTime) > oidcReauthMaxAge {
+		log.Warn("Provider login is not recent enough", slog.Int64("auth_time", identity.claims.AuthTime))
+
+		return s.invalidOIDCLoginResponse(c)
+	}
+
+	token, err := jwt.NewUserPurposeToken(*user, jwt.PurposeReauth, reauthTokenTTL)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	s.recordUserEvent(c, models.AuditReauthenticate, user.ID, "oidc:"+provider.name)
+
+	log.Info("User reauthenticated")
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"reauth": token,
+	})
+}
+
+var (
+	errOIDCEmailMissing = errors.New("identity provider did not return an email")
+	errOIDCEmailTaken   = errors.New("an account with this email already exists, log in and link this identity to it instead")
+)
+
+// provisionOIDCUser creates a local user for an external identity. The account has no password,
+// and its email counts as verified only if the provider says so.
+func (s *Service) provisionOIDCUser(ctx context.Context, provider, subject string, claims oidcClaims) (models.User, error) {
+	if claims.Email == "" {
+		return models.User{}, errOIDCEmailMissing
+	}
+
+	// @NOTE: Identities are never linked to existing accounts by email, as that would let
+	// a provider take over any local account. Their owners link them with OIDCLink instead
+	_, err := s.accountGetter.GetUser(ctx, claims.Email)
+	if err == nil {
+		return models.User{}, errOIDCEmailTaken
+	}
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		return models.User{}, err
+	}
+
+	name := claims.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	user := models.User{
+		Username:   validate.SanitizeUsername(name),
+		Email:      claims.Email,
+		IsVerified: claims.EmailVerified,
+	}
+
+	identity := &models.Identity{
+		Provider: provider,
+		Subject:  subject,
+		Email:    claims.Email,
+	}
+
+	// @NOTE: The provider's name may be unusable or taken locally, then a generated one is used
+	for attempt := 0; attempt < oidcUsernameAttempts; attempt++ {
+		if user.Username == "" || attempt > 0 {
+			user.Username = "user-" + strings.ToLower(random.String(oidcUsernameSuffix))
+		}
+
+		user.ID, err = s.accountSaver.SaveUserWithIdentity(ctx, &user, identity)
+		if !errors.Is(err, storage.ErrUsernameTaken) {
+			break
+		}
+	}
+
+	if err != nil {
+		if errors.Is(err, storage.ErrEmailTaken) {
+			return models.User{}, errOIDCEmailTaken
+		}
+
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *Service) unknownProviderResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "unknown identity provider",
+	})
+}
+
+func (s *Service) providerUnavailableResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+		"error": "identity provider is unavailable",
+	})
+}
+
+func (s *Service) identityTakenResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error": "this identity is linked to another account",
+	})
+}
+
+func (s *Service) invalidOIDCLoginResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": "invalid or expired login",
+	})
+}
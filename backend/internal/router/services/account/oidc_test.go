@@ -0,0 +1,453 @@
+package account
+
+import (
+	"TextVault/internal/config"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	mockProviderName = "mock"
+	mockClientID     = "textvault"
+	mockKeyID        = "mock-key"
+)
+
+// mockProvider is a minimal OpenID Connect provider: it serves discovery, its signing keys and a token
+// endpoint issuing RS256-signed ID tokens for the identity the test logs in as.
+type mockProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu       sync.Mutex
+	codes    map[string]mockLogin
+	subject  string
+	email    string
+	authTime time.Time
+	badNonce bool
+}
+
+// mockLogin is an authorization the provider issued a code for.
+type mockLogin struct {
+	nonce     string
+	challenge string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	p := &mockProvider{key: key, codes: map[string]mockLogin{}}
+	p.server = httptest.NewServer(p)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+// as sets the identity the provider authenticates from now on, authenticated at authTime.
+func (p *mockProvider) as(subject, email string, authTime time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.subject, p.email, p.authTime = subject, email, authTime
+}
+
+func (p *mockProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		writeJSON(w, map[string]any{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	case "/keys":
+		writeJSON(w, map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": mockKeyID,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+			}},
+		})
+	case "/token":
+		p.token(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorize stands in for the user logging in at the provider: it issues a code for the auth URL.
+func (p *mockProvider) authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse auth URL: %v", err)
+	}
+
+	query := u.Query()
+	if query.Get("client_id") != mockClientID || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("auth URL %s lacks the client ID or a PKCE challenge", authURL)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	code = base64.RawURLEncoding.EncodeToString([]byte(query.Get("state")))
+	p.codes[code] = mockLogin{nonce: query.Get("nonce"), challenge: query.Get("code_challenge")}
+
+	return code, query.Get("state")
+}
+
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	login, ok := p.codes[r.FormValue("code")]
+	delete(p.codes, r.FormValue("code"))
+
+	verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != login.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	nonce := login.nonce
+	if p.badNonce {
+		nonce = "forged"
+	}
+
+	now := time.Now()
+	idToken := gojwt.NewWithClaims(gojwt.SigningMethodRS256, gojwt.MapClaims{
+		"iss":            p.server.URL,
+		"sub":            p.subject,
+		"aud":            mockClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Minute).Unix(),
+		"auth_time":      p.authTime.Unix(),
+		"nonce":          nonce,
+		"email":          p.email,
+		"email_verified": true,
+	})
+	idToken.Header["kid"] = mockKeyID
+
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]any{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     signed,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func newOIDCTestEnv(t *testing.T, autoProvision bool) (*testEnv, *mockProvider) {
+	t.Helper()
+
+	provider := newMockProvider(t)
+	env := newTestEnv(t, &config.Config{
+		OIDC: map[string]config.OIDCProviderConfig{
+			mockProviderName: {
+				Issuer:        provider.server.URL,
+				ClientID:      mockClientID,
+				ClientSecret:  "secret",
+				AutoProvision: autoProvision,
+			},
+		},
+	})
+
+	return env, provider
+}
+
+// callback completes the provider login started with the auth URL.
+func (env *testEnv) callback(t *testing.T, provider *mockProvider, authURL string) response {
+	t.Helper()
+
+	code, state := provider.authorize(t, authURL)
+
+	return env.do(t, http.MethodGet, "/account/oidc/mock/callback?code="+url.QueryEscape(code)+"&state="+url.QueryEscape(state), nil, nil)
+}
+
+// oidcLogin logs in at the provider as the identity and returns the callback response.
+func (env *testEnv) oidcLogin(t *testing.T, provider *mockProvider, subject, email string) response {
+	t.Helper()
+
+	provider.as(subject, email, time.Now())
+
+	start := env.do(t, http.MethodGet, "/account/oidc/mock/login", nil, nil)
+	if start.status != http.StatusFound {
+		t.Fatalf("login status = %d, want %d", start.status, http.StatusFound)
+	}
+
+	return env.callback(t, provider, start.location)
+}
+
+func TestOIDCLoginProvisionsPasswordlessUser(t *testing.T) {
+	env, provider := newOIDCTestEnv(t, true)
+
+	resp := env.oidcLogin(t, provider, "alice-subject", "alice@example.com")
+	if resp.status != http.StatusOK || resp.string("token") == "" {
+		t.Fatalf("callback = %d %v, want a token", resp.status, resp.body)
+	}
+
+	user, err := env.store.GetUserByIdentity(context.Background(), mockProviderName, "alice-subject")
+	if err != nil {
+		t.Fatalf("GetUserByIdentity() error = %v", err)
+	}
+	if user.Email != "alice@example.com" || user.HasPassword() {
+		t.Errorf("provisioned user = %q with password %v, want alice@example.com without one", user.Email, user.HasPassword())
+	}
+
+	login := env.do(t, http.MethodPost, "/account/login", nil, map[string]string{"u": "alice@example.com", "p": "any password"})
+	if login.status != http.StatusUnauthorized {
+		t.Errorf("password login of password-less user status = %d, want %d", login.status, http.StatusUnauthorized)
+	}
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	env, provider := newOIDCTestEnv(t, true)
+	provider.badNonce = true
+
+	resp := env.oidcLogin(t, provider, "alice-subject", "alice@example.com")
+	if resp.status != http.StatusUnauthorized {
+		t.Errorf("callback status = %d, want %d", resp.status, http.StatusUnauthorized)
+	}
+}
+
+func TestOIDCCallbackStateIsSingleUse(t *testing.T) {
+	env, provider := newOIDCTestEnv(t, true)
+	provider.as("alice-subject", "alice@example.com", time.Now())
+
+	start := env.do(t, http.MethodGet, "/account/oidc/mock/login", nil, nil)
+	code, state := provider.authorize(t, start.location)
+	target := "/account/oidc/mock/callback?code=" + url.QueryEscape(code) + "&state=" + url.QueryEscape(state)
+
+	if resp := env.do(t, http.MethodGet, target, nil, nil); resp.status != http.StatusOK {
+		t.Fatalf("first callback status = %d, want %d", resp.status, http.StatusOK)
+	}
+	if resp := env.do(t, http.MethodGet, target, nil, nil); resp.status != http.StatusUnauthorized {
+		t.Errorf("replayed callback status = %d, want %d", resp.status, http.StatusUnauthorized)
+	}
+}
+
+func TestOIDCCallbackRequiresBrowserOfLogin(t *testing.T) {
+	env, provider := newOIDCTestEnv(t, true)
+	provider.as("mallory-subject", "mallory@example.com", time.Now())
+
+	start := env.do(t, http.MethodGet, "/account/oidc/mock/login", nil, nil)
+
+	// @NOTE: The victim's browser follows the callback URL of a login the attacker started
+	env.cookies = map[string]string{}
+	if resp := env.callback(t, provider, start.location); resp.status != http.StatusUnauthorized {
+		t.Errorf("callback without the binding cookie status = %d, want %d", resp.status, http.StatusUnauthorized)
+	}
+
+	start = env.do(t, http.MethodGet, "/account/oidc/mock/login", nil, nil)
+	env.do(t, http.MethodGet, "/account/oidc/mock/login", nil, nil)
+	if resp := env.callback(t, provider, start.location); resp.status != http.StatusUnauthorized {
+		t.Errorf("callback with the cookie of another login status = %d, want %d", resp.status, http.StatusUnauthorized)
+	}
+
+	if _, err := env.store.GetUserByIdentity(context.Background(), mockProviderName, "mallory-subject"); err == nil {
+		t.Error("a user was provisioned by a callback from another browser")
+	}
+}
+
+func TestOIDCLinkRequiresBrowserOfLink(t *testing.T) {
+	env, provider := newOIDCTestEnv(t, true)
+	mallory := env.store.addUser(t, "mallory", "mallory@example.com", "correct horse battery")
+
+	start := env.do(t, http.MethodPost, "/account/oidc/mock/link", &mallory, nil)
+	if start.status != http.StatusOK {
+		t.Fatalf("link = %d %v", start.status, start.body)
+	}
+
+	env.cookies = map[string]string{}
+	provider.as("alice-subject", "alice@example.com", time.Now())
+	if resp := env.callback(t, provider, start.string("url")); resp.status != http.StatusUnauthorized {
+		t.Errorf("link callback from another browser status = %d, want %d", resp.status, http.StatusUnauthorized)
+	}
+
+	if _, err := env.store.GetUserByIdentity(context.Background(), mockProviderName, "alice-subject"); err == nil {
+		t.Error("the victim's identity was linked to the attacker's account")
+	}
+}
+
+func TestOIDCLinkExistingAccount(t *testing.T) {
+	env, provider := newOIDCTestEnv(t, true)
+	bob := env.store.addUser(t, "bob", "bob@example.com", "correct horse battery")
+
+	if resp := env.oidcLogin(t, provider, "bob-subject", "bob@example.com"); resp.status != http.StatusConflict {
+		t.Fatalf("login with the email of a local account status = %d, want %d", resp.status, http.StatusConflict)
+	}
+
+	start := env.do(t, http.MethodPost, "/account/oidc/mock/link", &bob, nil)
+	if start.status != http.StatusOK || start.string("url") == "" {
+		t.Fatalf("link = %d %v, want a provider URL", start.status, start.body)
+	}
+
+	if resp := env.callback(t, provider, start.string("url")); resp.status != http.StatusOK {
+		t.Fatalf("link callback = %d %v, want %d", resp.status, resp.body, http.StatusOK)
+	}
+
+	resp := env.oidcLogin(t, provider, "bob-subject", "bob@example.com")
+	if resp.status != http.StatusOK || resp.string("token") == "" {
+		t.Fatalf("login with linked identity = %d %v, want a token", resp.status, resp.body)
+	}
+
+	if user, _ := env.store.GetUserByIdentity(context.Background(), mockProviderName, "bob-subject"); user.ID != bob.ID {
+		t.Errorf("identity is linked to user %d, want %d", user.ID, bob.ID)
+	}
+
+	carol := env.store.addUser(t, "carol", "carol@example.com", "correct horse battery")
+	start = env.do(t, http.MethodPost, "/account/oidc/mock/link", &carol, nil)
+	if resp := env.callback(t, provider, start.string("url")); resp.status != http.StatusConflict {
+		t.Errorf("linking an identity of another account status = %d, want %d", resp.status, http.StatusConflict)
+	}
+}
+
+func TestOIDCLinkRejectsRevokedSession(t *testing.T) {
+	env, provider := newOIDCTestEnv(t, true)
+	bob := env.store.addUser(t, "bob", "bob@example.com", "correct horse battery")
+	provider.as("bob-subject", "bob@example.com", time.Now())
+
+	start := env.do(t, http.MethodPost, "/account/oidc/mock/link", &bob, nil)
+
+	if _, err := env.store.UpdatePassword(context.Background(), bob.ID, "revoked"); err != nil {
+		t.Fatalf("UpdatePassword() error = %v", err)
+	}
+
+	if resp := env.callback(t, provider, start.string("url")); resp.status != http.StatusUnauthorized {
+		t.Errorf("callback after revocation status = %d, want %d", resp.status, http.StatusUnauthorized)
+	}
+}
+
+func TestOIDCReauthConfirmsPasswordlessUser(t *testing.T) {
+	env, provider := newOIDCTestEnv(t, true)
+
+	if resp := env.oidcLogin(t, provider, "alice-subject", "alice@example.com"); resp.status != http.StatusOK {
+		t.Fatalf("login = %d %v", resp.status, resp.body)
+	}
+	alice, _ := env.store.GetUserByIdentity(context.Background(), mockProviderName, "alice-subject")
+
+	change := map[string]string{"p": "a new long passphrase"}
+	if resp := env.do(t, http.MethodPost, "/account/password", &alice, change); resp.status != http.StatusBadRequest {
+		t.Fatalf("password change without confirmation status = %d, want %d", resp.status, http.StatusBadRequest)
+	}
+
+	provider.as("alice-subject", "alice@example.com", time.Now())
+	start := env.do(t, http.MethodPost, "/account/oidc/mock/reauth", &alice, nil)
+	if start.status != http.StatusOK {
+		t.Fatalf("reauth = %d %v", start.status, start.body)
+	}
+
+	authURL, _ := url.Parse(start.string("url"))
+	if authURL.Query().Get("prompt") != "login" || authURL.Query().Get("max_age") == "" {
+		t.Errorf("reauth URL %s doesn't ask the provider for a fresh login", authURL)
+	}
+
+	resp := env.callback(t, provider, start.string("url"))
+	if resp.status != http.StatusOK || resp.string("reauth") == "" {
+		t.Fatalf("reauth callback = %d %v, want a reauthentication token", resp.status, resp.body)
+	}
+
+	change["reauth"] = "forged"
+	if resp := env.do(t, http.MethodPost, "/account/password", &alice, change); resp.status != http.StatusUnauthorized {
+		t.Errorf("password change with forged token status = %d, want %d", resp.status, http.StatusUnauthorized)
+	}
+
+	change["reauth"] = resp.string("reauth")
+	if resp := env.do(t, http.MethodPost, "/account/password", &alice, change); resp.status != http.StatusOK {
+		t.Fatalf("password change with reauthentication = %d %v, want %d", resp.status, resp.body, http.StatusOK)
+	}
+
+	if !env.store.user(alice.ID).HasPassword() {
+		t.Error("password-less user has no password after setting one")
+	}
+}
+
+func TestOIDCReauthRequiresRecentLogin(t *testing.T) {
+	env, provider := newOIDCTestEnv(t, true)
+
+	env.oidcLogin(t, provider, "alice-subject", "alice@example.com")
+	alice, _ := env.store.GetUserByIdentity(context.Background(), mockProviderName, "alice-subject")
+
+	provider.as("alice-subject", "alice@example.com", time.Now().Add(-time.Hour))
+	start := env.do(t, http.MethodPost, "/account/oidc/mock/reauth", &alice, nil)
+
+	if resp := env.callback(t, provider, start.string("url")); resp.status != http.StatusUnauthorized {
+		t.Errorf("reauth with an old provider login status = %d, want %d", resp.status, http.StatusUnauthorized)
+	}
+}
+
+func TestOIDCReauthRejectsOtherIdentity(t *testing.T) {
+	env, provider := newOIDCTestEnv(t, true)
+
+	env.oidcLogin(t, provider, "alice-subject", "alice@example.com")
+	env.oidcLogin(t, provider, "mallory-subject", "mallory@example.com")
+	alice, _ := env.store.GetUserByIdentity(context.Background(), mockProviderName, "alice-subject")
+
+	provider.as("mallory-subject", "mallory@example.com", time.Now())
+	start := env.do(t, http.MethodPost, "/account/oidc/mock/reauth", &alice, nil)
+
+	if resp := env.callback(t, provider, start.string("url")); resp.status != http.StatusForbidden {
+		t.Errorf("reauth with another account's identity status = %d, want %d", resp.status, http.StatusForbidden)
+	}
+}
+
+func TestConfirmUserWithTwoFactorCode(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := env.store.addUser(t, "dave", "dave@example.com", "")
+
+	tests := []struct {
+		name string
+		body map[string]string
+		want int
+	}{
+		{name: "nothing", body: map[string]string{}, want: http.StatusBadRequest},
+		{name: "password", body: map[string]string{"p": "anything"}, want: http.StatusBadRequest},
+		{name: "code without 2FA", body: map[string]string{"code": "123456"}, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := env.do(t, http.MethodDelete, "/account/", &user, tt.body); resp.status != tt.want {
+				t.Errorf("delete account status = %d %v, want %d", resp.status, resp.body, tt.want)
+			}
+		})
+	}
+
+	if len(env.store.deleted) != 0 {
+		t.Errorf("deleted users %v without confirmation", env.store.deleted)
+	}
+}
@@ -0,0 +1,208 @@
+package account
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/mailer"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"TextVault/pkg/passwordhash"
+	"TextVault/pkg/random"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	resetTokenLength = 48
+	resetTokenTTL    = time.Hour
+
+	// At most resetRequestLimit reset emails are sent to one address per resetRequestWindow.
+	resetRequestLimit  = 3
+	resetRequestWindow = time.Hour
+)
+
+type forgotPasswordRequest struct {
+	Mail string `json:"m"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"p"`
+}
+
+// ForgotPassword emails a single-use, time-limited password reset token to the given address.
+// To avoid revealing which emails are registered, it returns a 200 OK status whether or not the user exists.
+// If too many resets were requested for the address, it returns a 429 Too Many Requests status with an error message.
+func (s *Service) ForgotPassword(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.ForgotPassword"
+
+	p := new(forgotPasswordRequest)
+
+	if err := c.BodyParser(p); err != nil || len(p.Mail) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "email is required",
+		})
+	}
+
+	email := strings.ToLower(strings.TrimSpace(p.Mail))
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+	)
+
+	attempts, err := s.cacheProvider.Incr(c.UserContext(), "password_reset:"+email, resetRequestWindow)
+	if err != nil {
+		log.Error("Failed to count reset requests", sl.Err(err))
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	if attempts > resetRequestLimit {
+		log.Warn("Password reset rate limit exceeded")
+
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "too many password reset requests, try again later",
+		})
+	}
+
+	user, err := s.accountGetter.GetUser(c.UserContext(), email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return c.SendStatus(fiber.StatusOK)
+		}
+
+		log.Error("Failed to get user", sl.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	// @NOTE: GetUser also matches usernames, but resets are only sent when an email was given
+	if !strings.EqualFold(user.Email, email) {
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	log = log.With(slog.Int64("user_id", user.ID))
+
+	token := random.String(resetTokenLength)
+
+	err = s.accountSaver.SavePasswordReset(c.UserContext(), user.ID, hashToken(token), time.Now().Add(resetTokenTTL))
+	if err != nil {
+		log.Error("Failed to save password reset", sl.Err(err))
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	err = s.mailer.Send(c.UserContext(), mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your TextVault password",
+		Body: fmt.Sprintf("Use the token below to reset your password. It expires in %s and can be used once.\n\n%s\n\n"+
+			"If you didn't request a password reset, you can ignore this email.\n", resetTokenTTL, token),
+	})
+	if err != nil {
+		log.Error("Failed to send password reset email", sl.Err(err))
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to send password reset email",
+		})
+	}
+
+	log.Info("Password reset email sent")
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ResetPassword sets a new password using a token sent by ForgotPassword.
+// All existing sessions and outstanding reset tokens of the user are revoked.
+// If the password violates the password policy, it returns a 400 Bad Request status with field-level errors.
+// If the token is unknown, used or expired, it returns a 400 Bad Request status with an error message.
+// On success, it returns a 200 OK status with an empty response body.
+func (s *Service) ResetPassword(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.ResetPassword"
+
+	p := new(resetPasswordRequest)
+
+	if err := c.BodyParser(p); err != nil || len(p.Token) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "token is required",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+	)
+
+	tokenHash := hashToken(p.Token)
+
+	user, err := s.accountGetter.GetUserByResetToken(c.UserContext(), tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidResetToken) {
+			return s.invalidResetTokenResponse(c, log)
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if problems := s.passwordPolicy.Validate(p.Password, user.Username, user.Email); len(problems) > 0 {
+		return s.validationErrorResponse(c, map[string][]string{"p": problems})
+	}
+
+	passwordHash, err := passwordhash.New(p.Password)
+	if err != nil {
+		log.Error("Failed to hash password", sl.Err(err))
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	// @NOTE: The token is consumed here, so it can't be used twice if it was used since it was looked up
+	userID, err := s.accountSaver.ResetPassword(c.UserContext(), tokenHash, passwordHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidResetToken) {
+			return s.invalidResetTokenResponse(c, log)
+		}
+
+		log.Error("Failed to reset password", sl.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal server error",
+		})
+	}
+
+	s.recordUserEvent(c, models.AuditPasswordReset, userID, "")
+
+	log.Info("Password reset", slog.Int64("user_id", userID))
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// invalidResetTokenResponse records the failed reset and writes a 400 Bad Request response.
+func (s *Service) invalidResetTokenResponse(c *fiber.Ctx, log *slog.Logger) error {
+	log.Warn("Invalid password reset token")
+
+	s.auditRecorder.Record(c, models.AuditEvent{
+		Action:  models.AuditPasswordReset,
+		Outcome: models.AuditFailure,
+		Details: "invalid reset token",
+	})
+
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "invalid or expired reset token",
+	})
+}
+
+// hashToken returns the hex-encoded SHA-256 of a reset token or recovery code. Only the hash is stored in the database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
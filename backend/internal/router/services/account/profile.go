@@ -0,0 +1,352 @@
+package account
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/lib/validate"
+	"TextVault/internal/mailer"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"TextVault/pkg/passwordhash"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// What happens to the pastes of a deleted account.
+const (
+	pastesDelete    = "delete"
+	pastesAnonymise = "anonymise"
+)
+
+// updateProfileRequest is a struct that represents the request body for updating the profile.
+// Fields left empty are not changed. Changing the email must be confirmed with Password, or for accounts
+// without a password with Reauth or Code (see confirmUser).
+type updateProfileRequest struct {
+	Username string `json:"u"`
+	Mail     string `json:"m"`
+	Password string `json:"p"`
+	Reauth   string `json:"reauth"`
+	Code     string `json:"code"`
+}
+
+// changePasswordRequest is a struct that represents the request body for changing the password.
+// Accounts without a password confirm with Reauth or Code instead of CurrentPassword (see confirmUser).
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current"`
+	Reauth          string `json:"reauth"`
+	Code            string `json:"code"`
+	Password        string `json:"p"`
+}
+
+// deleteAccountRequest is a struct that represents the request body for deleting the account.
+// Accounts without a password confirm with Reauth or Code instead of Password (see confirmUser).
+type deleteAccountRequest struct {
+	Password string `json:"p"`
+	Reauth   string `json:"reauth"`
+	Code     string `json:"code"`
+	Pastes   string `json:"pastes"`
+}
+
+// UpdateProfile changes the username and/or the email of the authenticated user.
+// Changing the email requires the same confirmation as changing the password, marks the account as unverified,
+// sends a new verification link and notifies the old address.
+// Invalid fields return a 400 Bad Request status with field-level errors, and a taken username
+// or email returns a 409 Conflict status naming the field.
+// If the confirmation of an email change is missing, it returns a 400 Bad Request status with an error message,
+// and if it is incorrect, a 401 Unauthorized status.
+// On success, it returns a 200 OK status with the updated profile in the response.
+func (s *Service) UpdateProfile(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.UpdateProfile"
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return s.unauthorizedResponse(c)
+	}
+
+	p := new(updateProfileRequest)
+
+	if err := c.BodyParser(p); err != nil {
+		sl.FromContext(c.UserContext(), s.log).Error("Failed to parse update profile request", sl.Err(err))
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "username or email is required",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int64("user_id", principal.ID),
+	)
+
+	user, err := s.accountGetter.GetUserByID(c.UserContext(), principal.ID)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	fields := make(map[string][]string)
+	if p.Username != "" {
+		if problems := validate.Username(p.Username); len(problems) > 0 {
+			fields["u"] = problems
+		}
+	}
+	if p.Mail != "" {
+		if problems := validate.Email(p.Mail); len(problems) > 0 {
+			fields["m"] = problems
+		}
+	}
+
+	if len(fields) > 0 {
+		return s.validationErrorResponse(c, fields)
+	}
+
+	oldEmail := user.Email
+	emailChanged := p.Mail != "" && !strings.EqualFold(p.Mail, user.Email)
+
+	// @NOTE: The email receives password resets, so a stolen session must not be enough to change it
+	if emailChanged {
+		confirm := confirmation{Password: p.Password, Reauth: p.Reauth, Code: p.Code}
+		if err := s.confirmUser(c, user, confirm, true, log); err != nil {
+			if isConfirmationFailure(err) {
+				s.auditRecorder.Record(c, models.AuditEvent{
+					Action:     models.AuditEmailChange,
+					Outcome:    models.AuditFailure,
+					TargetType: models.AuditTargetUser,
+					TargetID:   strconv.FormatInt(user.ID, 10),
+					Details:    "invalid credentials",
+				})
+			}
+
+			return s.confirmationErrorResponse(c, err, log)
+		}
+	}
+
+	if p.Username != "" {
+		user.Username = p.Username
+	}
+
+	if emailChanged {
+		user.Email = p.Mail
+		user.IsVerified = false
+	}
+
+	if err := s.accountSaver.UpdateUser(c.UserContext(), &user); err != nil {
+		return s.handleSaveUserError(c, err, log)
+	}
+
+	if emailChanged {
+		s.recordUserEvent(c, models.AuditEmailChange, user.ID, "")
+
+		if err := s.sendVerificationEmail(c.UserContext(), user.ID, user.Email); err != nil {
+			log.Error("Failed to send verification email", sl.Err(err))
+		}
+
+		if err := s.sendEmailChangedNotice(c.UserContext(), user.Username, oldEmail, user.Email); err != nil {
+			log.Error("Failed to notify the old email", sl.Err(err))
+		}
+	}
+
+	log.Info("Profile updated", slog.Bool("email_changed", emailChanged))
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"id":         user.ID,
+		"username":   user.Username,
+		"email":      user.Email,
+		"isVerified": user.IsVerified,
+	})
+}
+
+// ChangePassword sets a new password for the authenticated user after confirming the current one.
+// Accounts without a password, provisioned by an OpenID Connect provider, confirm with a reauthentication
+// token or a 2FA code instead, and set their first password this way. All other sessions of the user are revoked.
+// If the confirmation is missing, it returns a 400 Bad Request status with an error message.
+// If the current password is incorrect, it returns a 401 Unauthorized status with an error message.
+// If the new password violates the password policy, it returns a 400 Bad Request status with field-level errors.
+// On success, it returns a 200 OK status with a new JWT token in the response.
+func (s *Service) ChangePassword(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.ChangePassword"
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return s.unauthorizedResponse(c)
+	}
+
+	p := new(changePasswordRequest)
+
+	if err := c.BodyParser(p); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "current password is required",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int64("user_id", principal.ID),
+	)
+
+	user, err := s.accountGetter.GetUserByID(c.UserContext(), principal.ID)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	confirm := confirmation{Password: p.CurrentPassword, Reauth: p.Reauth, Code: p.Code}
+	if err := s.confirmUser(c, user, confirm, true, log); err != nil {
+		if isConfirmationFailure(err) {
+			s.auditRecorder.Record(c, models.AuditEvent{
+				Action:     models.AuditPasswordChange,
+				Outcome:    models.AuditFailure,
+				TargetType: models.AuditTargetUser,
+				TargetID:   strconv.FormatInt(user.ID, 10),
+				Details:    "invalid credentials",
+			})
+		}
+
+		return s.confirmationErrorResponse(c, err, log)
+	}
+
+	if problems := s.passwordPolicy.Validate(p.Password, user.Username, user.Email); len(problems) > 0 {
+		return s.validationErrorResponse(c, map[string][]string{"p": problems})
+	}
+
+	passwordHash, err := passwordhash.New(p.Password)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	user.TokenVersion, err = s.accountSaver.UpdatePassword(c.UserContext(), user.ID, passwordHash)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	s.recordUserEvent(c, models.AuditPasswordChange, user.ID, "")
+
+	token, err := s.newSessionToken(c, user, models.AuditPasswordChange)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	log.Info("Password changed")
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"token": token,
+	})
+}
+
+// DeleteAccount deletes the authenticated user after confirming their password, or for accounts without one,
+// a reauthentication token or a 2FA code.
+// The "pastes" field chooses whether the user's pastes are deleted ("delete", the default)
+// or kept without an author ("anonymise"). Private pastes are always deleted, except those owned
+// by an organization, which always stay with it.
+// If the confirmation is missing, it returns a 400 Bad Request status with an error message.
+// If the password is incorrect, it returns a 401 Unauthorized status with an error message.
+// If the user is the last owner of an organization, it returns a 409 Conflict status with an error message.
+// On success, it returns a 200 OK status with an empty response body.
+func (s *Service) DeleteAccount(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.DeleteAccount"
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return s.unauthorizedResponse(c)
+	}
+
+	p := new(deleteAccountRequest)
+
+	if err := c.BodyParser(p); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "password is required",
+		})
+	}
+
+	if p.Pastes == "" {
+		p.Pastes = pastesDelete
+	}
+
+	if p.Pastes != pastesDelete && p.Pastes != pastesAnonymise {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "pastes must be either \"delete\" or \"anonymise\"",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int64("user_id", principal.ID),
+		slog.String("pastes", p.Pastes),
+	)
+
+	user, err := s.accountGetter.GetUserByID(c.UserContext(), principal.ID)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	confirm := confirmation{Password: p.Password, Reauth: p.Reauth, Code: p.Code}
+	if err := s.confirmUser(c, user, confirm, true, log); err != nil {
+		if isConfirmationFailure(err) {
+			s.auditRecorder.Record(c, models.AuditEvent{
+				Action:     models.AuditAccountDelete,
+				Outcome:    models.AuditFailure,
+				TargetType: models.AuditTargetUser,
+				TargetID:   strconv.FormatInt(user.ID, 10),
+				Details:    "invalid credentials",
+			})
+		}
+
+		return s.confirmationErrorResponse(c, err, log)
+	}
+
+	ids, err := s.accountSaver.DeleteUser(c.UserContext(), user.ID, p.Pastes == pastesAnonymise)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return s.unauthorizedResponse(c)
+		}
+
+		if errors.Is(err, storage.ErrLastOrgOwner) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "transfer ownership of your organizations or delete them first",
+			})
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	// @NOTE: Rows are gone at this point, so content cleanup failures are only logged
+	for _, id := range ids {
+		if err := s.pasteProvider.DeletePaste(c.UserContext(), id); err != nil {
+			log.Error("Failed to delete paste from s3 storage", slog.String("id", id), sl.Err(err))
+		}
+
+		if err := s.cacheProvider.Delete(c.UserContext(), id); err != nil {
+			log.Error("Failed to delete paste from cache", slog.String("id", id), sl.Err(err))
+		}
+	}
+
+	s.recordUserEvent(c, models.AuditAccountDelete, user.ID, fmt.Sprintf("%d pastes deleted", len(ids)))
+
+	log.Info("Account deleted", slog.Int("deleted_pastes", len(ids)))
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// sendEmailChangedNotice tells the old address that the email of the account was changed, so the owner
+// notices if someone else took over the account.
+func (s *Service) sendEmailChangedNotice(ctx context.Context, username, oldEmail, newEmail string) error {
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      oldEmail,
+		Subject: "Your TextVault email was changed",
+		Body: fmt.Sprintf("The email of your TextVault account %s was changed to %s.\n\n"+
+			"If you didn't make this change, your account may be compromised. Contact the TextVault administrators.\n",
+			username, newEmail),
+	})
+}
+
+func (s *Service) handleInternalServerError(c *fiber.Ctx, err error, log *slog.Logger) error {
+	log.Error("Internal server error", sl.Err(err))
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Internal server error",
+	})
+}
@@ -0,0 +1,108 @@
+package account
+
+import (
+	"TextVault/internal/storage/models"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestUpdateProfileEmailChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       map[string]string
+		wantStatus int
+		wantEmail  string
+		wantMails  []string
+	}{
+		{
+			name:       "username only",
+			body:       map[string]string{"u": "alice2"},
+			wantStatus: http.StatusOK,
+			wantEmail:  "alice@example.com",
+		},
+		{
+			name:       "same email in other case",
+			body:       map[string]string{"m": "Alice@Example.com"},
+			wantStatus: http.StatusOK,
+			wantEmail:  "alice@example.com",
+		},
+		{
+			name:       "without password",
+			body:       map[string]string{"m": "mallory@example.com"},
+			wantStatus: http.StatusBadRequest,
+			wantEmail:  "alice@example.com",
+		},
+		{
+			name:       "wrong password",
+			body:       map[string]string{"m": "mallory@example.com", "p": "wrong password"},
+			wantStatus: http.StatusUnauthorized,
+			wantEmail:  "alice@example.com",
+		},
+		{
+			name:       "confirmed",
+			body:       map[string]string{"m": "alice@new.example.com", "p": "correct horse battery"},
+			wantStatus: http.StatusOK,
+			wantEmail:  "alice@new.example.com",
+			wantMails:  []string{"alice@new.example.com", "alice@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, nil)
+			alice := env.store.addUser(t, "alice", "alice@example.com", "correct horse battery")
+
+			if resp := env.do(t, http.MethodPatch, "/account/", &alice, tt.body); resp.status != tt.wantStatus {
+				t.Fatalf("update profile = %d %v, want %d", resp.status, resp.body, tt.wantStatus)
+			}
+
+			if got := env.store.user(alice.ID).Email; got != tt.wantEmail {
+				t.Errorf("email = %q, want %q", got, tt.wantEmail)
+			}
+
+			var recipients []string
+			for _, msg := range env.mailer.sent {
+				recipients = append(recipients, msg.To)
+			}
+			if !slices.Equal(recipients, tt.wantMails) {
+				t.Errorf("mails sent to %v, want %v", recipients, tt.wantMails)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileNotifiesOldEmail(t *testing.T) {
+	env := newTestEnv(t, nil)
+	alice := env.store.addUser(t, "alice", "alice@example.com", "correct horse battery")
+
+	body := map[string]string{"m": "alice@new.example.com", "p": "correct horse battery"}
+	if resp := env.do(t, http.MethodPatch, "/account/", &alice, body); resp.status != http.StatusOK {
+		t.Fatalf("update profile = %d %v", resp.status, resp.body)
+	}
+
+	if user := env.store.user(alice.ID); user.IsVerified {
+		t.Error("account is still verified after the email changed")
+	}
+
+	notice := env.mailer.sent[len(env.mailer.sent)-1]
+	if notice.To != "alice@example.com" || !strings.Contains(notice.Body, "alice@new.example.com") {
+		t.Errorf("notice = %+v, want a message to the old address naming the new one", notice)
+	}
+
+	if got := env.audit.actions(models.AuditSuccess); !slices.Contains(got, models.AuditEmailChange) {
+		t.Errorf("recorded actions = %v, want %q", got, models.AuditEmailChange)
+	}
+}
+
+func TestUpdateProfileEmailChangeFailureIsAudited(t *testing.T) {
+	env := newTestEnv(t, nil)
+	alice := env.store.addUser(t, "alice", "alice@example.com", "correct horse battery")
+
+	env.do(t, http.MethodPatch, "/account/", &alice, map[string]string{"m": "mallory@example.com", "p": "wrong password"})
+
+	if got := env.audit.actions(models.AuditFailure); !slices.Equal(got, []string{models.AuditEmailChange}) {
+		t.Errorf("recorded failures = %v, want [%s]", got, models.AuditEmailChange)
+	}
+}
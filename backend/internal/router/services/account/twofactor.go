@@ -0,0 +1,397 @@
+package account
+
+import (
+	"TextVault/internal/lib/jwt"
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"TextVault/pkg/random"
+	"TextVault/pkg/totp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	totpIssuer = "TextVault"
+
+	twoFactorChallengeTTL = 5 * time.Minute
+
+	// At most twoFactorAttemptLimit codes can be tried per challenge.
+	twoFactorAttemptLimit = 5
+
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+// errTwoFactorLocked is returned by checkTwoFactorCode while the 2FA codes of a user are locked.
+var errTwoFactorLocked = errors.New("too many two-factor attempts, try again later")
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type loginTwoFactorRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+// disableTwoFactorRequest is a struct that represents the request body for disabling 2FA.
+// Accounts without a password confirm with Reauth instead of Password (see confirmUser).
+type disableTwoFactorRequest struct {
+	Password string `json:"p"`
+	Reauth   string `json:"reauth"`
+	Code     string `json:"code"`
+}
+
+// EnrollTwoFactor generates a new TOTP secret for the authenticated user.
+// The secret is not used for logins until it is confirmed by ConfirmTwoFactor.
+// If 2FA is already enabled, it returns a 409 Conflict status with an error message.
+// On success, it returns a 200 OK status with the secret and its otpauth URI in the response.
+func (s *Service) EnrollTwoFactor(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.EnrollTwoFactor"
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return s.unauthorizedResponse(c)
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int64("user_id", principal.ID),
+	)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	err = s.accountSaver.SetTOTPSecret(c.UserContext(), principal.ID, secret)
+	if err != nil {
+		if errors.Is(err, storage.ErrTwoFactorEnabled) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "two-factor authentication is already enabled",
+			})
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	log.Info("Two-factor enrolment started")
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"secret": secret,
+		"uri":    totp.URI(totpIssuer, principal.Username, secret),
+	})
+}
+
+// ConfirmTwoFactor enables 2FA for the authenticated user once a first code of the pending secret is valid.
+// If the code is invalid, it returns a 400 Bad Request status with an error message.
+// On success, it returns a 200 OK status with one-time recovery codes in the response; they are shown only once.
+func (s *Service) ConfirmTwoFactor(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.ConfirmTwoFactor"
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return s.unauthorizedResponse(c)
+	}
+
+	p := new(twoFactorCodeRequest)
+
+	if err := c.BodyParser(p); err != nil || len(p.Code) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "code is required",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int64("user_id", principal.ID),
+	)
+
+	user, err := s.accountGetter.GetUserByID(c.UserContext(), principal.ID)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if user.TOTPEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "two-factor authentication is already enabled",
+		})
+	}
+
+	if user.TOTPSecret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "two-factor enrolment was not started",
+		})
+	}
+
+	step, ok := totp.Validate(p.Code, user.TOTPSecret, time.Now())
+	if !ok {
+		return s.invalidTwoFactorResponse(c)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		code := random.String(recoveryCodeLength)
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, hashToken(code))
+	}
+
+	if err := s.accountSaver.EnableTOTP(c.UserContext(), user.ID, hashes); err != nil {
+		if errors.Is(err, storage.ErrTwoFactorEnabled) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "two-factor authentication is already enabled",
+			})
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	// @NOTE: Burn the confirmation code so it can't be replayed for a login
+	if err := s.accountSaver.UseTOTPStep(c.UserContext(), user.ID, step); err != nil {
+		log.Warn("Failed to record confirmation code", sl.Err(err))
+	}
+
+	s.recordUserEvent(c, models.AuditTwoFactorEnable, user.ID, "")
+
+	log.Info("Two-factor authentication enabled")
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"recoveryCodes": codes,
+	})
+}
+
+// DisableTwoFactor disables 2FA for the authenticated user after confirming the password and a valid code.
+// Accounts without a password confirm with a reauthentication token instead of the password.
+// If the password or the code is missing, it returns a 400 Bad Request status with an error message.
+// If the password is incorrect, it returns a 401 Unauthorized status with an error message.
+// If the code is invalid, it returns a 400 Bad Request status with an error message.
+func (s *Service) DisableTwoFactor(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.DisableTwoFactor"
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return s.unauthorizedResponse(c)
+	}
+
+	p := new(disableTwoFactorRequest)
+
+	if err := c.BodyParser(p); err != nil || len(p.Code) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "password and code are required",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int64("user_id", principal.ID),
+	)
+
+	user, err := s.accountGetter.GetUserByID(c.UserContext(), principal.ID)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if !user.TOTPEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "two-factor authentication is not enabled",
+		})
+	}
+
+	// @NOTE: The code is the second factor here, so it can't also stand in for the password
+	if err := s.confirmUser(c, user, confirmation{Password: p.Password, Reauth: p.Reauth}, false, log); err != nil {
+		return s.confirmationErrorResponse(c, err, log)
+	}
+
+	if err := s.checkTwoFactorCode(c, user, p.Code, log); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInvalidTwoFactor):
+			return s.invalidTwoFactorResponse(c)
+		case errors.Is(err, errTwoFactorLocked):
+			return s.twoFactorLockedResponse(c)
+		default:
+			return s.handleInternalServerError(c, err, log)
+		}
+	}
+
+	if err := s.accountSaver.DisableTOTP(c.UserContext(), user.ID); err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	s.recordUserEvent(c, models.AuditTwoFactorDisable, user.ID, "")
+
+	log.Info("Two-factor authentication disabled")
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// LoginTwoFactor completes a login of a user with 2FA enabled. It requires the challenge token
+// returned by Login and either a TOTP code or an unused recovery code.
+// If the challenge is invalid, expired or older than the user's last session revocation, it returns a 401 Unauthorized status with an error message.
+// If the code is invalid, it returns a 400 Bad Request status with an error message.
+// If too many codes were tried with the challenge, it returns a 429 Too Many Requests status with an error message.
+// On success, it returns a 200 OK status with a JWT token in the response.
+func (s *Service) LoginTwoFactor(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.account.LoginTwoFactor"
+
+	p := new(loginTwoFactorRequest)
+
+	if err := c.BodyParser(p); err != nil || len(p.Challenge) == 0 || len(p.Code) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "challenge and code are required",
+		})
+	}
+
+	claims, err := jwt.ValidatePurposeToken(p.Challenge, jwt.PurposeTwoFactor)
+	if err != nil {
+		sl.FromContext(c.UserContext(), s.log).Warn("Invalid two-factor challenge", slog.String("op", prefix), sl.Err(err))
+
+		return s.unauthorizedResponse(c)
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int64("user_id", claims.ID),
+	)
+
+	// @NOTE: Attempts are counted per challenge, so other logins of the user can't use up this one's
+	attemptsKey := fmt.Sprintf("two_factor:%d:%s", claims.ID, claims.TokenID)
+
+	attempts, err := s.cacheProvider.Incr(c.UserContext(), attemptsKey, twoFactorChallengeTTL)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if attempts > twoFactorAttemptLimit {
+		log.Warn("Two-factor attempt limit exceeded")
+
+		s.recordFailedLogin(c, &models.User{ID: claims.ID}, "", "too many two-factor attempts")
+
+		return s.twoFactorLockedResponse(c)
+	}
+
+	user, err := s.accountGetter.GetUserByID(c.UserContext(), claims.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return s.unauthorizedResponse(c)
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	// @NOTE: A challenge issued before the password was changed or the sessions were revoked is rejected
+	if !user.TOTPEnabled || user.Email != claims.Email || user.TokenVersion != claims.Version {
+		log.Warn("Stale two-factor challenge rejected")
+
+		return s.unauthorizedResponse(c)
+	}
+
+	if user.IsBanned {
+		log.Warn("Banned user attempted to login")
+
+		s.recordFailedLogin(c, &user, user.Username, "banned")
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":  "user is banned",
+			"reason": user.BanReason,
+			"until":  user.BannedUntil,
+		})
+	}
+
+	if err := s.verifyTwoFactorCode(c.UserContext(), user, p.Code); err != nil {
+		if errors.Is(err, storage.ErrInvalidTwoFactor) {
+			log.Info("Invalid two-factor code")
+
+			s.recordFailedLogin(c, &user, user.Username, "invalid two-factor code")
+
+			return s.invalidTwoFactorResponse(c)
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if err := s.cacheProvider.Delete(c.UserContext(), attemptsKey); err != nil {
+		log.Error("Failed to reset two-factor attempts", sl.Err(err))
+	}
+
+	s.recordUserEvent(c, models.AuditLogin, user.ID, "two_factor")
+
+	token, err := s.newSessionToken(c, user, models.AuditLogin)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	log.Info("Successfully logged in user")
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"token": token,
+	})
+}
+
+// checkTwoFactorCode verifies a 2FA code confirming an action of the logged-in user with verifyTwoFactorCode.
+// Invalid codes count towards a lockout of the user's codes (see loginguard.go), so a stolen session can't
+// guess them. While the codes are locked, it returns errTwoFactorLocked without checking the code.
+func (s *Service) checkTwoFactorCode(c *fiber.Ctx, user models.User, code string, log *slog.Logger) error {
+	subjects := twoFactorSubjects(s.loginPolicy, user)
+
+	// @NOTE: The lockout fails open like the one of Login
+	lockout, err := s.loginLockout(c.UserContext(), subjects)
+	if err != nil {
+		log.Error("Failed to check two-factor lockout", sl.Err(err))
+	}
+
+	if lockout > 0 {
+		log.Warn("Two-factor code while locked")
+
+		return errTwoFactorLocked
+	}
+
+	if err := s.verifyTwoFactorCode(c.UserContext(), user, code); err != nil {
+		if errors.Is(err, storage.ErrInvalidTwoFactor) {
+			s.recordLoginFailure(c, subjects, log)
+		}
+
+		return err
+	}
+
+	s.resetLoginFailures(c.UserContext(), subjects, log)
+
+	return nil
+}
+
+// verifyTwoFactorCode accepts either a TOTP code of the user's secret or one of their unused recovery codes.
+// An accepted code is consumed. If the code is invalid, the function returns ErrInvalidTwoFactor.
+func (s *Service) verifyTwoFactorCode(ctx context.Context, user models.User, code string) error {
+	code = strings.TrimSpace(code)
+
+	if step, ok := totp.Validate(code, user.TOTPSecret, time.Now()); ok {
+		return s.accountSaver.UseTOTPStep(ctx, user.ID, step)
+	}
+
+	recoveryCode := strings.ReplaceAll(code, "-", "")
+	if len(recoveryCode) != recoveryCodeLength {
+		return storage.ErrInvalidTwoFactor
+	}
+
+	return s.accountSaver.UseRecoveryCode(ctx, user.ID, hashToken(recoveryCode))
+}
+
+func (s *Service) twoFactorLockedResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error": errTwoFactorLocked.Error(),
+	})
+}
+
+func (s *Service) invalidTwoFactorResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "invalid two-factor code",
+	})
+}
@@ -0,0 +1,112 @@
+package account
+
+import (
+	"TextVault/internal/storage/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// twoFactorChallenge logs in with the password and returns the 2FA challenge.
+func (env *testEnv) twoFactorChallenge(t *testing.T, login, password string) string {
+	t.Helper()
+
+	resp := env.do(t, http.MethodPost, "/account/login", nil, map[string]string{"u": login, "p": password})
+	if resp.status != http.StatusOK || resp.string("challenge") == "" {
+		t.Fatalf("login = %d %v, want a two-factor challenge", resp.status, resp.body)
+	}
+
+	return resp.string("challenge")
+}
+
+func TestLoginTwoFactorRepeatedLogins(t *testing.T) {
+	env := newTestEnv(t, nil)
+	alice := env.store.addUser(t, "alice", "alice@example.com", "correct horse battery")
+
+	var codes []string
+	for i := 0; i < 2*twoFactorAttemptLimit; i++ {
+		codes = append(codes, fmt.Sprintf("recov-%05d", i))
+	}
+	env.store.enableTwoFactor(alice.ID, codes...)
+
+	// @NOTE: Completed logins must not use up the attempts of later ones
+	for i, code := range codes {
+		challenge := env.twoFactorChallenge(t, "alice", "correct horse battery")
+
+		resp := env.do(t, http.MethodPost, "/account/login/2fa", nil, map[string]string{"challenge": challenge, "code": code})
+		if resp.status != http.StatusOK || resp.string("token") == "" {
+			t.Fatalf("two-factor login %d = %d %v, want a token", i+1, resp.status, resp.body)
+		}
+	}
+}
+
+func TestLoginTwoFactorAttemptsPerChallenge(t *testing.T) {
+	env := newTestEnv(t, nil)
+	alice := env.store.addUser(t, "alice", "alice@example.com", "correct horse battery")
+	env.store.enableTwoFactor(alice.ID, "recov-00001")
+
+	attacker := env.twoFactorChallenge(t, "alice", "correct horse battery")
+	owner := env.twoFactorChallenge(t, "alice", "correct horse battery")
+
+	for i := 0; i < twoFactorAttemptLimit; i++ {
+		resp := env.do(t, http.MethodPost, "/account/login/2fa", nil, map[string]string{"challenge": attacker, "code": "000000"})
+		if resp.status != http.StatusBadRequest {
+			t.Fatalf("invalid code %d status = %d, want %d", i+1, resp.status, http.StatusBadRequest)
+		}
+	}
+
+	resp := env.do(t, http.MethodPost, "/account/login/2fa", nil, map[string]string{"challenge": attacker, "code": "recov-00001"})
+	if resp.status != http.StatusTooManyRequests {
+		t.Fatalf("code after %d invalid ones status = %d, want %d", twoFactorAttemptLimit, resp.status, http.StatusTooManyRequests)
+	}
+
+	resp = env.do(t, http.MethodPost, "/account/login/2fa", nil, map[string]string{"challenge": owner, "code": "recov-00001"})
+	if resp.status != http.StatusOK {
+		t.Errorf("code with another challenge = %d %v, want %d", resp.status, resp.body, http.StatusOK)
+	}
+}
+
+func TestConfirmUserTwoFactorLockout(t *testing.T) {
+	env := newTestEnv(t, nil)
+	dave := env.store.addUser(t, "dave", "dave@example.com", "")
+	env.store.enableTwoFactor(dave.ID, "recov-00001")
+
+	// maxAttempts is the UserMaxAttempts of newTestEnv.
+	const maxAttempts = 5
+
+	change := map[string]string{"m": "mallory@example.com", "code": "000000"}
+	for i := 0; i < maxAttempts; i++ {
+		if resp := env.do(t, http.MethodPatch, "/account/", &dave, change); resp.status != http.StatusBadRequest {
+			t.Fatalf("invalid code %d status = %d, want %d", i+1, resp.status, http.StatusBadRequest)
+		}
+	}
+
+	// @NOTE: Once locked, even a valid code is refused, so codes can't be guessed through a stolen session
+	change["code"] = "recov-00001"
+	if resp := env.do(t, http.MethodPatch, "/account/", &dave, change); resp.status != http.StatusTooManyRequests {
+		t.Fatalf("valid code while locked status = %d, want %d", resp.status, http.StatusTooManyRequests)
+	}
+	if resp := env.do(t, http.MethodDelete, "/account/", &dave, map[string]string{"code": "recov-00001"}); resp.status != http.StatusTooManyRequests {
+		t.Fatalf("account deletion while locked status = %d, want %d", resp.status, http.StatusTooManyRequests)
+	}
+
+	if got := env.store.user(dave.ID).Email; got != "dave@example.com" {
+		t.Errorf("email = %q, want it unchanged", got)
+	}
+
+	lockouts := env.audit.lockouts()
+	if len(lockouts) != 1 || lockouts[0].TargetType != models.AuditTargetUser || lockouts[0].TargetID != strconv.FormatInt(dave.ID, 10) {
+		t.Errorf("recorded lockouts = %+v, want one targeting the user", lockouts)
+	}
+
+	var failedChanges int
+	for _, action := range env.audit.actions(models.AuditFailure) {
+		if action == models.AuditEmailChange {
+			failedChanges++
+		}
+	}
+	if failedChanges != maxAttempts {
+		t.Errorf("recorded %d failed email changes, want %d", failedChanges, maxAttempts)
+	}
+}
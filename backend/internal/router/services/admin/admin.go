@@ -0,0 +1,421 @@
+package admin
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// Actions recorded in the admin action log.
+const (
+	actionBanUser         = "ban_user"
+	actionUnbanUser       = "unban_user"
+	actionDeletePaste     = "delete_paste"
+	actionPurgeUserPastes = "purge_user_pastes"
+	actionResetTwoFactor  = "reset_two_factor"
+	actionHidePaste       = "hide_paste"
+	actionDismissReports  = "dismiss_reports"
+)
+
+type Service struct {
+	userManager    UserManager
+	pasteManager   PasteManager
+	pasteProvider  PasteProvider
+	cacheProvider  CacheProvider
+	actionRecorder ActionRecorder
+	reportManager  ReportManager
+	auditRecorder  AuditRecorder
+	auditGetter    AuditGetter
+
+	log *slog.Logger
+}
+
+// UserManager is an interface that provides methods for listing and moderating users in the database.
+type UserManager interface {
+	ListUsers(ctx context.Context, query string, limit, offset int) ([]models.User, error)
+	BanUser(ctx context.Context, id int64, reason string, until *time.Time) error
+	UnbanUser(ctx context.Context, id int64) error
+	DisableTOTP(ctx context.Context, id int64) error
+}
+
+// PasteManager is an interface that provides methods for deleting any paste from the database.
+type PasteManager interface {
+	GetPaste(ctx context.Context, hash string) (models.Paste, error)
+	DeletePaste(ctx context.Context, id string) error
+	DeleteUserPastes(ctx context.Context, authorID int64) ([]string, error)
+}
+
+// PasteProvider is an interface that provides a method for deleting paste content from s3 storage.
+type PasteProvider interface {
+	DeletePaste(ctx context.Context, objectKey string) error
+}
+
+type CacheProvider interface {
+	Delete(ctx context.Context, key string) error
+}
+
+// ActionRecorder is an interface that provides a method for recording admin actions.
+type ActionRecorder interface {
+	SaveAdminAction(ctx context.Context, action *models.AdminAction) error
+}
+
+// ReportManager is an interface that provides methods for reviewing paste reports and hiding reported pastes.
+type ReportManager interface {
+	GetPasteReports(ctx context.Context, status string, limit, offset int) ([]models.PasteReport, error)
+	GetPasteReport(ctx context.Context, id int64) (models.PasteReport, error)
+	ResolvePasteReports(ctx context.Context, pasteID, status string, resolvedBy int64) (int64, error)
+	SetPasteHidden(ctx context.Context, id string, hidden bool) error
+}
+
+// userResponse is a struct that represents a user in admin responses.
+type userResponse struct {
+	ID          int64      `json:"id"`
+	Username    string     `json:"username"`
+	Email       string     `json:"email"`
+	IsAdmin     bool       `json:"isAdmin"`
+	IsBanned    bool       `json:"isBanned"`
+	BanReason   string     `json:"banReason,omitempty"`
+	BannedUntil *time.Time `json:"bannedUntil,omitempty"`
+}
+
+// banRequest is a struct that represents the request body for banning a user.
+// A missing Until bans the user permanently.
+type banRequest struct {
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until"`
+}
+
+// New creates a new admin service.
+func New(log *slog.Logger,
+	userManager UserManager,
+	pasteManager PasteManager,
+	pasteProvider PasteProvider,
+	cacheProvider CacheProvider,
+	actionRecorder ActionRecorder,
+	reportManager ReportManager,
+	auditRecorder AuditRecorder,
+	auditGetter AuditGetter,
+) *Service {
+	return &Service{
+		userManager:    userManager,
+		pasteManager:   pasteManager,
+		pasteProvider:  pasteProvider,
+		cacheProvider:  cacheProvider,
+		actionRecorder: actionRecorder,
+		reportManager:  reportManager,
+		auditRecorder:  auditRecorder,
+		auditGetter:    auditGetter,
+		log:            log,
+	}
+}
+
+// ListUsers returns users matching the optional "q" query parameter, which is searched in usernames and emails.
+// The result is paginated with the "limit" and "offset" query parameters.
+// On success, it returns a 200 OK status with the users in the response.
+func (s *Service) ListUsers(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.admin.ListUsers"
+
+	query := c.Query("q")
+	limit := c.QueryInt("limit", defaultListLimit)
+	offset := c.QueryInt("offset", 0)
+
+	if limit <= 0 || limit > maxListLimit {
+		limit = defaultListLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("query", query),
+	)
+
+	users, err := s.userManager.ListUsers(c.UserContext(), query, limit, offset)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	response := make([]userResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, userResponse{
+			ID:          user.ID,
+			Username:    user.Username,
+			Email:       user.Email,
+			IsAdmin:     user.IsAdmin,
+			IsBanned:    user.IsBanned,
+			BanReason:   user.BanReason,
+			BannedUntil: user.BannedUntil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"users": response,
+	})
+}
+
+// BanUser bans the user given by the "id" path parameter with a reason and an optional expiry time.
+// If the user is not found, it returns a 404 Not Found status with an error message.
+// On success, it returns a 200 OK status with an empty response body.
+func (s *Service) BanUser(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.admin.BanUser"
+
+	userID, err := c.ParamsInt("id")
+	if err != nil {
+		return s.invalidUserIDResponse(c)
+	}
+
+	p := new(banRequest)
+
+	if err := c.BodyParser(p); err != nil {
+		sl.FromContext(c.UserContext(), s.log).Error("Failed to parse ban request", sl.Err(err))
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "reason is required",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int("user_id", userID),
+	)
+
+	if len(p.Reason) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "reason is required",
+		})
+	}
+
+	if p.Until != nil && p.Until.Before(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ban expiry must be in the future",
+		})
+	}
+
+	if principal, _ := middleware.GetPrincipal(c); principal.ID == int64(userID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "you can't ban yourself",
+		})
+	}
+
+	err = s.userManager.BanUser(c.UserContext(), int64(userID), p.Reason, p.Until)
+	if err != nil {
+		return s.handleUserError(c, err, log)
+	}
+
+	details := p.Reason
+	if p.Until != nil {
+		details = fmt.Sprintf("%s (until %s)", p.Reason, p.Until.Format(time.RFC3339))
+	}
+	s.recordAction(c, actionBanUser, "user", strconv.Itoa(userID), details)
+
+	log.Info("User banned")
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// UnbanUser lifts the ban of the user given by the "id" path parameter.
+// If the user is not found, it returns a 404 Not Found status with an error message.
+// On success, it returns a 200 OK status with an empty response body.
+func (s *Service) UnbanUser(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.admin.UnbanUser"
+
+	userID, err := c.ParamsInt("id")
+	if err != nil {
+		return s.invalidUserIDResponse(c)
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int("user_id", userID),
+	)
+
+	err = s.userManager.UnbanUser(c.UserContext(), int64(userID))
+	if err != nil {
+		return s.handleUserError(c, err, log)
+	}
+
+	s.recordAction(c, actionUnbanUser, "user", strconv.Itoa(userID), "")
+
+	log.Info("User unbanned")
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ResetTwoFactor disables 2FA of the user given by the "id" path parameter, e.g. when they lost
+// both their authenticator and their recovery codes. The user can enrol again afterwards.
+// If the user is not found, it returns a 404 Not Found status with an error message.
+// On success, it returns a 200 OK status with an empty response body.
+func (s *Service) ResetTwoFactor(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.admin.ResetTwoFactor"
+
+	userID, err := c.ParamsInt("id")
+	if err != nil {
+		return s.invalidUserIDResponse(c)
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int("user_id", userID),
+	)
+
+	err = s.userManager.DisableTOTP(c.UserContext(), int64(userID))
+	if err != nil {
+		return s.handleUserError(c, err, log)
+	}
+
+	s.recordAction(c, actionResetTwoFactor, "user", strconv.Itoa(userID), "")
+
+	log.Info("Two-factor authentication reset")
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// DeletePaste deletes any paste given by the "hash" path parameter, regardless of its author.
+// If the paste is not found, it returns a 404 Not Found status with an error message.
+// On success, it returns a 200 OK status with an empty response body.
+func (s *Service) DeletePaste(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.admin.DeletePaste"
+	hash := c.Params("hash")
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+	)
+
+	paste, err := s.pasteManager.GetPaste(c.UserContext(), hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrPasteNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "paste not found",
+			})
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if err := s.pasteManager.DeletePaste(c.UserContext(), paste.ID); err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	s.deletePasteContent(c, paste.ID, log)
+	s.recordAction(c, actionDeletePaste, "paste", paste.ID, fmt.Sprintf("author %d", paste.AuthorID))
+
+	log.Info("Paste force-deleted")
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// PurgeUserPastes deletes every personal paste of the user given by the "id" path parameter.
+// Pastes the user authored for an organization stay with it.
+// If the id is not a positive integer, it returns a 400 Bad Request status with an error message.
+// On success, it returns a 200 OK status with the number of deleted pastes in the response.
+func (s *Service) PurgeUserPastes(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.admin.PurgeUserPastes"
+
+	// @NOTE: Anonymous pastes have author 0, so purging it would delete every one of them
+	userID, err := c.ParamsInt("id")
+	if err != nil || userID <= 0 {
+		return s.invalidUserIDResponse(c)
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int("user_id", userID),
+	)
+
+	ids, err := s.pasteManager.DeleteUserPastes(c.UserContext(), int64(userID))
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	for _, id := range ids {
+		s.deletePasteContent(c, id, log)
+	}
+
+	s.recordAction(c, actionPurgeUserPastes, "user", strconv.Itoa(userID), fmt.Sprintf("%d pastes", len(ids)))
+
+	log.Info("User pastes purged", slog.Int("count", len(ids)))
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"deleted": len(ids),
+	})
+}
+
+// deletePasteContent removes the content of an already deleted paste from s3 storage and the cache.
+// Failures are logged only, because the paste row is gone and the content is unreachable.
+func (s *Service) deletePasteContent(c *fiber.Ctx, id string, log *slog.Logger) {
+	if err := s.pasteProvider.DeletePaste(c.UserContext(), id); err != nil {
+		log.Error("Failed to delete paste from s3 storage", slog.String("id", id), sl.Err(err))
+	}
+
+	if err := s.cacheProvider.Delete(c.UserContext(), id); err != nil {
+		log.Error("Failed to delete paste from cache", slog.String("id", id), sl.Err(err))
+	}
+}
+
+// recordAction saves an admin action performed by the caller, also to the audit log. Failures are logged only,
+// because the action itself has already been applied.
+func (s *Service) recordAction(c *fiber.Ctx, action, targetType, targetID, details string) {
+	principal, _ := middleware.GetPrincipal(c)
+
+	s.auditRecorder.Record(c, models.AuditEvent{
+		Action:     models.AuditAdminPrefix + action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+	})
+
+	err := s.actionRecorder.SaveAdminAction(c.UserContext(), &models.AdminAction{
+		AdminID:    principal.ID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+	})
+	if err != nil {
+		sl.FromContext(c.UserContext(), s.log).Error("Failed to record admin action",
+			slog.String("action", action),
+			slog.String("target_id", targetID),
+			sl.Err(err),
+		)
+	}
+}
+
+func (s *Service) handleUserError(c *fiber.Ctx, err error, log *slog.Logger) error {
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user not found",
+		})
+	}
+
+	return s.handleInternalServerError(c, err, log)
+}
+
+func (s *Service) invalidUserIDResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "invalid user id",
+	})
+}
+
+func (s *Service) handleInternalServerError(c *fiber.Ctx, err error, log *slog.Logger) error {
+	log.Error("Internal server error", sl.Err(err))
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Internal server error",
+	})
+}
@@ -0,0 +1,253 @@
+package admin
+
+import (
+	"TextVault/internal/lib/jwt"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// testAdmin is the admin calling the handlers in the tests.
+var testAdmin = models.User{ID: 1, Username: "admin", Email: "admin@example.com", IsAdmin: true}
+
+// fakeStore is an in-memory stand-in for the postgres storage. Like the database, it keeps the reports
+// of deleted pastes.
+type fakeStore struct {
+	UserManager
+
+	pastes  map[string]models.Paste
+	reports map[int64]*models.PasteReport
+	actions []models.AdminAction
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		pastes:  map[string]models.Paste{},
+		reports: map[int64]*models.PasteReport{},
+	}
+}
+
+func (f *fakeStore) GetUserByID(_ context.Context, id int64) (models.User, error) {
+	if id != testAdmin.ID {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return testAdmin, nil
+}
+
+func (f *fakeStore) GetPaste(_ context.Context, id string) (models.Paste, error) {
+	paste, ok := f.pastes[id]
+	if !ok {
+		return models.Paste{}, storage.ErrPasteNotFound
+	}
+
+	return paste, nil
+}
+
+func (f *fakeStore) DeletePaste(_ context.Context, id string) error {
+	delete(f.pastes, id)
+	return nil
+}
+
+func (f *fakeStore) DeleteUserPastes(_ context.Context, authorID int64) ([]string, error) {
+	var ids []string
+	for id, paste := range f.pastes {
+		if paste.AuthorID == authorID && paste.OrgID == nil {
+			ids = append(ids, id)
+			delete(f.pastes, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func (f *fakeStore) SaveAdminAction(_ context.Context, action *models.AdminAction) error {
+	f.actions = append(f.actions, *action)
+	return nil
+}
+
+func (f *fakeStore) report(report models.PasteReport) models.PasteReport {
+	paste, ok := f.pastes[report.PasteID]
+	report.PasteTitle = paste.Title
+	report.PasteHiddenAt = paste.HiddenAt
+	report.PasteDeleted = !ok
+
+	for _, other := range f.reports {
+		if other.PasteID == report.PasteID && other.Status == models.ReportStatusOpen {
+			report.OpenReports++
+		}
+	}
+
+	return report
+}
+
+func (f *fakeStore) GetPasteReports(_ context.Context, status string, _, _ int) ([]models.PasteReport, error) {
+	var reports []models.PasteReport
+	for _, report := range f.reports {
+		if report.Status == status {
+			reports = append(reports, f.report(*report))
+		}
+	}
+
+	return reports, nil
+}
+
+func (f *fakeStore) GetPasteReport(_ context.Context, id int64) (models.PasteReport, error) {
+	report, ok := f.reports[id]
+	if !ok {
+		return models.PasteReport{}, storage.ErrReportNotFound
+	}
+
+	return f.report(*report), nil
+}
+
+func (f *fakeStore) ResolvePasteReports(_ context.Context, pasteID, status string, resolvedBy int64) (int64, error) {
+	var resolved int64
+	now := time.Now()
+	for _, report := range f.reports {
+		if report.PasteID == pasteID && report.Status == models.ReportStatusOpen {
+			report.Status, report.ResolvedBy, report.ResolvedAt = status, &resolvedBy, &now
+			resolved++
+		}
+	}
+
+	return resolved, nil
+}
+
+func (f *fakeStore) SetPasteHidden(_ context.Context, id string, hidden bool) error {
+	paste, ok := f.pastes[id]
+	if !ok {
+		return storage.ErrPasteNotFound
+	}
+
+	paste.HiddenAt = nil
+	if hidden {
+		now := time.Now()
+		paste.HiddenAt = &now
+	}
+	f.pastes[id] = paste
+
+	return nil
+}
+
+func (f *fakeStore) GetAuditEvents(context.Context, models.AuditFilter, int, int) ([]models.AuditEvent, error) {
+	return nil, nil
+}
+
+// fakeContent is an in-memory stand-in for the s3 storage and the cache, recording the deleted keys.
+type fakeContent struct {
+	deleted []string
+}
+
+func (f *fakeContent) DeletePaste(_ context.Context, key string) error {
+	f.deleted = append(f.deleted, key)
+	return nil
+}
+
+func (f *fakeContent) Delete(_ context.Context, key string) error {
+	f.deleted = append(f.deleted, key)
+	return nil
+}
+
+// fakeAuditRecorder keeps the moderation events without checking them.
+type fakeAuditRecorder struct {
+	events []models.AuditEvent
+}
+
+func (f *fakeAuditRecorder) Record(_ *fiber.Ctx, event models.AuditEvent) {
+	f.events = append(f.events, event)
+}
+
+// newTestApp serves the admin routes backed by the fakes.
+func newTestApp(t *testing.T, store *fakeStore, content *fakeContent) *fiber.App {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := New(log, store, store, content, content, store, store, &fakeAuditRecorder{}, store)
+	auth := middleware.NewAuth(log, store)
+
+	app := fiber.New()
+	adminApi := app.Group("/admin", auth.RequireAuth, auth.RequireAdmin)
+	adminApi.Delete("/users/:id/pastes", service.PurgeUserPastes)
+	adminApi.Get("/reports", service.ListReports)
+	adminApi.Post("/reports/:id/resolve", service.ResolveReport)
+
+	return app
+}
+
+// do sends a request as testAdmin and decodes the JSON response into out, if it is not nil.
+func do(t *testing.T, app *fiber.App, method, target, body string, out any) int {
+	t.Helper()
+
+	token, err := jwt.NewToken(testAdmin)
+	if err != nil {
+		t.Fatalf("NewToken() error = %v", err)
+	}
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, target, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s decode response: %v", method, target, err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func TestPurgeUserPastes(t *testing.T) {
+	const authorID = 7
+	orgID := int64(3)
+
+	store := newFakeStore()
+	store.pastes["personal"] = models.Paste{ID: "personal", AuthorID: authorID}
+	store.pastes["org"] = models.Paste{ID: "org", AuthorID: authorID, OrgID: &orgID}
+	store.pastes["anonymous"] = models.Paste{ID: "anonymous"}
+	store.pastes["other"] = models.Paste{ID: "other", AuthorID: authorID + 1}
+
+	app := newTestApp(t, store, &fakeContent{})
+
+	for _, id := range []string{"0", "-1", "abc"} {
+		if status := do(t, app, fiber.MethodDelete, "/admin/users/"+id+"/pastes", "", nil); status != fiber.StatusBadRequest {
+			t.Errorf("purge of user %s status = %d, want %d", id, status, fiber.StatusBadRequest)
+		}
+	}
+
+	if _, ok := store.pastes["anonymous"]; !ok {
+		t.Fatal("purge of user 0 deleted an anonymous paste")
+	}
+
+	var resp struct {
+		Deleted int `json:"deleted"`
+	}
+	if status := do(t, app, fiber.MethodDelete, "/admin/users/7/pastes", "", &resp); status != fiber.StatusOK {
+		t.Fatalf("purge status = %d, want %d", status, fiber.StatusOK)
+	}
+
+	if resp.Deleted != 1 {
+		t.Errorf("purge deleted %d pastes, want 1", resp.Deleted)
+	}
+	for _, id := range []string{"org", "anonymous", "other"} {
+		if _, ok := store.pastes[id]; !ok {
+			t.Errorf("purge deleted paste %q", id)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/storage/models"
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditRecorder is an interface that provides a method for recording security-relevant events.
+type AuditRecorder interface {
+	Record(c *fiber.Ctx, event models.AuditEvent)
+}
+
+// AuditGetter is an interface that provides a method for querying the audit log.
+type AuditGetter interface {
+	GetAuditEvents(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]models.AuditEvent, error)
+}
+
+// auditEventResponse is a struct that represents an audit event in admin responses.
+type auditEventResponse struct {
+	ID         int64     `json:"id"`
+	Action     string    `json:"action"`
+	Outcome    string    `json:"outcome"`
+	ActorID    *int64    `json:"actorId,omitempty"`
+	TargetType string    `json:"targetType,omitempty"`
+	TargetID   string    `json:"targetId,omitempty"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	Details    string    `json:"details,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// ListAuditEvents queries the audit log, newest first. The events can be filtered with the query parameters
+// "user" (acted by or targeting the user), "actor", "action", "outcome", "targetType", "targetId", "ip",
+// and "since" and "until" as RFC 3339 times. The result is paginated with the "limit" and "offset" query parameters.
+// If a filter is invalid, it returns a 400 Bad Request status with an error message.
+// On success, it returns a 200 OK status with the events in the response.
+func (s *Service) ListAuditEvents(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.admin.ListAuditEvents"
+
+	limit := c.QueryInt("limit", defaultListLimit)
+	offset := c.QueryInt("offset", 0)
+
+	if limit <= 0 || limit > maxListLimit {
+		limit = defaultListLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	filter := models.AuditFilter{
+		Action:     c.Query("action"),
+		Outcome:    c.Query("outcome"),
+		TargetType: c.Query("targetType"),
+		TargetID:   c.Query("targetId"),
+		IP:         c.Query("ip"),
+	}
+
+	var err error
+	if filter.UserID, err = queryID(c, "user"); err != nil {
+		return s.invalidFilterResponse(c, "user")
+	}
+	if filter.ActorID, err = queryID(c, "actor"); err != nil {
+		return s.invalidFilterResponse(c, "actor")
+	}
+	if filter.Since, err = queryTime(c, "since"); err != nil {
+		return s.invalidFilterResponse(c, "since")
+	}
+	if filter.Until, err = queryTime(c, "until"); err != nil {
+		return s.invalidFilterResponse(c, "until")
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+	)
+
+	events, err := s.auditGetter.GetAuditEvents(c.UserContext(), filter, limit, offset)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	response := make([]auditEventResponse, 0, len(events))
+	for _, event := range events {
+		response = append(response, auditEventResponse{
+			ID:         event.ID,
+			Action:     event.Action,
+			Outcome:    event.Outcome,
+			ActorID:    event.ActorID,
+			TargetType: event.TargetType,
+			TargetID:   event.TargetID,
+			IP:         event.IP,
+			UserAgent:  event.UserAgent,
+			Details:    event.Details,
+			CreatedAt:  event.CreatedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"events": response,
+	})
+}
+
+// queryID parses an optional user ID query parameter.
+func queryID(c *fiber.Ctx, key string) (*int64, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+// queryTime parses an optional RFC 3339 time query parameter.
+func queryTime(c *fiber.Ctx, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (s *Service) invalidFilterResponse(c *fiber.Ctx, key string) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "invalid " + key + " filter",
+	})
+}
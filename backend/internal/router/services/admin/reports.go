@@ -0,0 +1,198 @@
+package admin
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Actions that resolve the reports of a paste.
+const (
+	resolveHide    = "hide"
+	resolveDelete  = "delete"
+	resolveDismiss = "dismiss"
+)
+
+// reportStatuses are the report statuses the moderation queue can be filtered by.
+var reportStatuses = map[string]bool{
+	models.ReportStatusOpen:      true,
+	models.ReportStatusHidden:    true,
+	models.ReportStatusDeleted:   true,
+	models.ReportStatusDismissed: true,
+}
+
+// reportResponse is a struct that represents a paste report in the moderation queue.
+type reportResponse struct {
+	ID           int64      `json:"id"`
+	PasteID      string     `json:"pasteId"`
+	PasteTitle   string     `json:"pasteTitle"`
+	PasteHidden  bool       `json:"pasteHidden"`
+	PasteDeleted bool       `json:"pasteDeleted"`
+	ReporterID   *int64     `json:"reporterId,omitempty"`
+	Category     string     `json:"category"`
+	Details      string     `json:"details,omitempty"`
+	Status       string     `json:"status"`
+	ResolvedBy   *int64     `json:"resolvedBy,omitempty"`
+	ResolvedAt   *time.Time `json:"resolvedAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	OpenReports  int64      `json:"openReports"`
+}
+
+// resolveRequest is a struct that represents the request body for resolving the reports of a paste.
+type resolveRequest struct {
+	Action string `json:"action"`
+}
+
+// ListReports returns the moderation queue: the paste reports with the status given by the "status" query
+// parameter, "open" by default. Reports of the most reported pastes come first, grouped by paste.
+// The result is paginated with the "limit" and "offset" query parameters.
+// On success, it returns a 200 OK status with the reports in the response.
+func (s *Service) ListReports(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.admin.ListReports"
+
+	status := c.Query("status", models.ReportStatusOpen)
+	limit := c.QueryInt("limit", defaultListLimit)
+	offset := c.QueryInt("offset", 0)
+
+	if !reportStatuses[status] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "unknown report status",
+		})
+	}
+
+	if limit <= 0 || limit > maxListLimit {
+		limit = defaultListLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("status", status),
+	)
+
+	reports, err := s.reportManager.GetPasteReports(c.UserContext(), status, limit, offset)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	response := make([]reportResponse, 0, len(reports))
+	for _, report := range reports {
+		response = append(response, reportResponse{
+			ID:           report.ID,
+			PasteID:      report.PasteID,
+			PasteTitle:   report.PasteTitle,
+			PasteHidden:  report.PasteHiddenAt != nil,
+			PasteDeleted: report.PasteDeleted,
+			ReporterID:   report.ReporterID,
+			Category:     report.Category,
+			Details:      report.Details,
+			Status:       report.Status,
+			ResolvedBy:   report.ResolvedBy,
+			ResolvedAt:   report.ResolvedAt,
+			CreatedAt:    report.CreatedAt,
+			OpenReports:  report.OpenReports,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"reports": response,
+	})
+}
+
+// ResolveReport resolves the report given by the "id" path parameter together with every other open report
+// of the same paste. The "hide" action hides the paste, "delete" deletes it and "dismiss" keeps it,
+// making it visible again if it is hidden. The reports are kept after the paste is deleted, and the reports of
+// a paste that is already gone are resolved as deleted whatever the action.
+// If the report is not found, it returns a 404 Not Found status with an error message.
+// If the report is already resolved, it returns a 409 Conflict status with an error message.
+// On success, it returns a 200 OK status with the number of resolved reports in the response.
+func (s *Service) ResolveReport(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.admin.ResolveReport"
+
+	reportID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid report id",
+		})
+	}
+
+	p := new(resolveRequest)
+
+	if err := c.BodyParser(p); err != nil ||
+		(p.Action != resolveHide && p.Action != resolveDelete && p.Action != resolveDismiss) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "action must be one of \"hide\", \"delete\" or \"dismiss\"",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int("report_id", reportID),
+		slog.String("action", p.Action),
+	)
+
+	report, err := s.reportManager.GetPasteReport(c.UserContext(), int64(reportID))
+	if err != nil {
+		if errors.Is(err, storage.ErrReportNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "report not found",
+			})
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if report.Status != models.ReportStatusOpen {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "report is already resolved",
+		})
+	}
+
+	principal, _ := middleware.GetPrincipal(c)
+
+	var status, action string
+	switch {
+	case report.PasteDeleted:
+		status, action = models.ReportStatusDeleted, actionDismissReports
+	case p.Action == resolveHide:
+		status, action = models.ReportStatusHidden, actionHidePaste
+		err = s.reportManager.SetPasteHidden(c.UserContext(), report.PasteID, true)
+	case p.Action == resolveDismiss:
+		status, action = models.ReportStatusDismissed, actionDismissReports
+		err = s.reportManager.SetPasteHidden(c.UserContext(), report.PasteID, false)
+	case p.Action == resolveDelete:
+		status, action = models.ReportStatusDeleted, actionDeletePaste
+		err = s.pasteManager.DeletePaste(c.UserContext(), report.PasteID)
+	}
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if action == actionDeletePaste {
+		s.deletePasteContent(c, report.PasteID, log)
+	} else if err := s.cacheProvider.Delete(c.UserContext(), report.PasteID); err != nil {
+		log.Error("Failed to delete paste from cache", sl.Err(err))
+	}
+
+	resolved, err := s.reportManager.ResolvePasteReports(c.UserContext(), report.PasteID, status, principal.ID)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	s.recordAction(c, action, "paste", report.PasteID, fmt.Sprintf("%d reports", resolved))
+
+	log.Info("Paste reports resolved", slog.String("paste_id", report.PasteID), slog.Int64("resolved", resolved))
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"resolved": resolved,
+	})
+}
@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"TextVault/internal/storage/models"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestResolveReportDeleteKeepsReports(t *testing.T) {
+	store := newFakeStore()
+	content := &fakeContent{}
+	app := newTestApp(t, store, content)
+
+	const pasteID = "paste-1"
+	store.pastes[pasteID] = models.Paste{ID: pasteID, Title: "spam", AuthorID: 2}
+	store.reports[1] = &models.PasteReport{ID: 1, PasteID: pasteID, Reporter: "ip:192.0.2.1", Category: "spam", Status: models.ReportStatusOpen, CreatedAt: time.Now()}
+	store.reports[2] = &models.PasteReport{ID: 2, PasteID: pasteID, Reporter: "ip:192.0.2.2", Category: "malware", Status: models.ReportStatusOpen, CreatedAt: time.Now()}
+
+	var resolved struct {
+		Resolved int64 `json:"resolved"`
+	}
+	if status := do(t, app, http.MethodPost, "/admin/reports/1/resolve", `{"action":"delete"}`, &resolved); status != http.StatusOK {
+		t.Fatalf("resolve status = %d, want %d", status, http.StatusOK)
+	}
+	if resolved.Resolved != 2 {
+		t.Errorf("resolved = %d, want 2", resolved.Resolved)
+	}
+
+	if _, ok := store.pastes[pasteID]; ok {
+		t.Error("paste was not deleted")
+	}
+	if !slices.Contains(content.deleted, pasteID) {
+		t.Error("paste content was not deleted")
+	}
+
+	var list struct {
+		Reports []reportResponse `json:"reports"`
+	}
+	if status := do(t, app, http.MethodGet, "/admin/reports?status=deleted", "", &list); status != http.StatusOK {
+		t.Fatalf("list status = %d, want %d", status, http.StatusOK)
+	}
+	if len(list.Reports) != 2 {
+		t.Fatalf("listed %d deleted reports, want 2", len(list.Reports))
+	}
+	for _, report := range list.Reports {
+		if report.PasteID != pasteID || !report.PasteDeleted || report.ResolvedBy == nil || *report.ResolvedBy != testAdmin.ID {
+			t.Errorf("report %d = paste %q, deleted %v, resolved by %v; want deleted paste %q resolved by %d",
+				report.ID, report.PasteID, report.PasteDeleted, report.ResolvedBy, pasteID, testAdmin.ID)
+		}
+	}
+}
+
+func TestResolveReportOfDeletedPaste(t *testing.T) {
+	store := newFakeStore()
+	app := newTestApp(t, store, &fakeContent{})
+
+	store.reports[1] = &models.PasteReport{ID: 1, PasteID: "gone", Reporter: "ip:192.0.2.1", Category: "spam", Status: models.ReportStatusOpen}
+
+	if status := do(t, app, http.MethodPost, "/admin/reports/1/resolve", `{"action":"hide"}`, nil); status != http.StatusOK {
+		t.Fatalf("resolve status = %d, want %d", status, http.StatusOK)
+	}
+
+	if got := store.reports[1].Status; got != models.ReportStatusDeleted {
+		t.Errorf("report status = %q, want %q", got, models.ReportStatusDeleted)
+	}
+
+	if status := do(t, app, http.MethodPost, "/admin/reports/1/resolve", `{"action":"hide"}`, nil); status != http.StatusConflict {
+		t.Errorf("second resolve status = %d, want %d", status, http.StatusConflict)
+	}
+}
@@ -0,0 +1,166 @@
+package health
+
+import (
+	"TextVault/internal/config"
+	"TextVault/internal/lib/log/sl"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Readiness states. A degraded service is ready, but an optional dependency is down.
+const (
+	statusOK          = "ok"
+	statusDegraded    = "degraded"
+	statusUnavailable = "unavailable"
+	statusDown        = "down"
+	statusTimeout     = "timeout"
+)
+
+// Check is a readiness check of a dependency. A failing optional dependency only degrades readiness.
+type Check struct {
+	Name     string
+	Optional bool
+	Run      func(ctx context.Context) error
+}
+
+type Service struct {
+	checks   []Check
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	report    readinessReport
+	checkedAt time.Time
+
+	log *slog.Logger
+}
+
+type checkResult struct {
+	Status    string `json:"status"`
+	Optional  bool   `json:"optional,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+type readinessReport struct {
+	Status    string                 `json:"status"`
+	CheckedAt time.Time              `json:"checkedAt"`
+	Checks    map[string]checkResult `json:"checks"`
+}
+
+// New creates a new health service running the given readiness checks.
+func New(log *slog.Logger, cfg config.HealthConfig, checks ...Check) *Service {
+	return &Service{
+		checks:   checks,
+		timeout:  cfg.CheckTimeout,
+		cacheTTL: cfg.CacheTTL,
+		log:      log,
+	}
+}
+
+// Liveness reports that the process is up and serving requests. It never checks dependencies,
+// so a dependency outage doesn't get the process restarted.
+func (s *Service) Liveness(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": statusOK,
+	})
+}
+
+// Readiness reports whether the service can handle requests, with the status of every dependency.
+// Results are cached for the configured time, so frequent probes don't load the dependencies.
+// It returns a 200 OK status if every required dependency is up, even if an optional one is down,
+// and a 503 Service Unavailable status otherwise.
+func (s *Service) Readiness(c *fiber.Ctx) error {
+	report := s.readiness(c.UserContext())
+
+	status := fiber.StatusOK
+	if report.Status == statusUnavailable {
+		status = fiber.StatusServiceUnavailable
+	}
+
+	return c.Status(status).JSON(report)
+}
+
+// readiness returns the cached report, or runs the checks if it is outdated.
+func (s *Service) readiness(ctx context.Context) readinessReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.checkedAt.IsZero() && time.Since(s.checkedAt) < s.cacheTTL {
+		return s.report
+	}
+
+	s.report = s.runChecks(ctx)
+	s.checkedAt = s.report.CheckedAt
+
+	return s.report
+}
+
+// runChecks runs every check concurrently, each bounded by the check timeout.
+func (s *Service) runChecks(ctx context.Context) readinessReport {
+	const prefix = "internal.router.services.health.runChecks"
+	log := s.log.With(
+		slog.String("op", prefix),
+	)
+
+	results := make([]checkResult, len(s.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, s.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Run(checkCtx)
+
+			result := checkResult{
+				Status:    statusOK,
+				Optional:  check.Optional,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+
+			if err != nil {
+				result.Status = statusDown
+				if errors.Is(err, context.DeadlineExceeded) {
+					result.Status = statusTimeout
+				}
+
+				log.Warn("Readiness check failed", slog.String("check", check.Name), sl.Err(err))
+			}
+
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	report := readinessReport{
+		Status:    statusOK,
+		CheckedAt: time.Now(),
+		Checks:    make(map[string]checkResult, len(s.checks)),
+	}
+
+	for i, check := range s.checks {
+		result := results[i]
+		report.Checks[check.Name] = result
+
+		if result.Status == statusOK {
+			continue
+		}
+
+		if !check.Optional {
+			report.Status = statusUnavailable
+		} else if report.Status == statusOK {
+			report.Status = statusDegraded
+		}
+	}
+
+	return report
+}
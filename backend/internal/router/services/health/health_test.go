@@ -0,0 +1,144 @@
+package health
+
+import (
+	"TextVault/internal/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	up   = func(context.Context) error { return nil }
+	down = func(context.Context) error { return errors.New("connection refused") }
+	hung = func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+)
+
+// readiness serves one readiness probe of the service and decodes its report.
+func readiness(t *testing.T, service *Service) (int, readinessReport) {
+	t.Helper()
+
+	app := fiber.New()
+	app.Get("/readyz", service.Readiness)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil), -1)
+	if err != nil {
+		t.Fatalf("GET /readyz error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	var report readinessReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+
+	return resp.StatusCode, report
+}
+
+func TestReadiness(t *testing.T) {
+	cfg := config.HealthConfig{CheckTimeout: 50 * time.Millisecond}
+
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantStatus int
+		want       string
+		wantChecks map[string]string
+	}{
+		{
+			name:       "all up",
+			checks:     []Check{{Name: "postgres", Run: up}, {Name: "redis", Optional: true, Run: up}},
+			wantStatus: http.StatusOK,
+			want:       statusOK,
+			wantChecks: map[string]string{"postgres": statusOK, "redis": statusOK},
+		},
+		{
+			name:       "optional down",
+			checks:     []Check{{Name: "postgres", Run: up}, {Name: "redis", Optional: true, Run: down}},
+			wantStatus: http.StatusOK,
+			want:       statusDegraded,
+			wantChecks: map[string]string{"postgres": statusOK, "redis": statusDown},
+		},
+		{
+			name:       "required down",
+			checks:     []Check{{Name: "postgres", Run: down}, {Name: "redis", Optional: true, Run: down}},
+			wantStatus: http.StatusServiceUnavailable,
+			want:       statusUnavailable,
+			wantChecks: map[string]string{"postgres": statusDown, "redis": statusDown},
+		},
+		{
+			name:       "required timeout",
+			checks:     []Check{{Name: "postgres", Run: hung}, {Name: "redis", Optional: true, Run: up}},
+			wantStatus: http.StatusServiceUnavailable,
+			want:       statusUnavailable,
+			wantChecks: map[string]string{"postgres": statusTimeout, "redis": statusOK},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := New(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, tt.checks...)
+
+			status, report := readiness(t, service)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if report.Status != tt.want {
+				t.Errorf("report status = %q, want %q", report.Status, tt.want)
+			}
+			for name, want := range tt.wantChecks {
+				if got := report.Checks[name].Status; got != want {
+					t.Errorf("check %s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadinessCache(t *testing.T) {
+	var runs atomic.Int32
+	check := Check{Name: "postgres", Run: func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}}
+
+	cfg := config.HealthConfig{CheckTimeout: time.Second, CacheTTL: time.Hour}
+	service := New(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, check)
+
+	for i := 0; i < 3; i++ {
+		readiness(t, service)
+	}
+
+	if got := runs.Load(); got != 1 {
+		t.Errorf("check ran %d times within the cache time, want 1", got)
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	// @NOTE: A failing dependency must not fail liveness, or the process gets restarted for nothing
+	service := New(slog.New(slog.NewTextHandler(io.Discard, nil)), config.HealthConfig{}, Check{Name: "postgres", Run: down})
+
+	app := fiber.New()
+	app.Get("/healthz", service.Liveness)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/healthz", nil), -1)
+	if err != nil {
+		t.Fatalf("GET /healthz error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
@@ -0,0 +1,219 @@
+package orgs
+
+import (
+	"TextVault/internal/lib/jwt"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// testOrgID is the only organization of fakeOrgs.
+const testOrgID = 1
+
+// fakeOrgs holds the member roles of one organization. Like the database, it refuses to demote
+// or remove its last owner.
+type fakeOrgs struct {
+	OrgManager
+
+	roles map[int64]string
+}
+
+func (f *fakeOrgs) GetMemberRole(_ context.Context, orgID, userID int64) (string, error) {
+	role, ok := f.roles[userID]
+	if orgID != testOrgID || !ok {
+		return "", storage.ErrNotOrgMember
+	}
+
+	return role, nil
+}
+
+func (f *fakeOrgs) AddOrganizationMember(_ context.Context, _, userID int64, role string) error {
+	if _, ok := f.roles[userID]; ok {
+		return storage.ErrAlreadyOrgMember
+	}
+
+	f.roles[userID] = role
+
+	return nil
+}
+
+func (f *fakeOrgs) UpdateMemberRole(_ context.Context, _, userID int64, role string) error {
+	if _, ok := f.roles[userID]; !ok {
+		return storage.ErrNotOrgMember
+	}
+
+	if role != models.OrgRoleOwner && f.lastOwner(userID) {
+		return storage.ErrLastOrgOwner
+	}
+
+	f.roles[userID] = role
+
+	return nil
+}
+
+func (f *fakeOrgs) RemoveOrganizationMember(_ context.Context, _, userID int64) error {
+	if _, ok := f.roles[userID]; !ok {
+		return storage.ErrNotOrgMember
+	}
+
+	if f.lastOwner(userID) {
+		return storage.ErrLastOrgOwner
+	}
+
+	delete(f.roles, userID)
+
+	return nil
+}
+
+// lastOwner reports whether the user is the only owner.
+func (f *fakeOrgs) lastOwner(userID int64) bool {
+	if f.roles[userID] != models.OrgRoleOwner {
+		return false
+	}
+
+	for id, role := range f.roles {
+		if id != userID && role == models.OrgRoleOwner {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fakeUsers resolves the callers of the tests by ID and the users to add by username.
+type fakeUsers map[int64]models.User
+
+func (f fakeUsers) GetUserByID(_ context.Context, id int64) (models.User, error) {
+	user, ok := f[id]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (f fakeUsers) GetUser(_ context.Context, username string) (models.User, error) {
+	for _, user := range f {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+
+	return models.User{}, storage.ErrUserNotFound
+}
+
+type fakeAuditRecorder struct{}
+
+func (fakeAuditRecorder) Record(*fiber.Ctx, models.AuditEvent) {}
+
+// Users of the tests. The first four are members of testOrgID with the role in their name.
+const (
+	ownerID    = 10
+	adminID    = 11
+	memberID   = 12
+	viewerID   = 13
+	outsiderID = 20
+	newbieID   = 30
+)
+
+func TestMemberRoles(t *testing.T) {
+	users := fakeUsers{}
+	for id, name := range map[int64]string{
+		ownerID: "owner", adminID: "admin", memberID: "member", viewerID: "viewer", outsiderID: "outsider", newbieID: "newbie",
+	} {
+		users[id] = models.User{ID: id, Username: name, Email: name + "@example.com"}
+	}
+
+	tests := []struct {
+		name   string
+		caller int64
+		method string
+		target string
+		body   map[string]string
+		want   int
+	}{
+		{name: "viewer adds member", caller: viewerID, method: http.MethodPost, target: "/orgs/1/members", body: map[string]string{"u": "newbie"}, want: http.StatusForbidden},
+		{name: "outsider adds member", caller: outsiderID, method: http.MethodPost, target: "/orgs/1/members", body: map[string]string{"u": "newbie"}, want: http.StatusNotFound},
+		{name: "admin adds member", caller: adminID, method: http.MethodPost, target: "/orgs/1/members", body: map[string]string{"u": "newbie"}, want: http.StatusOK},
+		{name: "admin adds owner", caller: adminID, method: http.MethodPost, target: "/orgs/1/members", body: map[string]string{"u": "newbie", "role": "owner"}, want: http.StatusForbidden},
+		{name: "owner adds owner", caller: ownerID, method: http.MethodPost, target: "/orgs/1/members", body: map[string]string{"u": "newbie", "role": "owner"}, want: http.StatusOK},
+		{name: "admin adds existing member", caller: adminID, method: http.MethodPost, target: "/orgs/1/members", body: map[string]string{"u": "member"}, want: http.StatusConflict},
+		{name: "member changes role", caller: memberID, method: http.MethodPatch, target: "/orgs/1/members/13", body: map[string]string{"role": "member"}, want: http.StatusForbidden},
+		{name: "admin promotes to admin", caller: adminID, method: http.MethodPatch, target: "/orgs/1/members/12", body: map[string]string{"role": "admin"}, want: http.StatusOK},
+		{name: "admin promotes to owner", caller: adminID, method: http.MethodPatch, target: "/orgs/1/members/12", body: map[string]string{"role": "owner"}, want: http.StatusForbidden},
+		{name: "admin demotes owner", caller: adminID, method: http.MethodPatch, target: "/orgs/1/members/10", body: map[string]string{"role": "admin"}, want: http.StatusForbidden},
+		{name: "last owner demotes themselves", caller: ownerID, method: http.MethodPatch, target: "/orgs/1/members/10", body: map[string]string{"role": "admin"}, want: http.StatusConflict},
+		{name: "owner changes role of outsider", caller: ownerID, method: http.MethodPatch, target: "/orgs/1/members/20", body: map[string]string{"role": "viewer"}, want: http.StatusNotFound},
+		{name: "member leaves", caller: memberID, method: http.MethodDelete, target: "/orgs/1/members/12", want: http.StatusOK},
+		{name: "member removes viewer", caller: memberID, method: http.MethodDelete, target: "/orgs/1/members/13", want: http.StatusForbidden},
+		{name: "admin removes member", caller: adminID, method: http.MethodDelete, target: "/orgs/1/members/12", want: http.StatusOK},
+		{name: "admin removes owner", caller: adminID, method: http.MethodDelete, target: "/orgs/1/members/10", want: http.StatusForbidden},
+		{name: "last owner leaves", caller: ownerID, method: http.MethodDelete, target: "/orgs/1/members/10", want: http.StatusConflict},
+		{name: "admin deletes organization", caller: adminID, method: http.MethodDelete, target: "/orgs/1", want: http.StatusForbidden},
+		{name: "outsider gets organization", caller: outsiderID, method: http.MethodGet, target: "/orgs/1", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgs := &fakeOrgs{roles: map[int64]string{
+				ownerID:  models.OrgRoleOwner,
+				adminID:  models.OrgRoleAdmin,
+				memberID: models.OrgRoleMember,
+				viewerID: models.OrgRoleViewer,
+			}}
+
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			service := New(log, orgs, users, nil, nil, fakeAuditRecorder{})
+			auth := middleware.NewAuth(log, users)
+
+			app := fiber.New()
+			orgApi := app.Group("/orgs", auth.RequireAuth)
+			orgApi.Get("/:id", service.GetOrganization)
+			orgApi.Delete("/:id", service.DeleteOrganization)
+			orgApi.Post("/:id/members", service.AddMember)
+			orgApi.Patch("/:id/members/:userId", service.UpdateMember)
+			orgApi.Delete("/:id/members/:userId", service.RemoveMember)
+
+			var body io.Reader
+			if tt.body != nil {
+				data, err := json.Marshal(tt.body)
+				if err != nil {
+					t.Fatalf("marshal body: %v", err)
+				}
+				body = strings.NewReader(string(data))
+			}
+
+			token, err := jwt.NewToken(users[tt.caller])
+			if err != nil {
+				t.Fatalf("NewToken() error = %v", err)
+			}
+
+			req := httptest.NewRequest(tt.method, tt.target, body)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("%s %s error = %v", tt.method, tt.target, err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Errorf("%s %s status = %d, want %d", tt.method, tt.target, resp.StatusCode, tt.want)
+			}
+			if resp.StatusCode != http.StatusOK && orgs.roles[ownerID] != models.OrgRoleOwner {
+				t.Errorf("rejected request changed the owner's role to %q", orgs.roles[ownerID])
+			}
+		})
+	}
+}
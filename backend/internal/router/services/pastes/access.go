@@ -0,0 +1,85 @@
+package pastes
+
+import (
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+)
+
+// access is what a caller may do with a paste. Every level includes the ones below it.
+type access int
+
+const (
+	accessNone access = iota
+	accessRead
+	accessWrite
+	// accessManage allows deleting the paste, changing its visibility and managing its grants.
+	accessManage
+)
+
+// resolveAccess returns what the principal may do with the paste. Anonymous callers have a nil principal.
+//
+// Anyone can read public and unlisted pastes. A personal paste is managed by its author. A paste owned
+// by an organization is managed by the organization's admins and owners and by its author while they are
+// still a member; other members can edit it and viewers can read it. Grants add read or write access on top.
+func (s *Service) resolveAccess(ctx context.Context, paste models.Paste, principal *middleware.Principal) (access, error) {
+	level := accessNone
+	if paste.Visibility != models.VisibilityPrivate {
+		level = accessRead
+	}
+
+	if principal == nil {
+		return level, nil
+	}
+
+	if paste.OrgID == nil {
+		if paste.AuthorID == principal.ID {
+			return accessManage, nil
+		}
+	} else {
+		role, err := s.orgRole(ctx, *paste.OrgID, principal.ID)
+		if err != nil {
+			return accessNone, err
+		}
+
+		switch {
+		case models.HasOrgRole(role, models.OrgRoleAdmin),
+			models.HasOrgRole(role, models.OrgRoleMember) && paste.AuthorID == principal.ID:
+			return accessManage, nil
+		case models.HasOrgRole(role, models.OrgRoleMember):
+			level = accessWrite
+		case models.HasOrgRole(role, models.OrgRoleViewer):
+			level = max(level, accessRead)
+		}
+	}
+
+	granted, err := s.grantManager.GetGrantedAccess(ctx, paste.ID, principal.ID)
+	if err != nil {
+		return accessNone, err
+	}
+
+	switch granted {
+	case models.AccessWrite:
+		level = max(level, accessWrite)
+	case models.AccessRead:
+		level = max(level, accessRead)
+	}
+
+	return level, nil
+}
+
+// orgRole returns the role of the user in the organization, or an empty string if they aren't a member.
+func (s *Service) orgRole(ctx context.Context, orgID, userID int64) (string, error) {
+	role, err := s.orgProvider.GetMemberRole(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotOrgMember) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return role, nil
+}
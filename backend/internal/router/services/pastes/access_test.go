@@ -0,0 +1,114 @@
+package pastes
+
+import (
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+const (
+	author   = 1
+	stranger = 2
+	orgID    = 10
+	brokenID = 11
+)
+
+var errStorage = errors.New("storage is down")
+
+// fakeOrgs maps "org/user" to the user's role in the organization.
+type fakeOrgs map[string]string
+
+func (f fakeOrgs) GetMemberRole(_ context.Context, orgID, userID int64) (string, error) {
+	if orgID == brokenID {
+		return "", errStorage
+	}
+
+	role, ok := f[fmt.Sprintf("%d/%d", orgID, userID)]
+	if !ok {
+		return "", storage.ErrNotOrgMember
+	}
+
+	return role, nil
+}
+
+// fakeGrants maps "paste/user" to the access granted to the user. Methods the tests don't need panic
+// through the nil embedded interface.
+type fakeGrants struct {
+	GrantManager
+
+	granted map[string]string
+}
+
+func (f fakeGrants) GetGrantedAccess(_ context.Context, pasteID string, userID int64) (string, error) {
+	return f.granted[fmt.Sprintf("%s/%d", pasteID, userID)], nil
+}
+
+func TestResolveAccess(t *testing.T) {
+	org := int64(orgID)
+	broken := int64(brokenID)
+
+	personal := func(visibility string) models.Paste {
+		return models.Paste{ID: "personal-" + visibility, AuthorID: author, Visibility: visibility}
+	}
+	owned := models.Paste{ID: "org", AuthorID: author, OrgID: &org, Visibility: models.VisibilityPrivate}
+
+	orgRole := func(role string) fakeOrgs {
+		return fakeOrgs{fmt.Sprintf("%d/%d", orgID, stranger): role, fmt.Sprintf("%d/%d", orgID, author): models.OrgRoleMember}
+	}
+	grant := func(paste models.Paste, access string) map[string]string {
+		return map[string]string{fmt.Sprintf("%s/%d", paste.ID, stranger): access}
+	}
+
+	tests := []struct {
+		name      string
+		paste     models.Paste
+		principal int64
+		orgs      fakeOrgs
+		granted   map[string]string
+		want      access
+		wantErr   error
+	}{
+		{name: "anonymous public", paste: personal(models.VisibilityPublic), want: accessRead},
+		{name: "anonymous unlisted", paste: personal(models.VisibilityUnlisted), want: accessRead},
+		{name: "anonymous private", paste: personal(models.VisibilityPrivate), want: accessNone},
+		{name: "author", paste: personal(models.VisibilityPrivate), principal: author, want: accessManage},
+		{name: "stranger public", paste: personal(models.VisibilityPublic), principal: stranger, want: accessRead},
+		{name: "stranger private", paste: personal(models.VisibilityPrivate), principal: stranger, want: accessNone},
+		{name: "read grant", paste: personal(models.VisibilityPrivate), principal: stranger, granted: grant(personal(models.VisibilityPrivate), models.AccessRead), want: accessRead},
+		{name: "write grant", paste: personal(models.VisibilityPublic), principal: stranger, granted: grant(personal(models.VisibilityPublic), models.AccessWrite), want: accessWrite},
+		{name: "grant of another paste", paste: personal(models.VisibilityPrivate), principal: stranger, granted: grant(owned, models.AccessWrite), want: accessNone},
+		{name: "org owner", paste: owned, principal: stranger, orgs: orgRole(models.OrgRoleOwner), want: accessManage},
+		{name: "org admin", paste: owned, principal: stranger, orgs: orgRole(models.OrgRoleAdmin), want: accessManage},
+		{name: "org member", paste: owned, principal: stranger, orgs: orgRole(models.OrgRoleMember), want: accessWrite},
+		{name: "org viewer", paste: owned, principal: stranger, orgs: orgRole(models.OrgRoleViewer), want: accessRead},
+		{name: "org viewer with write grant", paste: owned, principal: stranger, orgs: orgRole(models.OrgRoleViewer), granted: grant(owned, models.AccessWrite), want: accessWrite},
+		{name: "org member author", paste: owned, principal: author, orgs: orgRole(models.OrgRoleViewer), want: accessManage},
+		{name: "author who left the org", paste: owned, principal: author, orgs: fakeOrgs{}, want: accessNone},
+		{name: "non-member", paste: owned, principal: stranger, orgs: fakeOrgs{}, want: accessNone},
+		{name: "non-member with read grant", paste: owned, principal: stranger, orgs: fakeOrgs{}, granted: grant(owned, models.AccessRead), want: accessRead},
+		{name: "storage error", paste: models.Paste{ID: "broken", AuthorID: author, OrgID: &broken}, principal: stranger, want: accessNone, wantErr: errStorage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{orgProvider: tt.orgs, grantManager: fakeGrants{granted: tt.granted}}
+
+			var principal *middleware.Principal
+			if tt.principal != 0 {
+				principal = &middleware.Principal{ID: tt.principal}
+			}
+
+			got, err := s.resolveAccess(context.Background(), tt.paste, principal)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveAccess() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveAccess() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package pastes
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/lib/pow"
+	"TextVault/internal/storage"
+	"TextVault/pkg/random"
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	challengeIDLength = 16
+	rateWindow        = time.Minute
+)
+
+// GetChallenge issues a proof-of-work challenge that anonymous callers must solve before saving a paste.
+// The difficulty rises with the current rate of anonymous pastes.
+// If proofs of work are disabled, it returns a 404 Not Found status with an error message.
+// On success, it returns a 200 OK status with the challenge, its difficulty in bits and its expiry time.
+func (s *Service) GetChallenge(c *fiber.Ctx) error {
+	if !s.challengeConfig.Enabled {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "proof of work is not required",
+		})
+	}
+
+	challenge := pow.Challenge{
+		ID:         random.String(challengeIDLength),
+		Difficulty: s.challengeDifficulty(c.UserContext()),
+		ExpiresAt:  time.Now().Add(s.challengeConfig.TTL),
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"challenge":  s.challengeIssuer.Token(challenge),
+		"difficulty": challenge.Difficulty,
+		"expiresAt":  challenge.ExpiresAt,
+	})
+}
+
+// verifyProofOfWork verifies the solved challenge of an anonymous paste without using it up, see consumeProofOfWork.
+// It returns nil if the proof is missing or invalid, after it has responded with a 400 Bad Request
+// or a 403 Forbidden status.
+func (s *Service) verifyProofOfWork(c *fiber.Ctx, p *pasteBody, log *slog.Logger) *pow.Challenge {
+	if p.Challenge == "" || p.Nonce == "" {
+		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "anonymous pastes require a solved challenge from /pastes/challenge",
+		})
+
+		return nil
+	}
+
+	challenge, err := s.challengeIssuer.Verify(p.Challenge, p.Nonce, time.Now())
+	if err != nil {
+		log.Warn("Rejected proof of work", sl.Err(err))
+
+		_ = c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "invalid or expired challenge",
+		})
+
+		return nil
+	}
+
+	return &challenge
+}
+
+// consumeProofOfWork marks a verified challenge as used. It returns false if it was used before, after it has
+// responded with a 403 Forbidden status. If the cache is unavailable, a replay can't be detected and the proof
+// is accepted.
+func (s *Service) consumeProofOfWork(c *fiber.Ctx, challenge *pow.Challenge, log *slog.Logger) bool {
+	uses, err := s.cacheProvider.Incr(c.UserContext(), "pow:used:"+challenge.ID, time.Until(challenge.ExpiresAt))
+	if err != nil {
+		log.Warn("Failed to check challenge for replay", sl.Err(err))
+
+		return true
+	}
+
+	if uses > 1 {
+		log.Warn("Rejected replayed proof of work")
+
+		_ = c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "invalid or expired challenge",
+		})
+
+		return false
+	}
+
+	return true
+}
+
+// recordAnonymousPaste counts an anonymous paste towards the current rate.
+func (s *Service) recordAnonymousPaste(ctx context.Context, log *slog.Logger) {
+	if _, err := s.cacheProvider.Incr(ctx, rateWindowKey(time.Now()), 2*rateWindow); err != nil {
+		log.Warn("Failed to count anonymous paste", sl.Err(err))
+	}
+}
+
+// challengeDifficulty returns the difficulty for the current rate of anonymous pastes. The rate is estimated
+// over a sliding window from the counts of the current and the previous window.
+func (s *Service) challengeDifficulty(ctx context.Context) int {
+	now := time.Now()
+
+	current := s.windowCount(ctx, rateWindowKey(now))
+	previous := s.windowCount(ctx, rateWindowKey(now.Add(-rateWindow)))
+
+	elapsed := float64(now.Sub(now.Truncate(rateWindow))) / float64(rateWindow)
+	rate := float64(previous)*(1-elapsed) + float64(current)
+
+	difficulty := s.challengeConfig.BaseDifficulty
+	for limit := float64(s.challengeConfig.RateThreshold); rate > limit && difficulty < s.challengeConfig.MaxDifficulty; limit *= 2 {
+		difficulty++
+	}
+
+	return difficulty
+}
+
+// windowCount returns the number of anonymous pastes counted in a window, 0 if it is unknown.
+func (s *Service) windowCount(ctx context.Context, key string) int64 {
+	value, err := s.cacheProvider.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrCacheMiss) {
+			s.log.Warn("Failed to get anonymous paste rate", sl.Err(err))
+		}
+
+		return 0
+	}
+
+	count, _ := strconv.ParseInt(value, 10, 64)
+
+	return count
+}
+
+func rateWindowKey(t time.Time) string {
+	return "pow:rate:" + strconv.FormatInt(t.Truncate(rateWindow).Unix(), 10)
+}
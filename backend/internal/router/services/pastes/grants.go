@@ -0,0 +1,234 @@
+package pastes
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// grantRequest is a struct that represents the request body for granting access to a paste.
+// Exactly one of U, the username or email of a user, and Org, the ID of an organization, must be set.
+type grantRequest struct {
+	Username string `json:"u"`
+	Org      int64  `json:"org"`
+	Access   string `json:"access"`
+}
+
+type grantResponse struct {
+	ID          int64     `json:"id"`
+	UserID      *int64    `json:"userId,omitempty"`
+	OrgID       *int64    `json:"orgId,omitempty"`
+	GranteeName string    `json:"granteeName"`
+	Access      string    `json:"access"`
+	GrantedBy   int64     `json:"grantedBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ListGrants returns the access grants of the paste given by the "hash" path parameter.
+// Only callers who manage the paste can list its grants.
+func (s *Service) ListGrants(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.ListGrants"
+	hash := c.Params("hash")
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+	)
+
+	paste, err := s.managedPaste(c, hash, log)
+	if err != nil || paste == nil {
+		return err
+	}
+
+	grants, err := s.grantManager.GetPasteGrants(c.UserContext(), paste.ID)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	response := make([]grantResponse, 0, len(grants))
+	for _, grant := range grants {
+		response = append(response, grantResponse{
+			ID:          grant.ID,
+			UserID:      grant.UserID,
+			OrgID:       grant.OrgID,
+			GranteeName: grant.GranteeName,
+			Access:      grant.Access,
+			GrantedBy:   grant.GrantedBy,
+			CreatedAt:   grant.CreatedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"grants": response,
+	})
+}
+
+// GrantAccess gives a user or an organization read or write access to the paste given by the "hash" path parameter.
+// Granting a grantee again changes its access level. Only callers who manage the paste can grant access.
+// If the user or organization is not found, it returns a 404 Not Found status with an error message.
+// On success, it returns a 200 OK status with the grant ID in the response.
+func (s *Service) GrantAccess(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.GrantAccess"
+	hash := c.Params("hash")
+
+	p := new(grantRequest)
+
+	if err := c.BodyParser(p); err != nil || (p.Username == "") == (p.Org == 0) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "either a username or an organization is required",
+		})
+	}
+
+	if p.Access != models.AccessRead && p.Access != models.AccessWrite {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "access must be either \"read\" or \"write\"",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+	)
+
+	paste, err := s.managedPaste(c, hash, log)
+	if err != nil || paste == nil {
+		return err
+	}
+
+	principal, _ := middleware.GetPrincipal(c)
+
+	grant := &models.PasteGrant{
+		PasteID:   paste.ID,
+		Access:    p.Access,
+		GrantedBy: principal.ID,
+	}
+
+	if p.Org != 0 {
+		grant.OrgID = &p.Org
+	} else {
+		user, err := s.userGetter.GetUser(c.UserContext(), p.Username)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "user not found",
+				})
+			}
+
+			return s.handleInternalServerError(c, err, log)
+		}
+
+		grant.UserID = &user.ID
+	}
+
+	id, err := s.grantManager.SavePasteGrant(c.UserContext(), grant)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrOrgNotFound), errors.Is(err, storage.ErrUserNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, storage.ErrPasteNotFound):
+			return s.pasteNotFoundResponse(c)
+		default:
+			return s.handleInternalServerError(c, err, log)
+		}
+	}
+
+	grantee := fmt.Sprintf("org %d", p.Org)
+	if grant.UserID != nil {
+		grantee = fmt.Sprintf("user %d", *grant.UserID)
+	}
+	s.recordPasteEvent(c, models.AuditGrantAccess, models.AuditSuccess, paste.ID, fmt.Sprintf("%s access for %s", p.Access, grantee))
+
+	log.Info("Paste access granted", slog.Int64("grant_id", id), slog.String("access", p.Access))
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"id": id,
+	})
+}
+
+// RevokeAccess deletes the grant given by the "id" path parameter from the paste given by the "hash" path parameter.
+// Only callers who manage the paste can revoke access.
+// If the paste has no such grant, it returns a 404 Not Found status with an error message.
+func (s *Service) RevokeAccess(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.RevokeAccess"
+	hash := c.Params("hash")
+
+	grantID, err := c.ParamsInt("id")
+	if err != nil {
+		return s.grantNotFoundResponse(c)
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+		slog.Int("grant_id", grantID),
+	)
+
+	paste, err := s.managedPaste(c, hash, log)
+	if err != nil || paste == nil {
+		return err
+	}
+
+	if err := s.grantManager.DeletePasteGrant(c.UserContext(), paste.ID, int64(grantID)); err != nil {
+		if errors.Is(err, storage.ErrGrantNotFound) {
+			return s.grantNotFoundResponse(c)
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	s.recordPasteEvent(c, models.AuditRevokeAccess, models.AuditSuccess, paste.ID, fmt.Sprintf("grant %d", grantID))
+
+	log.Info("Paste access revoked")
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// managedPaste loads the paste and checks that the caller manages it. If not, it writes the error
+// response and returns a nil paste together with the result of writing it.
+func (s *Service) managedPaste(c *fiber.Ctx, hash string, log *slog.Logger) (*models.Paste, error) {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return nil, s.handleUnauthorizedResponse(c)
+	}
+
+	paste, err := s.pasteGetter.GetPaste(c.UserContext(), hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrPasteNotFound) {
+			return nil, s.pasteNotFoundResponse(c)
+		}
+
+		return nil, s.handleInternalServerError(c, err, log)
+	}
+
+	level, err := s.resolveAccess(c.UserContext(), paste, principal)
+	if err != nil {
+		return nil, s.handleInternalServerError(c, err, log)
+	}
+
+	if level < accessRead {
+		return nil, s.pasteNotFoundResponse(c)
+	}
+
+	if level < accessManage {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "you can't manage access to this paste",
+		})
+	}
+
+	return &paste, nil
+}
+
+func (s *Service) grantNotFoundResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "grant not found",
+	})
+}
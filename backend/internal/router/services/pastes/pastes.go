@@ -1,15 +1,21 @@
 package pastes
 
 import (
-	"TextVault/internal/lib/jwt"
+	"TextVault/internal/config"
 	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/lib/pow"
+	"TextVault/internal/lib/secretscan"
+	"TextVault/internal/lib/sharelink"
 	"TextVault/internal/middleware"
+	"TextVault/internal/notifier"
 	"TextVault/internal/storage"
 	"TextVault/internal/storage/models"
+	"TextVault/pkg/random"
 	"context"
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -19,13 +25,37 @@ type Service struct {
 	pasteGetter   PasteGetter
 	pasteProvider PasteProvider
 	cacheProvider CacheProvider
+	orgProvider   OrgProvider
+	grantManager  GrantManager
+	userGetter    UserGetter
+
+	transferManager TransferManager
+
+	shareLinkManager ShareLinkManager
+	shareLinkSigner  *sharelink.Signer
+	shareLinkConfig  config.ShareLinkConfig
+	publicURL        string
+
+	challengeIssuer *pow.Issuer
+	challengeConfig config.ChallengeConfig
+
+	secretPolicy *secretscan.Policy
+
+	reportManager    ReportManager
+	notifier         Notifier
+	moderationConfig config.ModerationConfig
+
+	auditRecorder AuditRecorder
+
+	metrics Metrics
 
 	log *slog.Logger
 }
 
-// PasteSaver is an interface that provides methods for saving and deleting pastes to the database.
+// PasteSaver is an interface that provides methods for saving, updating and deleting pastes to the database.
 type PasteSaver interface {
 	SavePaste(ctx context.Context, paste *models.Paste) (string, error)
+	UpdatePaste(ctx context.Context, paste *models.Paste) error
 	DeletePaste(ctx context.Context, id string) error
 }
 
@@ -46,13 +76,81 @@ type CacheProvider interface {
 	Get(ctx context.Context, key string) (string, error)
 	Delete(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) error
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// OrgProvider is an interface that provides a method for looking up roles in the organizations that own pastes.
+type OrgProvider interface {
+	GetMemberRole(ctx context.Context, orgID, userID int64) (string, error)
+}
+
+// GrantManager is an interface that provides methods for managing the access grants of pastes.
+type GrantManager interface {
+	SavePasteGrant(ctx context.Context, grant *models.PasteGrant) (int64, error)
+	GetPasteGrants(ctx context.Context, pasteID string) ([]models.PasteGrant, error)
+	DeletePasteGrant(ctx context.Context, pasteID string, id int64) error
+	GetGrantedAccess(ctx context.Context, pasteID string, userID int64) (string, error)
+}
+
+// ShareLinkManager is an interface that provides methods for managing and using the share links of pastes.
+type ShareLinkManager interface {
+	SaveShareLink(ctx context.Context, link *models.ShareLink) (string, error)
+	GetShareLinks(ctx context.Context, pasteID string) ([]models.ShareLink, error)
+	RevokeShareLink(ctx context.Context, pasteID, id string) error
+	UseShareLink(ctx context.Context, id, pasteID, ip, userAgent string) error
+	GetShareLinkUses(ctx context.Context, pasteID, id string) ([]models.ShareLinkUse, error)
+}
+
+// TransferManager is an interface that provides methods for claiming anonymous pastes and transferring pastes.
+type TransferManager interface {
+	SaveClaimToken(ctx context.Context, pasteID, tokenHash string) error
+	ClaimPaste(ctx context.Context, pasteID, tokenHash string, userID int64) error
+	SavePasteTransfer(ctx context.Context, transfer *models.PasteTransfer) (int64, error)
+	GetPasteTransfer(ctx context.Context, pasteID string) (models.PasteTransfer, error)
+	GetIncomingTransfers(ctx context.Context, userID int64) ([]models.PasteTransfer, error)
+	DeletePasteTransfer(ctx context.Context, id int64) error
+	AcceptPasteTransfer(ctx context.Context, id int64) error
+}
+
+// ReportManager is an interface that provides methods for reporting pastes and hiding reported pastes.
+type ReportManager interface {
+	SavePasteReport(ctx context.Context, report *models.PasteReport) (int64, error)
+	SetPasteHidden(ctx context.Context, id string, hidden bool) error
+}
+
+// Notifier is an interface that provides a method for notifying moderators of reported pastes.
+type Notifier interface {
+	Notify(ctx context.Context, event notifier.Event) error
+}
+
+// AuditRecorder is an interface that provides a method for recording security-relevant events.
+type AuditRecorder interface {
+	Record(c *fiber.Ctx, event models.AuditEvent)
 }
 
-// pasteBody is a struct that represents the request body for saving a new paste.
+// Metrics is an interface that provides methods for recording paste metrics.
+type Metrics interface {
+	ObserveCacheLookup(hit bool)
+	ObservePasteSize(size int)
+}
+
+// UserGetter is an interface that provides a method for looking up users by username or email.
+type UserGetter interface {
+	GetUser(ctx context.Context, username string) (models.User, error)
+}
+
+// pasteBody is a struct that represents the request body for saving a new paste. It is also
+// the cached form of a paste.
 type pasteBody struct {
-	Title    string `json:"title"`
-	Language string `json:"language"`
-	Content  string `json:"content"`
+	Title      string `json:"title"`
+	Language   string `json:"language"`
+	Content    string `json:"content"`
+	Visibility string `json:"visibility"`
+	Org        int64  `json:"org,omitempty"`
+
+	// @NOTE: The solved proof of work of anonymous pastes, never cached
+	Challenge string `json:"challenge,omitempty"`
+	Nonce     string `json:"nonce,omitempty"`
 }
 
 // New creates a new paste service.
@@ -61,64 +159,153 @@ func New(log *slog.Logger,
 	pasteGetter PasteGetter,
 	pasteProvider PasteProvider,
 	cacheProvider CacheProvider,
+	orgProvider OrgProvider,
+	grantManager GrantManager,
+	userGetter UserGetter,
+	transferManager TransferManager,
+	shareLinkManager ShareLinkManager,
+	secretPolicy *secretscan.Policy,
+	reportManager ReportManager,
+	notifier Notifier,
+	auditRecorder AuditRecorder,
+	metrics Metrics,
+	cfg *config.Config,
 ) *Service {
 	return &Service{
 		pasteSaver:    pasteSaver,
 		pasteGetter:   pasteGetter,
 		pasteProvider: pasteProvider,
 		cacheProvider: cacheProvider,
-		log:           log,
+		orgProvider:   orgProvider,
+		grantManager:  grantManager,
+		userGetter:    userGetter,
+
+		transferManager: transferManager,
+
+		shareLinkManager: shareLinkManager,
+		shareLinkSigner:  sharelink.NewSigner(cfg.ShareLinks.SigningKey),
+		shareLinkConfig:  cfg.ShareLinks,
+		publicURL:        cfg.PublicURL,
+
+		challengeIssuer: pow.NewIssuer(cfg.Challenge.SigningKey),
+		challengeConfig: cfg.Challenge,
+
+		secretPolicy: secretPolicy,
+
+		reportManager:    reportManager,
+		notifier:         notifier,
+		moderationConfig: cfg.Moderation,
+
+		auditRecorder: auditRecorder,
+
+		metrics: metrics,
+
+		log: log,
 	}
 }
 
-// SavePaste saves a new paste to the database and upload content to s3 storage. If the request contains a valid
-// authorization token, the paste's author ID is set to the user ID extracted from
-// the token. Otherwise, the author ID is set to 0 (anonymous user). The response
-// body contains the hash of the saved paste.
+// SavePaste saves a new paste to the database and upload content to s3 storage. If the request was
+// authenticated by OptionalAuth, the paste's author ID is set to the caller's user ID.
+// Otherwise, the author ID is set to 0 (anonymous user). The response
+// body contains the hash of the saved paste, and for anonymous pastes a one-time claim token for ClaimPaste.
+// Anonymous users can't create private pastes, and users with an unverified email can't create public pastes.
+// If "org" is set, the paste is owned by that organization, which requires at least the member role in it.
+// Anonymous callers must send a challenge from GetChallenge in "challenge" and its solution in "nonce".
+// The content is scanned for secrets; depending on the policy, they are reported, redacted, make the paste
+// private or get it rejected with a 422 Unprocessable Entity status. Findings are listed with their positions.
 func (s *Service) SavePaste(c *fiber.Ctx) error {
 	const prefix = "internal.router.services.paste.SavePaste"
 
-	tokenString, err := middleware.ExtractToken(c)
-
 	p := new(pasteBody)
 
 	if err := c.BodyParser(p); err != nil {
-		s.log.Error("Failed to parse save request", sl.Err(err))
+		sl.FromContext(c.UserContext(), s.log).Error("Failed to parse save request", sl.Err(err))
 
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "content is required",
 		})
 	}
 
-	log := s.log.With(
+	log := sl.FromContext(c.UserContext(), s.log).With(
 		slog.String("op", prefix),
 		slog.String("title", p.Title),
 	)
 
-	log.Info("Attempting to save paste")
-
-	log.Debug("Paste content", slog.String("content", p.Content))
+	// @NOTE: Paste content is never logged, it may contain secrets
+	log.Info("Attempting to save paste", slog.Int("size", len(p.Content)))
 
 	var AuthorID int64 = 0
-	if err == nil {
-		userID, err := middleware.GetUserIDFromToken(tokenString)
-		if err == nil {
-			AuthorID = userID
-			log.Info("User ID extracted from token", slog.Int64("user_id", userID))
-		} else {
-			log.Warn("Failed to extract user ID from token", sl.Err(err))
+	principal, ok := middleware.GetPrincipal(c)
+	if ok {
+		AuthorID = principal.ID
+		log.Info("Paste author resolved", slog.Int64("user_id", principal.ID))
+	}
+
+	visibility, err := resolveVisibility(p.Visibility, principal)
+	if err != nil {
+		log.Warn("Rejected paste visibility", slog.String("visibility", p.Visibility), sl.Err(err))
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var orgID *int64
+	if p.Org != 0 {
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "anonymous pastes can't belong to an organization",
+			})
+		}
+
+		role, err := s.orgRole(c.UserContext(), p.Org, principal.ID)
+		if err != nil {
+			return s.handleInternalServerError(c, err, log)
 		}
+
+		if !models.HasOrgRole(role, models.OrgRoleMember) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "you can't create pastes in this organization",
+			})
+		}
+
+		orgID = &p.Org
+	}
+
+	var challenge *pow.Challenge
+	if !ok && s.challengeConfig.Enabled {
+		if challenge = s.verifyProofOfWork(c, p, log); challenge == nil {
+			return nil
+		}
+	}
+
+	scan := s.scanSecrets(c, p.Content, ok, log)
+	if scan == nil {
+		return nil
+	}
+
+	p.Content = scan.Content
+	if scan.Private {
+		visibility = models.VisibilityPrivate
+	}
+
+	// @NOTE: A challenge is only used up by a paste that gets saved, so a request rejected for its body
+	// can be corrected and sent again with the same proof
+	if challenge != nil && !s.consumeProofOfWork(c, challenge, log) {
+		return nil
 	}
 
 	log.Info("Saving paste", slog.String("title", p.Title))
 
 	pasteModel := &models.Paste{
-		Title:    p.Title,
-		Language: p.Language,
-		AuthorID: AuthorID, // If token is not valid, AuthorID will be 0
+		Title:      p.Title,
+		Language:   p.Language,
+		AuthorID:   AuthorID, // If request is anonymous, AuthorID will be 0
+		Visibility: visibility,
+		OrgID:      orgID,
 	}
 
-	id, err := s.pasteSaver.SavePaste(c.Context(), pasteModel)
+	id, err := s.pasteSaver.SavePaste(c.UserContext(), pasteModel)
 	if err != nil {
 		log.Error("Failed to save paste", sl.Err(err))
 
@@ -127,7 +314,7 @@ func (s *Service) SavePaste(c *fiber.Ctx) error {
 		})
 	}
 
-	err = s.pasteProvider.UploadPaste(c.Context(), id, []byte(p.Content))
+	err = s.pasteProvider.UploadPaste(c.UserContext(), id, []byte(p.Content))
 	if err != nil {
 		log.Error("Failed to upload paste", sl.Err(err))
 
@@ -138,27 +325,62 @@ func (s *Service) SavePaste(c *fiber.Ctx) error {
 
 	log.Info("Paste saved successfully", slog.String("id", id))
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+	s.metrics.ObservePasteSize(len(p.Content))
+
+	if AuthorID == 0 {
+		s.recordAnonymousPaste(c.UserContext(), log)
+	}
+
+	response := fiber.Map{
 		"id": id,
-	})
+	}
+
+	if len(scan.Findings) > 0 {
+		response["visibility"] = visibility
+		response["findings"] = newFindingResponses(scan.Findings)
+	}
+
+	if AuthorID != 0 {
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+
+	// @NOTE: Without a claim token the paste keeps working, it just can't be claimed later
+	claimToken := random.String(claimTokenLength)
+	if err := s.transferManager.SaveClaimToken(c.UserContext(), id, hashToken(claimToken)); err != nil {
+		log.Error("Failed to save claim token", sl.Err(err))
+
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+
+	response["claimToken"] = claimToken
+
+	return c.Status(fiber.StatusOK).JSON(response)
 }
 
 // GetPaste retrieves a paste from the database and its content from S3 storage based on the provided hash.
-// If the paste is not found, it returns a 401 Unauthorized status with an error message.
+// If the paste is not found, it returns a 404 Not Found status with an error message.
+// Private pastes are only returned to callers with access to them (see resolveAccess) and are never cached.
+// A signed share link in the "link", "expires" and "sig" query parameters grants read access in place of a JWT.
+// If the paste is hidden by moderation, it returns a 451 Unavailable For Legal Reasons status, except to admins.
 // If any other error occurs during retrieval, it returns a 500 Internal Server Error status with an error message.
 // On successful retrieval, it sends the paste content as a string in the response.
 func (s *Service) GetPaste(c *fiber.Ctx) error {
 	const prefix = "internal.router.services.paste.GetPaste"
 	hash := c.Params("hash")
 
-	log := s.log.With(
+	log := sl.FromContext(c.UserContext(), s.log).With(
 		slog.String("op", prefix),
 		slog.String("hash", hash),
 	)
 
 	log.Info("Attempting to get paste")
 
-	cacheContent, err := s.cacheProvider.Get(c.Context(), hash)
+	// @NOTE: Share link views bypass the cache, so every use is counted and recorded
+	if c.Query("sig") != "" {
+		return s.getPasteWithShareLink(c, hash, log)
+	}
+
+	cacheContent, err := s.cacheProvider.Get(c.UserContext(), hash)
 	if err == nil {
 		pasteResponse := pasteBody{}
 
@@ -167,7 +389,16 @@ func (s *Service) GetPaste(c *fiber.Ctx) error {
 			return s.handleInternalServerError(c, err, log)
 		}
 
+		// @NOTE: Only entries known to be public or unlisted are served without an access check
+		if !isCacheable(pasteResponse.Visibility) {
+			log.Warn("Ignoring cache entry of a restricted paste")
+			s.metrics.ObserveCacheLookup(false)
+
+			return s.getPasteFromStorage(c, hash, log)
+		}
+
 		log.Info("Paste cache retrieved successfully", slog.String("hash", hash))
+		s.metrics.ObserveCacheLookup(true)
 
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
 			"title":    pasteResponse.Title,
@@ -176,20 +407,55 @@ func (s *Service) GetPaste(c *fiber.Ctx) error {
 		})
 	}
 
-	paste, err := s.pasteGetter.GetPaste(c.Context(), hash)
+	s.metrics.ObserveCacheLookup(false)
+
+	return s.getPasteFromStorage(c, hash, log)
+}
+
+// getPasteFromStorage responds to GetPaste from the database and S3 storage, checking the caller's access.
+func (s *Service) getPasteFromStorage(c *fiber.Ctx, hash string, log *slog.Logger) error {
+	paste, err := s.pasteGetter.GetPaste(c.UserContext(), hash)
 	if err != nil {
 		if errors.Is(err, storage.ErrPasteNotFound) {
-			s.log.Warn("Failed to find paste")
+			sl.FromContext(c.UserContext(), s.log).Warn("Failed to find paste")
 
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "paste not found",
-			})
+			return s.pasteNotFoundResponse(c)
 		}
 
 		return s.handleInternalServerError(c, err, log)
 	}
 
-	content, err := s.pasteProvider.GetPasteContent(c.Context(), hash)
+	principal, _ := middleware.GetPrincipal(c)
+
+	level, err := s.resolveAccess(c.UserContext(), paste, principal)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if level < accessRead {
+		log.Warn("Rejected access to private paste")
+
+		return s.pasteNotFoundResponse(c)
+	}
+
+	return s.respondWithPaste(c, paste, log)
+}
+
+// respondWithPaste responds with the paste and its content from S3 storage, and caches it if it is not restricted.
+// The caller's access must have been checked.
+func (s *Service) respondWithPaste(c *fiber.Ctx, paste models.Paste, log *slog.Logger) error {
+	hash := paste.ID
+
+	// @NOTE: Admins can still read hidden pastes to review their reports
+	if paste.HiddenAt != nil {
+		if principal, _ := middleware.GetPrincipal(c); principal == nil || !principal.IsAdmin {
+			log.Info("Rejected access to hidden paste")
+
+			return s.pasteHiddenResponse(c)
+		}
+	}
+
+	content, err := s.pasteProvider.GetPasteContent(c.UserContext(), hash)
 	if err != nil {
 		log.Error("Failed to get paste content", sl.Err(err))
 
@@ -201,20 +467,24 @@ func (s *Service) GetPaste(c *fiber.Ctx) error {
 	log.Info("Paste retrieved successfully", slog.String("id", paste.ID))
 
 	pasteResponse := pasteBody{
-		Title:    paste.Title,
-		Language: paste.Language,
-		Content:  string(content),
+		Title:      paste.Title,
+		Language:   paste.Language,
+		Content:    string(content),
+		Visibility: paste.Visibility,
 	}
 
-	var cacheData []byte
-	cacheData, err = json.Marshal(pasteResponse)
-	if err != nil {
-		return s.handleInternalServerError(c, err, log)
-	}
+	// @NOTE: Private and hidden pastes are never cached, so a cache hit is always readable by anyone
+	if isCacheable(paste.Visibility) && paste.HiddenAt == nil {
+		var cacheData []byte
+		cacheData, err = json.Marshal(pasteResponse)
+		if err != nil {
+			return s.handleInternalServerError(c, err, log)
+		}
 
-	err = s.cacheProvider.Set(c.Context(), hash, string(cacheData))
-	if err != nil {
-		log.Error("Failed to set cache", sl.Err(err))
+		err = s.cacheProvider.Set(c.UserContext(), hash, string(cacheData))
+		if err != nil {
+			log.Error("Failed to set cache", sl.Err(err))
+		}
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -225,40 +495,31 @@ func (s *Service) GetPaste(c *fiber.Ctx) error {
 }
 
 // DeletePaste deletes a paste from the database and s3 storage based on the provided hash.
-// If the paste is not found, it returns a 401 Unauthorized status with an error message.
+// Deleting requires managing the paste (see resolveAccess); grants never allow deleting.
+// If the paste is not found or not readable by the caller, it returns a 401 Unauthorized status with an error message.
 // If any other error occurs during deletion, it returns a 500 Internal Server Error status with an error message.
 // On successful deletion, it returns a 200 OK status with an empty response body.
 func (s *Service) DeletePaste(c *fiber.Ctx) error {
 	const prefix = "internal.router.services.paste.DeletePaste"
 	hash := c.Params("hash")
 
-	tokenString, err := middleware.ExtractToken(c)
-	if err != nil {
-		return s.handleUnauthorizedResponse(c)
-	}
-
-	token, err := jwt.ValidateToken(tokenString)
-	if err != nil {
-		return s.handleUnauthorizedResponse(c)
-	}
-
-	claims, err := jwt.ExtractUserClaims(token)
-	if err != nil {
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
 		return s.handleUnauthorizedResponse(c)
 	}
 
-	log := s.log.With(
+	log := sl.FromContext(c.UserContext(), s.log).With(
 		slog.String("op", prefix),
 		slog.String("hash", hash),
-		slog.String("mail", claims.Email),
+		slog.Int64("user_id", principal.ID),
 	)
 
 	log.Info("Attempting to delete paste")
 
-	paste, err := s.pasteGetter.GetPaste(c.Context(), hash)
+	paste, err := s.pasteGetter.GetPaste(c.UserContext(), hash)
 	if err != nil {
 		if errors.Is(err, storage.ErrPasteNotFound) {
-			s.log.Warn("Failed to find paste")
+			sl.FromContext(c.UserContext(), s.log).Warn("Failed to find paste")
 
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "paste not found",
@@ -268,14 +529,28 @@ func (s *Service) DeletePaste(c *fiber.Ctx) error {
 		return s.handleInternalServerError(c, err, log)
 	}
 
-	if paste.AuthorID != claims.ID {
+	level, err := s.resolveAccess(c.UserContext(), paste, principal)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	// @NOTE: Callers who can't read a private paste must not learn that it exists
+	if level < accessRead {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "paste not found",
+		})
+	}
+
+	if level < accessManage {
+		s.recordPasteEvent(c, models.AuditPasteDelete, models.AuditFailure, paste.ID, "not permitted")
+
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "you are not owner of this paste",
 		})
 	}
 
 	// @NOTE: Delete paste from db
-	err = s.pasteSaver.DeletePaste(c.Context(), hash)
+	err = s.pasteSaver.DeletePaste(c.UserContext(), hash)
 	if err != nil {
 		log.Error("Failed to delete paste", sl.Err(err))
 
@@ -285,7 +560,7 @@ func (s *Service) DeletePaste(c *fiber.Ctx) error {
 	}
 
 	// @NOTE: Delete paste from s3
-	err = s.pasteProvider.DeletePaste(c.Context(), hash)
+	err = s.pasteProvider.DeletePaste(c.UserContext(), hash)
 	if err != nil {
 		log.Error("Failed to delete paste from s3 storage", sl.Err(err))
 
@@ -294,13 +569,184 @@ func (s *Service) DeletePaste(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := s.cacheProvider.Exists(c.Context(), hash); err == nil {
-		_ = s.cacheProvider.Delete(c.Context(), hash)
+	if err := s.cacheProvider.Exists(c.UserContext(), hash); err == nil {
+		_ = s.cacheProvider.Delete(c.UserContext(), hash)
 	}
 
+	s.recordPasteEvent(c, models.AuditPasteDelete, models.AuditSuccess, paste.ID, "")
+
 	return c.SendStatus(fiber.StatusOK)
 }
 
+// UpdatePaste changes the title, language, content and/or visibility of the paste given by the "hash" path parameter.
+// Fields left empty are not changed. Editing requires write access; changing the visibility requires managing the paste.
+// If the paste is not found or not readable by the caller, it returns a 404 Not Found status with an error message.
+// If the caller may read but not edit the paste, it returns a 403 Forbidden status with an error message.
+// New content is scanned for secrets like in SavePaste, before anything is saved.
+// On success, it returns a 200 OK status, with the findings and the resulting visibility if secrets were found.
+func (s *Service) UpdatePaste(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.UpdatePaste"
+	hash := c.Params("hash")
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return s.handleUnauthorizedResponse(c)
+	}
+
+	p := new(pasteBody)
+
+	if err := c.BodyParser(p); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+		slog.Int64("user_id", principal.ID),
+	)
+
+	paste, err := s.pasteGetter.GetPaste(c.UserContext(), hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrPasteNotFound) {
+			return s.pasteNotFoundResponse(c)
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	level, err := s.resolveAccess(c.UserContext(), paste, principal)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if level < accessRead {
+		return s.pasteNotFoundResponse(c)
+	}
+
+	if level < accessWrite || (p.Visibility != "" && level < accessManage) {
+		log.Warn("Rejected paste edit", slog.Int("access", int(level)))
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "you can't edit this paste",
+		})
+	}
+
+	visibility := paste.Visibility
+
+	if p.Visibility != "" {
+		paste.Visibility, err = resolveVisibility(p.Visibility, principal)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if p.Title != "" {
+		paste.Title = p.Title
+	}
+
+	if p.Language != "" {
+		paste.Language = p.Language
+	}
+
+	var findings []secretscan.PolicyFinding
+	if p.Content != "" {
+		scan := s.scanSecrets(c, p.Content, true, log)
+		if scan == nil {
+			return nil
+		}
+
+		p.Content, findings = scan.Content, scan.Findings
+		if scan.Private {
+			paste.Visibility = models.VisibilityPrivate
+		}
+	}
+
+	if err := s.pasteSaver.UpdatePaste(c.UserContext(), &paste); err != nil {
+		if errors.Is(err, storage.ErrPasteNotFound) {
+			return s.pasteNotFoundResponse(c)
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if p.Content != "" {
+		if err := s.pasteProvider.UploadPaste(c.UserContext(), hash, []byte(p.Content)); err != nil {
+			return s.handleInternalServerError(c, err, log)
+		}
+	}
+
+	s.invalidateCache(c.UserContext(), hash, log)
+
+	if paste.Visibility != visibility {
+		s.recordPasteEvent(c, models.AuditVisibilityChange, models.AuditSuccess, paste.ID, visibility+" -> "+paste.Visibility)
+	}
+
+	log.Info("Paste updated")
+
+	if len(findings) > 0 {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"visibility": paste.Visibility,
+			"findings":   newFindingResponses(findings),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// isCacheable reports whether pastes with the visibility may be cached. Cached pastes are served to anyone.
+func isCacheable(visibility string) bool {
+	return visibility == models.VisibilityPublic || visibility == models.VisibilityUnlisted
+}
+
+// invalidateCache drops the cached copy of a paste after it changed.
+func (s *Service) invalidateCache(ctx context.Context, hash string, log *slog.Logger) {
+	if err := s.cacheProvider.Delete(ctx, hash); err != nil {
+		log.Error("Failed to delete paste from cache", sl.Err(err))
+	}
+}
+
+// recordPasteEvent records an event of a paste acted by the caller.
+func (s *Service) recordPasteEvent(c *fiber.Ctx, action, outcome, pasteID, details string) {
+	s.auditRecorder.Record(c, models.AuditEvent{
+		Action:     action,
+		Outcome:    outcome,
+		TargetType: models.AuditTargetPaste,
+		TargetID:   pasteID,
+		Details:    details,
+	})
+}
+
+// resolveVisibility validates the requested visibility of a new paste for the caller.
+// An empty visibility defaults to public, or to unlisted for users with an unverified email.
+func resolveVisibility(visibility string, principal *middleware.Principal) (string, error) {
+	unverified := principal != nil && !principal.IsVerified
+
+	switch visibility {
+	case "":
+		if unverified {
+			return models.VisibilityUnlisted, nil
+		}
+		return models.VisibilityPublic, nil
+	case models.VisibilityPublic:
+		if unverified {
+			return "", errors.New("verify your email to create public pastes")
+		}
+	case models.VisibilityUnlisted:
+	case models.VisibilityPrivate:
+		if principal == nil {
+			return "", errors.New("anonymous pastes can't be private")
+		}
+	default:
+		return "", errors.New("unknown visibility")
+	}
+
+	return visibility, nil
+}
+
 func (s *Service) handleInternalServerError(c *fiber.Ctx, err error, log *slog.Logger) error {
 	log.Error("Internal server error", sl.Err(err))
 
@@ -309,6 +755,12 @@ func (s *Service) handleInternalServerError(c *fiber.Ctx, err error, log *slog.L
 	})
 }
 
+func (s *Service) pasteNotFoundResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "paste not found",
+	})
+}
+
 func (s *Service) handleUnauthorizedResponse(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 		"error": "unauthorized",
@@ -0,0 +1,323 @@
+package pastes
+
+import (
+	"TextVault/internal/config"
+	"TextVault/internal/lib/jwt"
+	"TextVault/internal/lib/pow"
+	"TextVault/internal/lib/secretscan"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// fakeStore holds the users, pastes, claim tokens and pending transfers behind the paste handlers. It only
+// implements saving, claiming and accepting transfers of pastes; PasteSaver and TransferManager fill in the rest.
+type fakeStore struct {
+	PasteSaver
+	TransferManager
+
+	nextID      int
+	users       map[int64]models.User
+	pastes      map[string]models.Paste
+	claimTokens map[string]string
+	transfers   map[int64]models.PasteTransfer
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		users:       map[int64]models.User{},
+		pastes:      map[string]models.Paste{},
+		claimTokens: map[string]string{},
+		transfers:   map[int64]models.PasteTransfer{},
+	}
+}
+
+func (f *fakeStore) GetUserByID(_ context.Context, id int64) (models.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return models.User{}, storage.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (f *fakeStore) SavePaste(_ context.Context, paste *models.Paste) (string, error) {
+	f.nextID++
+	saved := *paste
+	saved.ID = "paste" + strconv.Itoa(f.nextID)
+	f.pastes[saved.ID] = saved
+
+	return saved.ID, nil
+}
+
+func (f *fakeStore) GetPaste(_ context.Context, id string) (models.Paste, error) {
+	paste, ok := f.pastes[id]
+	if !ok {
+		return models.Paste{}, storage.ErrPasteNotFound
+	}
+
+	return paste, nil
+}
+
+func (f *fakeStore) SaveClaimToken(_ context.Context, pasteID, tokenHash string) error {
+	f.claimTokens[pasteID] = tokenHash
+
+	return nil
+}
+
+func (f *fakeStore) ClaimPaste(_ context.Context, pasteID, tokenHash string, userID int64) error {
+	if f.claimTokens[pasteID] != tokenHash {
+		return storage.ErrInvalidClaimToken
+	}
+
+	delete(f.claimTokens, pasteID)
+
+	paste := f.pastes[pasteID]
+	paste.AuthorID = userID
+	f.pastes[pasteID] = paste
+
+	return nil
+}
+
+func (f *fakeStore) GetPasteTransfer(_ context.Context, pasteID string) (models.PasteTransfer, error) {
+	for _, transfer := range f.transfers {
+		if transfer.PasteID == pasteID {
+			return transfer, nil
+		}
+	}
+
+	return models.PasteTransfer{}, storage.ErrTransferNotFound
+}
+
+func (f *fakeStore) AcceptPasteTransfer(_ context.Context, id int64) error {
+	transfer, ok := f.transfers[id]
+	if !ok {
+		return storage.ErrTransferNotFound
+	}
+
+	delete(f.transfers, id)
+
+	paste := f.pastes[transfer.PasteID]
+	paste.OrgID = transfer.ToOrgID
+	if transfer.ToUserID != nil {
+		paste.AuthorID = *transfer.ToUserID
+	}
+	f.pastes[transfer.PasteID] = paste
+
+	return nil
+}
+
+// fakeContent keeps paste contents by object key.
+type fakeContent struct {
+	objects map[string][]byte
+}
+
+func (f *fakeContent) UploadPaste(_ context.Context, key string, content []byte) error {
+	f.objects[key] = content
+
+	return nil
+}
+
+func (f *fakeContent) GetPasteContent(_ context.Context, key string) ([]byte, error) {
+	return f.objects[key], nil
+}
+
+func (f *fakeContent) DeletePaste(_ context.Context, key string) error {
+	delete(f.objects, key)
+
+	return nil
+}
+
+// fakeCache caches pastes and counts challenge uses without expiring them. It remembers every deleted key,
+// so the tests can check which pastes were invalidated.
+type fakeCache struct {
+	values  map[string]string
+	deleted []string
+}
+
+func (f *fakeCache) Set(_ context.Context, key, value string) error {
+	f.values[key] = value
+
+	return nil
+}
+
+func (f *fakeCache) Get(_ context.Context, key string) (string, error) {
+	value, ok := f.values[key]
+	if !ok {
+		return "", storage.ErrCacheMiss
+	}
+
+	return value, nil
+}
+
+func (f *fakeCache) Delete(_ context.Context, key string) error {
+	delete(f.values, key)
+	f.deleted = append(f.deleted, key)
+
+	return nil
+}
+
+func (f *fakeCache) Exists(_ context.Context, key string) error {
+	if _, ok := f.values[key]; !ok {
+		return storage.ErrCacheMiss
+	}
+
+	return nil
+}
+
+func (f *fakeCache) Incr(_ context.Context, key string, _ time.Duration) (int64, error) {
+	n, _ := strconv.ParseInt(f.values[key], 10, 64)
+	n++
+	f.values[key] = strconv.FormatInt(n, 10)
+
+	return n, nil
+}
+
+// fakeAudit keeps the paste events in the order they were recorded.
+type fakeAudit struct {
+	events []models.AuditEvent
+}
+
+func (f *fakeAudit) Record(_ *fiber.Ctx, event models.AuditEvent) {
+	f.events = append(f.events, event)
+}
+
+type fakeMetrics struct{}
+
+func (fakeMetrics) ObserveCacheLookup(bool) {}
+func (fakeMetrics) ObservePasteSize(int)    {}
+
+// testEnv serves the anonymous paste creation, claim and transfer routes of a paste service backed by the fakes.
+type testEnv struct {
+	app     *fiber.App
+	store   *fakeStore
+	content *fakeContent
+	cache   *fakeCache
+	audit   *fakeAudit
+	issuer  *pow.Issuer
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	cfg := &config.Config{
+		PublicURL: "https://textvault.test",
+		Challenge: config.ChallengeConfig{
+			Enabled:        true,
+			SigningKey:     "challenge-key",
+			TTL:            time.Minute,
+			BaseDifficulty: 4,
+			MaxDifficulty:  4,
+			RateThreshold:  1000,
+		},
+		SecretScan: config.SecretScanConfig{
+			Enabled: true,
+			Policy:  secretscan.ActionWarn,
+			Rules:   map[string]string{secretscan.RuleGitHubToken: secretscan.ActionReject},
+		},
+	}
+
+	policy, err := secretscan.NewPolicy(&cfg.SecretScan)
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+
+	env := &testEnv{
+		store:   newFakeStore(),
+		content: &fakeContent{objects: map[string][]byte{}},
+		cache:   &fakeCache{values: map[string]string{}},
+		audit:   &fakeAudit{},
+		issuer:  pow.NewIssuer(cfg.Challenge.SigningKey),
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := New(log, env.store, env.store, env.content, env.cache, fakeOrgs{}, fakeGrants{}, nil, env.store, nil,
+		policy, nil, nil, env.audit, fakeMetrics{}, cfg)
+	auth := middleware.NewAuth(log, env.store)
+
+	env.app = fiber.New()
+	pasteApi := env.app.Group("/pastes")
+	pasteApi.Post("/", auth.OptionalAuth, service.SavePaste)
+	pasteApi.Get("/challenge", service.GetChallenge)
+	pasteApi.Post("/:hash/claim", auth.RequireAuth, service.ClaimPaste)
+	pasteApi.Post("/:hash/transfer/accept", auth.RequireAuth, service.AcceptTransfer)
+
+	return env
+}
+
+// addUser saves a verified user.
+func (env *testEnv) addUser(id int64, username string) models.User {
+	user := models.User{ID: id, Username: username, Email: username + "@example.com", IsVerified: true}
+	env.store.users[id] = user
+
+	return user
+}
+
+// do sends a request with the JSON body, authenticated as the user if it is not nil, and decodes
+// the JSON response.
+func (env *testEnv) do(t *testing.T, method, target string, user *models.User, body any) (int, map[string]any) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal body: %v", err)
+		}
+		reader = strings.NewReader(string(data))
+	}
+
+	req := httptest.NewRequest(method, target, reader)
+	req.Header.Set("Content-Type", "application/json")
+
+	if user != nil {
+		token, err := jwt.NewToken(*user)
+		if err != nil {
+			t.Fatalf("NewToken() error = %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := env.app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, target, err)
+	}
+	defer resp.Body.Close()
+
+	var decoded map[string]any
+	if data, _ := io.ReadAll(resp.Body); len(data) > 0 {
+		_ = json.Unmarshal(data, &decoded)
+	}
+
+	return resp.StatusCode, decoded
+}
+
+// solveChallenge gets a challenge and returns it with a solution.
+func (env *testEnv) solveChallenge(t *testing.T) (string, string) {
+	t.Helper()
+
+	status, body := env.do(t, http.MethodGet, "/pastes/challenge", nil, nil)
+	if status != http.StatusOK {
+		t.Fatalf("get challenge status = %d, want %d", status, http.StatusOK)
+	}
+
+	token, _ := body["challenge"].(string)
+	for n := 0; ; n++ {
+		nonce := strconv.Itoa(n)
+		if _, err := env.issuer.Verify(token, nonce, time.Now()); err == nil {
+			return token, nonce
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package pastes
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/middleware"
+	"TextVault/internal/notifier"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	maxReportDetailsLength = 1000
+	notifyTimeout          = 30 * time.Second
+)
+
+// reportCategories are the reasons a paste can be reported for.
+var reportCategories = map[string]bool{
+	"spam":          true,
+	"malware":       true,
+	"phishing":      true,
+	"harassment":    true,
+	"personal_data": true,
+	"copyright":     true,
+	"illegal":       true,
+	"other":         true,
+}
+
+// reportRequest is a struct that represents the request body for reporting a paste.
+type reportRequest struct {
+	Category string `json:"category"`
+	Details  string `json:"details"`
+}
+
+// ReportPaste reports the paste given by the "hash" path parameter to the moderators. Anyone who can read
+// the paste can report it once: users are told apart by their ID and anonymous callers by their IP.
+// Once the paste has as many open reports as the auto-hide threshold, it is hidden until a moderator reviews it.
+// If the paste was already reported by the caller, it returns a 409 Conflict status with an error message.
+// On success, it returns a 200 OK status with the report ID in the response.
+func (s *Service) ReportPaste(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.ReportPaste"
+	hash := c.Params("hash")
+
+	p := new(reportRequest)
+
+	if err := c.BodyParser(p); err != nil || !reportCategories[p.Category] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "a valid category is required",
+		})
+	}
+
+	p.Details = strings.TrimSpace(p.Details)
+	if utf8.RuneCountInString(p.Details) > maxReportDetailsLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "details must be at most " + strconv.Itoa(maxReportDetailsLength) + " characters long",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+	)
+
+	paste, err := s.pasteGetter.GetPaste(c.UserContext(), hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrPasteNotFound) {
+			return s.pasteNotFoundResponse(c)
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	principal, _ := middleware.GetPrincipal(c)
+
+	level, err := s.resolveAccess(c.UserContext(), paste, principal)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if level < accessRead {
+		return s.pasteNotFoundResponse(c)
+	}
+
+	report := &models.PasteReport{
+		PasteID:  paste.ID,
+		Reporter: "ip:" + c.IP(),
+		Category: p.Category,
+		Details:  p.Details,
+	}
+
+	if principal != nil {
+		report.ReporterID = &principal.ID
+		report.Reporter = "user:" + strconv.FormatInt(principal.ID, 10)
+	}
+
+	open, err := s.reportManager.SavePasteReport(c.UserContext(), report)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrAlreadyReported):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "you have already reported this paste",
+			})
+		case errors.Is(err, storage.ErrPasteNotFound):
+			return s.pasteNotFoundResponse(c)
+		default:
+			return s.handleInternalServerError(c, err, log)
+		}
+	}
+
+	log.Info("Paste reported",
+		slog.Int64("report_id", report.ID),
+		slog.String("category", report.Category),
+		slog.Int64("open_reports", open),
+	)
+
+	s.notify(c, notifier.Event{
+		Type:     notifier.EventPasteReported,
+		PasteID:  paste.ID,
+		ReportID: report.ID,
+		Category: report.Category,
+		Reports:  open,
+		Time:     time.Now(),
+	}, log)
+
+	threshold := int64(s.moderationConfig.AutoHideThreshold)
+	if threshold > 0 && open >= threshold && paste.HiddenAt == nil {
+		if err := s.reportManager.SetPasteHidden(c.UserContext(), paste.ID, true); err != nil {
+			log.Error("Failed to hide reported paste", sl.Err(err))
+		} else {
+			s.invalidateCache(c.UserContext(), paste.ID, log)
+
+			log.Warn("Paste hidden after reports", slog.Int64("open_reports", open))
+
+			s.notify(c, notifier.Event{
+				Type:    notifier.EventPasteHidden,
+				PasteID: paste.ID,
+				Reports: open,
+				Time:    time.Now(),
+			}, log)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"id": report.ID,
+	})
+}
+
+// notify notifies the moderators of the event in the background, so slow hooks don't delay the response.
+// Failures are logged only.
+func (s *Service) notify(c *fiber.Ctx, event notifier.Event, log *slog.Logger) {
+	ctx := context.WithoutCancel(c.UserContext())
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		defer cancel()
+
+		if err := s.notifier.Notify(ctx, event); err != nil {
+			log.Error("Failed to notify moderators", slog.String("event", event.Type), sl.Err(err))
+		}
+	}()
+}
+
+func (s *Service) pasteHiddenResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusUnavailableForLegalReasons).JSON(fiber.Map{
+		"error": "paste has been hidden by moderation",
+	})
+}
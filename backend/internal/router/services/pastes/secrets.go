@@ -0,0 +1,62 @@
+package pastes
+
+import (
+	"TextVault/internal/lib/secretscan"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// findingResponse is a secret found in a paste. The secret itself is never sent back.
+type findingResponse struct {
+	Rule   string `json:"rule"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Length int    `json:"length"`
+	Action string `json:"action"`
+}
+
+func newFindingResponses(findings []secretscan.PolicyFinding) []findingResponse {
+	responses := make([]findingResponse, 0, len(findings))
+	for _, finding := range findings {
+		responses = append(responses, findingResponse{
+			Rule:   finding.Rule,
+			Line:   finding.Line,
+			Column: finding.Column,
+			Length: finding.End - finding.Start,
+			Action: finding.Action,
+		})
+	}
+
+	return responses
+}
+
+// findingRules returns the rules of the findings, which can be logged unlike the secrets.
+func findingRules(findings []secretscan.PolicyFinding) []string {
+	rules := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		rules = append(rules, finding.Rule)
+	}
+
+	return rules
+}
+
+// scanSecrets checks new paste content against the secret policy.
+// If the policy rejects the content, it writes a 422 Unprocessable Entity response with the findings and returns nil.
+func (s *Service) scanSecrets(c *fiber.Ctx, content string, canBePrivate bool, log *slog.Logger) *secretscan.Result {
+	scan := s.secretPolicy.Check(content, canBePrivate)
+	if len(scan.Findings) > 0 {
+		log.Warn("Secrets found in paste", slog.Any("rules", findingRules(scan.Findings)))
+	}
+
+	if scan.Rejected {
+		_ = c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":    "paste contains secrets",
+			"findings": newFindingResponses(scan.Findings),
+		})
+
+		return nil
+	}
+
+	return &scan
+}
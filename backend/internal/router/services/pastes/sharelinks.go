@@ -0,0 +1,305 @@
+package pastes
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxUserAgentLength = 255
+
+// createShareLinkRequest is a struct that represents the request body for creating a share link.
+// ExpiresIn is in seconds; zero uses the configured default. A missing MaxViews allows unlimited views.
+type createShareLinkRequest struct {
+	ExpiresIn int64 `json:"expiresIn"`
+	MaxViews  *int  `json:"maxViews"`
+}
+
+type shareLinkResponse struct {
+	ID        string     `json:"id"`
+	URL       string     `json:"url,omitempty"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	MaxViews  *int       `json:"maxViews,omitempty"`
+	Views     int        `json:"views"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	CreatedBy int64      `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type shareLinkUseResponse struct {
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	UsedAt    time.Time `json:"usedAt"`
+}
+
+// CreateShareLink creates a signed share link that gives anyone holding it read access to the paste given by
+// the "hash" path parameter, regardless of its visibility. Only callers who manage the paste can create links.
+// If the expiry or view limit is invalid, it returns a 400 Bad Request status with an error message.
+// On success, it returns a 200 OK status with the link and its URL in the response. The URL is only returned once.
+func (s *Service) CreateShareLink(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.CreateShareLink"
+	hash := c.Params("hash")
+
+	p := new(createShareLinkRequest)
+
+	if err := c.BodyParser(p); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	ttl := s.shareLinkConfig.DefaultTTL
+	if p.ExpiresIn != 0 {
+		ttl = time.Duration(p.ExpiresIn) * time.Second
+	}
+
+	if ttl <= 0 || ttl > s.shareLinkConfig.MaxTTL {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("expiresIn must be between 1 and %d seconds", int64(s.shareLinkConfig.MaxTTL.Seconds())),
+		})
+	}
+
+	if p.MaxViews != nil && *p.MaxViews <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "maxViews must be positive",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+	)
+
+	paste, err := s.managedPaste(c, hash, log)
+	if err != nil || paste == nil {
+		return err
+	}
+
+	principal, _ := middleware.GetPrincipal(c)
+
+	now := time.Now()
+
+	// @NOTE: The URL carries the expiry in whole seconds, so the stored one must match it exactly
+	link := &models.ShareLink{
+		PasteID:   paste.ID,
+		CreatedBy: principal.ID,
+		ExpiresAt: now.Add(ttl).Truncate(time.Second),
+		MaxViews:  p.MaxViews,
+	}
+
+	link.ID, err = s.shareLinkManager.SaveShareLink(c.UserContext(), link)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	s.recordPasteEvent(c, models.AuditShareLinkCreate, models.AuditSuccess, paste.ID,
+		fmt.Sprintf("link %s until %s", link.ID, link.ExpiresAt.Format(time.RFC3339)))
+
+	log.Info("Share link created", slog.String("link_id", link.ID), slog.Time("expires_at", link.ExpiresAt))
+
+	return c.Status(fiber.StatusOK).JSON(shareLinkResponse{
+		ID:        link.ID,
+		URL:       s.shareLinkURL(link),
+		ExpiresAt: link.ExpiresAt,
+		MaxViews:  link.MaxViews,
+		CreatedBy: link.CreatedBy,
+		CreatedAt: now,
+	})
+}
+
+// ListShareLinks returns every share link of the paste given by the "hash" path parameter, including
+// expired and revoked ones, with their view counts. Only callers who manage the paste can list its links.
+func (s *Service) ListShareLinks(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.ListShareLinks"
+	hash := c.Params("hash")
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+	)
+
+	paste, err := s.managedPaste(c, hash, log)
+	if err != nil || paste == nil {
+		return err
+	}
+
+	links, err := s.shareLinkManager.GetShareLinks(c.UserContext(), paste.ID)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	response := make([]shareLinkResponse, 0, len(links))
+	for _, link := range links {
+		response = append(response, shareLinkResponse{
+			ID:        link.ID,
+			ExpiresAt: link.ExpiresAt,
+			MaxViews:  link.MaxViews,
+			Views:     link.Views,
+			RevokedAt: link.RevokedAt,
+			CreatedBy: link.CreatedBy,
+			CreatedAt: link.CreatedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"shareLinks": response,
+	})
+}
+
+// ListShareLinkUses returns the recorded uses of the share link given by the "id" path parameter.
+// Only callers who manage the paste can list them.
+func (s *Service) ListShareLinkUses(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.ListShareLinkUses"
+	hash := c.Params("hash")
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+		slog.String("link_id", c.Params("id")),
+	)
+
+	paste, err := s.managedPaste(c, hash, log)
+	if err != nil || paste == nil {
+		return err
+	}
+
+	uses, err := s.shareLinkManager.GetShareLinkUses(c.UserContext(), paste.ID, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrShareLinkNotFound) {
+			return s.shareLinkNotFoundResponse(c)
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	response := make([]shareLinkUseResponse, 0, len(uses))
+	for _, use := range uses {
+		response = append(response, shareLinkUseResponse{
+			IP:        use.IP,
+			UserAgent: use.UserAgent,
+			UsedAt:    use.UsedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"uses": response,
+	})
+}
+
+// RevokeShareLink revokes the share link given by the "id" path parameter, so it can't be used anymore.
+// Only callers who manage the paste can revoke its links.
+// If the paste has no such link, it returns a 404 Not Found status with an error message.
+func (s *Service) RevokeShareLink(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.RevokeShareLink"
+	hash := c.Params("hash")
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+		slog.String("link_id", c.Params("id")),
+	)
+
+	paste, err := s.managedPaste(c, hash, log)
+	if err != nil || paste == nil {
+		return err
+	}
+
+	if err := s.shareLinkManager.RevokeShareLink(c.UserContext(), paste.ID, c.Params("id")); err != nil {
+		if errors.Is(err, storage.ErrShareLinkNotFound) {
+			return s.shareLinkNotFoundResponse(c)
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	s.recordPasteEvent(c, models.AuditShareLinkRevoke, models.AuditSuccess, paste.ID, "link "+c.Params("id"))
+
+	log.Info("Share link revoked")
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// getPasteWithShareLink responds to GetPaste for a request signed with a share link. The signature is checked
+// before the database is queried, so forged links are rejected cheaply. Every accepted view is recorded.
+// An invalid, expired, revoked or used up link returns a 403 Forbidden status with an error message.
+func (s *Service) getPasteWithShareLink(c *fiber.Ctx, hash string, log *slog.Logger) error {
+	linkID := c.Query("link")
+
+	log = log.With(slog.String("link_id", linkID))
+
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil || linkID == "" {
+		return s.invalidShareLinkResponse(c)
+	}
+
+	expiresAt := time.Unix(expires, 0)
+
+	if !s.shareLinkSigner.Verify(linkID, hash, expiresAt, c.Query("sig")) {
+		log.Warn("Invalid share link signature")
+
+		return s.invalidShareLinkResponse(c)
+	}
+
+	if time.Now().After(expiresAt) {
+		return s.invalidShareLinkResponse(c)
+	}
+
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	if err := s.shareLinkManager.UseShareLink(c.UserContext(), linkID, hash, c.IP(), userAgent); err != nil {
+		if errors.Is(err, storage.ErrInvalidShareLink) {
+			log.Info("Rejected revoked, expired or used up share link")
+
+			return s.invalidShareLinkResponse(c)
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	paste, err := s.pasteGetter.GetPaste(c.UserContext(), hash)
+	if err != nil {
+		if errors.Is(err, storage.ErrPasteNotFound) {
+			return s.pasteNotFoundResponse(c)
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	log.Info("Paste accessed with share link")
+
+	return s.respondWithPaste(c, paste, log)
+}
+
+// shareLinkURL returns the signed URL of a share link.
+func (s *Service) shareLinkURL(link *models.ShareLink) string {
+	query := url.Values{}
+	query.Set("link", link.ID)
+	query.Set("expires", strconv.FormatInt(link.ExpiresAt.Unix(), 10))
+	query.Set("sig", s.shareLinkSigner.Sign(link.ID, link.PasteID, link.ExpiresAt))
+
+	return fmt.Sprintf("%s/pastes/%s?%s", s.publicURL, link.PasteID, query.Encode())
+}
+
+func (s *Service) invalidShareLinkResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error": "share link is invalid or expired",
+	})
+}
+
+func (s *Service) shareLinkNotFoundResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "share link not found",
+	})
+}
@@ -0,0 +1,389 @@
+package pastes
+
+import (
+	"TextVault/internal/lib/log/sl"
+	"TextVault/internal/middleware"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const claimTokenLength = 32
+
+type claimPasteRequest struct {
+	Token string `json:"token"`
+}
+
+// transferPasteRequest is a struct that represents the request body for transferring a paste.
+// Exactly one of U, the username or email of a user, and Org, the ID of an organization, must be set.
+type transferPasteRequest struct {
+	Username string `json:"u"`
+	Org      int64  `json:"org"`
+}
+
+type transferResponse struct {
+	ID           int64     `json:"id"`
+	PasteID      string    `json:"pasteId"`
+	PasteTitle   string    `json:"pasteTitle"`
+	FromUserID   int64     `json:"fromUserId"`
+	FromUsername string    `json:"fromUsername"`
+	ToUserID     *int64    `json:"toUserId,omitempty"`
+	ToOrgID      *int64    `json:"toOrgId,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// ClaimPaste makes the authenticated user the author of the anonymous paste given by the "hash" path parameter.
+// It requires the claim token returned when the paste was created; the token works only once.
+// If the token is invalid or the paste was already claimed, it returns a 403 Forbidden status with an error message.
+// On success, the paste is dropped from the cache and it returns a 200 OK status with an empty response body.
+func (s *Service) ClaimPaste(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.ClaimPaste"
+	hash := c.Params("hash")
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return s.handleUnauthorizedResponse(c)
+	}
+
+	p := new(claimPasteRequest)
+
+	if err := c.BodyParser(p); err != nil || len(p.Token) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "token is required",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+		slog.Int64("user_id", principal.ID),
+	)
+
+	if err := s.transferManager.ClaimPaste(c.UserContext(), hash, hashToken(p.Token), principal.ID); err != nil {
+		if errors.Is(err, storage.ErrInvalidClaimToken) {
+			s.recordPasteEvent(c, models.AuditPasteClaim, models.AuditFailure, hash, "invalid claim token")
+
+			log.Warn("Rejected paste claim")
+
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	// @NOTE: The cached paste still names the anonymous author
+	s.invalidateCache(c.UserContext(), hash, log)
+	s.recordPasteEvent(c, models.AuditPasteClaim, models.AuditSuccess, hash, "")
+
+	log.Info("Anonymous paste claimed")
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// TransferPaste offers the paste given by the "hash" path parameter to another user or an organization.
+// The transfer takes effect once the recipient accepts it; a new offer replaces a pending one.
+// Only callers who manage the paste can transfer it.
+// If the recipient is not found, it returns a 404 Not Found status with an error message.
+// On success, it returns a 200 OK status with the transfer ID in the response.
+func (s *Service) TransferPaste(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.TransferPaste"
+	hash := c.Params("hash")
+
+	p := new(transferPasteRequest)
+
+	if err := c.BodyParser(p); err != nil || (p.Username == "") == (p.Org == 0) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "either a username or an organization is required",
+		})
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+	)
+
+	paste, err := s.managedPaste(c, hash, log)
+	if err != nil || paste == nil {
+		return err
+	}
+
+	principal, _ := middleware.GetPrincipal(c)
+
+	transfer := &models.PasteTransfer{
+		PasteID:    paste.ID,
+		FromUserID: principal.ID,
+	}
+
+	if p.Org != 0 {
+		if paste.OrgID != nil && *paste.OrgID == p.Org {
+			return s.alreadyOwnedResponse(c)
+		}
+
+		transfer.ToOrgID = &p.Org
+	} else {
+		user, err := s.userGetter.GetUser(c.UserContext(), p.Username)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "user not found",
+				})
+			}
+
+			return s.handleInternalServerError(c, err, log)
+		}
+
+		if paste.OrgID == nil && paste.AuthorID == user.ID {
+			return s.alreadyOwnedResponse(c)
+		}
+
+		transfer.ToUserID = &user.ID
+	}
+
+	id, err := s.transferManager.SavePasteTransfer(c.UserContext(), transfer)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrOrgNotFound), errors.Is(err, storage.ErrUserNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, storage.ErrPasteNotFound):
+			return s.pasteNotFoundResponse(c)
+		default:
+			return s.handleInternalServerError(c, err, log)
+		}
+	}
+
+	log.Info("Paste transfer offered", slog.Int64("transfer_id", id))
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"id": id,
+	})
+}
+
+// CancelTransfer withdraws the pending transfer of the paste given by the "hash" path parameter.
+// Only callers who manage the paste can cancel its transfer.
+// If the paste has no pending transfer, it returns a 404 Not Found status with an error message.
+func (s *Service) CancelTransfer(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.CancelTransfer"
+	hash := c.Params("hash")
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+	)
+
+	paste, err := s.managedPaste(c, hash, log)
+	if err != nil || paste == nil {
+		return err
+	}
+
+	transfer, err := s.transferManager.GetPasteTransfer(c.UserContext(), paste.ID)
+	if err != nil {
+		return s.handleTransferError(c, err, log)
+	}
+
+	if err := s.transferManager.DeletePasteTransfer(c.UserContext(), transfer.ID); err != nil {
+		return s.handleTransferError(c, err, log)
+	}
+
+	log.Info("Paste transfer cancelled", slog.Int64("transfer_id", transfer.ID))
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ListIncomingTransfers returns the pending transfers the authenticated user can accept: those to the user
+// and those to organizations the user is an admin or owner of.
+func (s *Service) ListIncomingTransfers(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.ListIncomingTransfers"
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return s.handleUnauthorizedResponse(c)
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.Int64("user_id", principal.ID),
+	)
+
+	transfers, err := s.transferManager.GetIncomingTransfers(c.UserContext(), principal.ID)
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	response := make([]transferResponse, 0, len(transfers))
+	for _, transfer := range transfers {
+		response = append(response, transferResponse{
+			ID:           transfer.ID,
+			PasteID:      transfer.PasteID,
+			PasteTitle:   transfer.PasteTitle,
+			FromUserID:   transfer.FromUserID,
+			FromUsername: transfer.FromUsername,
+			ToUserID:     transfer.ToUserID,
+			ToOrgID:      transfer.ToOrgID,
+			CreatedAt:    transfer.CreatedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"transfers": response,
+	})
+}
+
+// AcceptTransfer completes the pending transfer of the paste given by the "hash" path parameter.
+// A transfer to a user can only be accepted by that user, and one to an organization by its admins and owners.
+// If the sender can't manage the paste anymore, the transfer is dropped and it returns a 409 Conflict status.
+// If there is no transfer the caller can accept, it returns a 404 Not Found status with an error message.
+func (s *Service) AcceptTransfer(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.AcceptTransfer"
+
+	transfer, log, err := s.incomingTransfer(c, prefix)
+	if err != nil || transfer == nil {
+		return err
+	}
+
+	paste, err := s.pasteGetter.GetPaste(c.UserContext(), transfer.PasteID)
+	if err != nil {
+		return s.handleTransferError(c, err, log)
+	}
+
+	// @NOTE: Ownership may have changed since the offer, e.g. the sender left the owning organization
+	level, err := s.resolveAccess(c.UserContext(), paste, &middleware.Principal{ID: transfer.FromUserID})
+	if err != nil {
+		return s.handleInternalServerError(c, err, log)
+	}
+
+	if level < accessManage {
+		if err := s.transferManager.DeletePasteTransfer(c.UserContext(), transfer.ID); err != nil {
+			return s.handleTransferError(c, err, log)
+		}
+
+		log.Warn("Dropped stale paste transfer")
+
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "the sender can't transfer this paste anymore",
+		})
+	}
+
+	if err := s.transferManager.AcceptPasteTransfer(c.UserContext(), transfer.ID); err != nil {
+		return s.handleTransferError(c, err, log)
+	}
+
+	s.invalidateCache(c.UserContext(), paste.ID, log)
+
+	var recipient string
+	if transfer.ToUserID != nil {
+		recipient = fmt.Sprintf("user %d", *transfer.ToUserID)
+	} else {
+		recipient = fmt.Sprintf("org %d", *transfer.ToOrgID)
+	}
+	s.recordPasteEvent(c, models.AuditPasteTransfer, models.AuditSuccess, paste.ID,
+		fmt.Sprintf("from user %d to %s", transfer.FromUserID, recipient))
+
+	log.Info("Paste transfer accepted")
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// DeclineTransfer rejects the pending transfer of the paste given by the "hash" path parameter.
+// The same callers who could accept it can decline it.
+func (s *Service) DeclineTransfer(c *fiber.Ctx) error {
+	const prefix = "internal.router.services.paste.DeclineTransfer"
+
+	transfer, log, err := s.incomingTransfer(c, prefix)
+	if err != nil || transfer == nil {
+		return err
+	}
+
+	if err := s.transferManager.DeletePasteTransfer(c.UserContext(), transfer.ID); err != nil {
+		return s.handleTransferError(c, err, log)
+	}
+
+	log.Info("Paste transfer declined")
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// incomingTransfer loads the pending transfer of the paste given by the "hash" path parameter and checks that
+// the caller can accept it. If not, it writes the error response and returns a nil transfer together with
+// the result of writing it.
+func (s *Service) incomingTransfer(c *fiber.Ctx, prefix string) (*models.PasteTransfer, *slog.Logger, error) {
+	hash := c.Params("hash")
+
+	principal, ok := middleware.GetPrincipal(c)
+	if !ok {
+		return nil, nil, s.handleUnauthorizedResponse(c)
+	}
+
+	log := sl.FromContext(c.UserContext(), s.log).With(
+		slog.String("op", prefix),
+		slog.String("hash", hash),
+		slog.Int64("user_id", principal.ID),
+	)
+
+	transfer, err := s.transferManager.GetPasteTransfer(c.UserContext(), hash)
+	if err != nil {
+		return nil, log, s.handleTransferError(c, err, log)
+	}
+
+	allowed, err := s.canReceiveTransfer(c.UserContext(), transfer, principal)
+	if err != nil {
+		return nil, log, s.handleInternalServerError(c, err, log)
+	}
+
+	// @NOTE: Other callers get the same response as for a paste without a transfer
+	if !allowed {
+		return nil, log, s.handleTransferError(c, storage.ErrTransferNotFound, log)
+	}
+
+	return &transfer, log.With(slog.Int64("transfer_id", transfer.ID)), nil
+}
+
+// canReceiveTransfer reports whether the principal can accept or decline the transfer.
+func (s *Service) canReceiveTransfer(ctx context.Context, transfer models.PasteTransfer, principal *middleware.Principal) (bool, error) {
+	if transfer.ToUserID != nil {
+		return *transfer.ToUserID == principal.ID, nil
+	}
+
+	role, err := s.orgRole(ctx, *transfer.ToOrgID, principal.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return models.HasOrgRole(role, models.OrgRoleAdmin), nil
+}
+
+func (s *Service) handleTransferError(c *fiber.Ctx, err error, log *slog.Logger) error {
+	switch {
+	case errors.Is(err, storage.ErrTransferNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "transfer not found",
+		})
+	case errors.Is(err, storage.ErrPasteNotFound):
+		return s.pasteNotFoundResponse(c)
+	default:
+		return s.handleInternalServerError(c, err, log)
+	}
+}
+
+func (s *Service) alreadyOwnedResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "the recipient already owns this paste",
+	})
+}
+
+// hashToken returns the hex-encoded SHA-256 of a claim token. Only the hash is stored in the database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,92 @@
+package pastes
+
+import (
+	"TextVault/internal/storage/models"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// pasteEvents returns the recorded events of the action.
+func (f *fakeAudit) pasteEvents(action string) []models.AuditEvent {
+	var events []models.AuditEvent
+	for _, event := range f.events {
+		if event.Action == action {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+func TestClaimPaste(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.addUser(1, "alice")
+
+	env.store.pastes["paste1"] = models.Paste{ID: "paste1", Visibility: models.VisibilityPublic}
+	env.store.claimTokens["paste1"] = hashToken("claim-token")
+	env.cache.values["paste1"] = `{"visibility":"public"}`
+
+	if status, _ := env.do(t, http.MethodPost, "/pastes/paste1/claim", &alice, map[string]string{"token": "wrong-token"}); status != http.StatusForbidden {
+		t.Fatalf("claim with a wrong token status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	if status, body := env.do(t, http.MethodPost, "/pastes/paste1/claim", &alice, map[string]string{"token": "claim-token"}); status != http.StatusOK {
+		t.Fatalf("claim status = %d %v, want %d", status, body, http.StatusOK)
+	}
+
+	if got := env.store.pastes["paste1"].AuthorID; got != alice.ID {
+		t.Errorf("author = %d, want %d", got, alice.ID)
+	}
+
+	if !slices.Contains(env.cache.deleted, "paste1") {
+		t.Errorf("deleted cache keys = %v, want the claimed paste", env.cache.deleted)
+	}
+
+	events := env.audit.pasteEvents(models.AuditPasteClaim)
+	if len(events) != 2 || events[0].Outcome != models.AuditFailure || events[1].Outcome != models.AuditSuccess {
+		t.Fatalf("claim events = %+v, want a failure and a success", events)
+	}
+	if events[1].TargetType != models.AuditTargetPaste || events[1].TargetID != "paste1" {
+		t.Errorf("claim event = %+v, want it to target the paste", events[1])
+	}
+}
+
+func TestAcceptTransfer(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.addUser(1, "alice")
+	bob := env.addUser(2, "bob")
+	mallory := env.addUser(3, "mallory")
+
+	env.store.pastes["paste1"] = models.Paste{ID: "paste1", AuthorID: alice.ID, Visibility: models.VisibilityPublic}
+	env.store.transfers[7] = models.PasteTransfer{ID: 7, PasteID: "paste1", FromUserID: alice.ID, ToUserID: &bob.ID}
+	env.cache.values["paste1"] = `{"visibility":"public"}`
+
+	if status, _ := env.do(t, http.MethodPost, "/pastes/paste1/transfer/accept", &mallory, nil); status != http.StatusNotFound {
+		t.Fatalf("accept by another user status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	if len(env.cache.deleted) != 0 || len(env.audit.pasteEvents(models.AuditPasteTransfer)) != 0 {
+		t.Fatalf("a rejected accept dropped %v from the cache or was recorded", env.cache.deleted)
+	}
+
+	if status, body := env.do(t, http.MethodPost, "/pastes/paste1/transfer/accept", &bob, nil); status != http.StatusOK {
+		t.Fatalf("accept status = %d %v, want %d", status, body, http.StatusOK)
+	}
+
+	if got := env.store.pastes["paste1"].AuthorID; got != bob.ID {
+		t.Errorf("author = %d, want %d", got, bob.ID)
+	}
+
+	if !slices.Contains(env.cache.deleted, "paste1") {
+		t.Errorf("deleted cache keys = %v, want the transferred paste", env.cache.deleted)
+	}
+
+	events := env.audit.pasteEvents(models.AuditPasteTransfer)
+	if len(events) != 1 {
+		t.Fatalf("transfer events = %+v, want one", events)
+	}
+	if event := events[0]; event.Outcome != models.AuditSuccess || event.TargetID != "paste1" || event.Details != "from user 1 to user 2" {
+		t.Errorf("transfer event = %+v, want a success moving paste1 from user 1 to user 2", event)
+	}
+}
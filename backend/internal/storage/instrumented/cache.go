@@ -0,0 +1,102 @@
+package instrumented
+
+import (
+	"TextVault/internal/storage"
+	"context"
+	"errors"
+	"time"
+)
+
+// CacheStorage is the cache, i.e. Redis.
+type CacheStorage interface {
+	Set(ctx context.Context, key string, value string) error
+	Get(ctx context.Context, key string) (string, error)
+	Delete(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) error
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	SetWithTTL(ctx context.Context, key string, value string, ttl time.Duration) error
+	GetDel(ctx context.Context, key string) (string, error)
+	TTL(ctx context.Context, key string) (time.Duration, error)
+}
+
+// Cache measures and traces the operations of a CacheStorage. Cache misses are not counted as errors.
+type Cache struct {
+	next CacheStorage
+	instrument
+}
+
+func NewCache(next CacheStorage, observer Observer) *Cache {
+	return &Cache{next: next, instrument: newInstrument("redis", observer)}
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value string) error {
+	ctx, end := c.start(ctx, "Set")
+	err := c.next.Set(ctx, key, value)
+	end(err)
+
+	return err
+}
+
+func (c *Cache) Get(ctx context.Context, key string) (string, error) {
+	ctx, end := c.start(ctx, "Get")
+	value, err := c.next.Get(ctx, key)
+	end(cacheMissIsNil(err))
+
+	return value, err
+}
+
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	ctx, end := c.start(ctx, "Delete")
+	err := c.next.Delete(ctx, key)
+	end(err)
+
+	return err
+}
+
+func (c *Cache) Exists(ctx context.Context, key string) error {
+	ctx, end := c.start(ctx, "Exists")
+	err := c.next.Exists(ctx, key)
+	end(err)
+
+	return err
+}
+
+func (c *Cache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	ctx, end := c.start(ctx, "Incr")
+	count, err := c.next.Incr(ctx, key, ttl)
+	end(err)
+
+	return count, err
+}
+
+func (c *Cache) SetWithTTL(ctx context.Context, key string, value string, ttl time.Duration) error {
+	ctx, end := c.start(ctx, "SetWithTTL")
+	err := c.next.SetWithTTL(ctx, key, value, ttl)
+	end(err)
+
+	return err
+}
+
+func (c *Cache) GetDel(ctx context.Context, key string) (string, error) {
+	ctx, end := c.start(ctx, "GetDel")
+	value, err := c.next.GetDel(ctx, key)
+	end(cacheMissIsNil(err))
+
+	return value, err
+}
+
+func (c *Cache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ctx, end := c.start(ctx, "TTL")
+	ttl, err := c.next.TTL(ctx, key)
+	end(err)
+
+	return ttl, err
+}
+
+func cacheMissIsNil(err error) error {
+	if errors.Is(err, storage.ErrCacheMiss) {
+		return nil
+	}
+
+	return err
+}
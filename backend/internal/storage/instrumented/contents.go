@@ -0,0 +1,46 @@
+package instrumented
+
+import (
+	"context"
+)
+
+// ContentStorage is the paste content storage, i.e. S3.
+type ContentStorage interface {
+	UploadPaste(ctx context.Context, objectKey string, content []byte) error
+	GetPasteContent(ctx context.Context, objectKey string) ([]byte, error)
+	DeletePaste(ctx context.Context, objectKey string) error
+}
+
+// ContentStore measures and traces the operations of a ContentStorage.
+type ContentStore struct {
+	next ContentStorage
+	instrument
+}
+
+func NewContentStore(next ContentStorage, observer Observer) *ContentStore {
+	return &ContentStore{next: next, instrument: newInstrument("s3", observer)}
+}
+
+func (s *ContentStore) UploadPaste(ctx context.Context, objectKey string, content []byte) error {
+	ctx, end := s.start(ctx, "UploadPaste")
+	err := s.next.UploadPaste(ctx, objectKey, content)
+	end(err)
+
+	return err
+}
+
+func (s *ContentStore) GetPasteContent(ctx context.Context, objectKey string) ([]byte, error) {
+	ctx, end := s.start(ctx, "GetPasteContent")
+	content, err := s.next.GetPasteContent(ctx, objectKey)
+	end(err)
+
+	return content, err
+}
+
+func (s *ContentStore) DeletePaste(ctx context.Context, objectKey string) error {
+	ctx, end := s.start(ctx, "DeletePaste")
+	err := s.next.DeletePaste(ctx, objectKey)
+	end(err)
+
+	return err
+}
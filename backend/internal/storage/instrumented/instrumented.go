@@ -0,0 +1,53 @@
+// Package instrumented wraps the storages so every operation is measured and traced.
+package instrumented
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Observer records the latency and the outcome of storage operations.
+type Observer interface {
+	ObserveStorage(storage, operation string, start time.Time, err error)
+}
+
+// instrument measures and traces the operations of one storage.
+type instrument struct {
+	storage  string
+	observer Observer
+	tracer   trace.Tracer
+}
+
+func newInstrument(storage string, observer Observer) instrument {
+	return instrument{
+		storage:  storage,
+		observer: observer,
+		tracer:   otel.Tracer("TextVault/internal/storage/instrumented"),
+	}
+}
+
+// start begins an operation with a span named after the storage and the operation. The returned
+// function ends it; the error it is given counts as the operation's failure.
+func (i instrument) start(ctx context.Context, operation string) (context.Context, func(err error)) {
+	start := time.Now()
+
+	ctx, span := i.tracer.Start(ctx, i.storage+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("storage", i.storage)),
+	)
+
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		i.observer.ObserveStorage(i.storage, operation, start, err)
+	}
+}
@@ -0,0 +1,67 @@
+package instrumented
+
+import (
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+)
+
+// PasteStorage is the paste metadata storage, i.e. Postgres.
+type PasteStorage interface {
+	SavePaste(ctx context.Context, paste *models.Paste) (string, error)
+	UpdatePaste(ctx context.Context, paste *models.Paste) error
+	DeletePaste(ctx context.Context, id string) error
+	GetPaste(ctx context.Context, hash string) (models.Paste, error)
+}
+
+// PasteStore measures and traces the operations of a PasteStorage.
+type PasteStore struct {
+	next PasteStorage
+	instrument
+}
+
+func NewPasteStore(next PasteStorage, observer Observer) *PasteStore {
+	return &PasteStore{next: next, instrument: newInstrument("postgres", observer)}
+}
+
+func (p *PasteStore) SavePaste(ctx context.Context, paste *models.Paste) (string, error) {
+	ctx, end := p.start(ctx, "SavePaste")
+	id, err := p.next.SavePaste(ctx, paste)
+	end(err)
+
+	return id, err
+}
+
+func (p *PasteStore) UpdatePaste(ctx context.Context, paste *models.Paste) error {
+	ctx, end := p.start(ctx, "UpdatePaste")
+	err := p.next.UpdatePaste(ctx, paste)
+	end(err)
+
+	return err
+}
+
+func (p *PasteStore) DeletePaste(ctx context.Context, id string) error {
+	ctx, end := p.start(ctx, "DeletePaste")
+	err := p.next.DeletePaste(ctx, id)
+	end(err)
+
+	return err
+}
+
+func (p *PasteStore) GetPaste(ctx context.Context, hash string) (models.Paste, error) {
+	ctx, end := p.start(ctx, "GetPaste")
+	paste, err := p.next.GetPaste(ctx, hash)
+	end(notFoundIsNil(err))
+
+	return paste, err
+}
+
+// notFoundIsNil doesn't count lookups of missing pastes as failures, as they are answered normally.
+func notFoundIsNil(err error) error {
+	if errors.Is(err, storage.ErrPasteNotFound) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type AdminAction struct {
+	ID         int64     `db:"id"`
+	AdminID    int64     `db:"adminid"`
+	Action     string    `db:"action"`
+	TargetType string    `db:"targettype"`
+	TargetID   string    `db:"targetid"`
+	Details    string    `db:"details"`
+	CreatedAt  time.Time `db:"createdat"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// Outcomes of audit events.
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// Actions of audit events. Admin actions are recorded as AuditAdminPrefix followed by the admin action.
+const (
+	AuditLogin            = "login"
+	AuditLoginLockout     = "login_lockout"
+	AuditRegister         = "register"
+	AuditTokenIssue       = "token_issue"
+	AuditPasswordChange   = "password_change"
+	AuditPasswordReset    = "password_reset"
+	AuditEmailChange      = "email_change"
+	AuditTwoFactorEnable  = "two_factor_enable"
+	AuditTwoFactorDisable = "two_factor_disable"
+	AuditAccountDelete    = "account_delete"
+	AuditIdentityLink     = "identity_link"
+	AuditReauthenticate   = "reauthenticate"
+	AuditPasteDelete      = "paste_delete"
+	AuditVisibilityChange = "paste_visibility_change"
+	AuditGrantAccess      = "paste_grant"
+	AuditRevokeAccess     = "paste_grant_revoke"
+	AuditShareLinkCreate  = "share_link_create"
+	AuditShareLinkRevoke  = "share_link_revoke"
+	AuditPasteClaim       = "paste_claim"
+	AuditPasteTransfer    = "paste_transfer"
+	AuditOrgMemberAdd     = "org_member_add"
+	AuditOrgMemberUpdate  = "org_member_update"
+	AuditOrgMemberRemove  = "org_member_remove"
+	AuditOrgDelete        = "org_delete"
+
+	AuditAdminPrefix = "admin."
+)
+
+// Target types of audit events.
+const (
+	AuditTargetUser     = "user"
+	AuditTargetUsername = "username"
+	AuditTargetPaste    = "paste"
+	AuditTargetOrg      = "org"
+	AuditTargetIP       = "ip"
+)
+
+// AuditEvent is an entry of the append-only audit log of security-relevant events. ActorID is the user
+// who acted, unset for anonymous clients such as failed logins. A failed login of an unknown user names
+// the username as its target.
+type AuditEvent struct {
+	ID         int64     `db:"id"`
+	Action     string    `db:"action"`
+	Outcome    string    `db:"outcome"`
+	ActorID    *int64    `db:"actorid"`
+	TargetType string    `db:"targettype"`
+	TargetID   string    `db:"targetid"`
+	IP         string    `db:"ip"`
+	UserAgent  string    `db:"useragent"`
+	Details    string    `db:"details"`
+	CreatedAt  time.Time `db:"createdat"`
+}
+
+// AuditFilter selects audit events. Unset fields match every event. UserID matches the events
+// acted by the user or targeting them.
+type AuditFilter struct {
+	UserID     *int64
+	ActorID    *int64
+	Action     string
+	Outcome    string
+	TargetType string
+	TargetID   string
+	IP         string
+	Since      *time.Time
+	Until      *time.Time
+}
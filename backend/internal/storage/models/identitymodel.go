@@ -0,0 +1,9 @@
+package models
+
+// Identity links a user to an account at an external OpenID Connect provider.
+type Identity struct {
+	UserID   int64  `db:"userid"`
+	Provider string `db:"provider"`
+	Subject  string `db:"subject"`
+	Email    string `db:"email"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Organization member roles, from the most to the least privileged.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+	OrgRoleViewer = "viewer"
+)
+
+var orgRoleRanks = map[string]int{
+	OrgRoleOwner:  4,
+	OrgRoleAdmin:  3,
+	OrgRoleMember: 2,
+	OrgRoleViewer: 1,
+}
+
+// IsOrgRole reports whether role is a known organization role.
+func IsOrgRole(role string) bool {
+	_, ok := orgRoleRanks[role]
+	return ok
+}
+
+// HasOrgRole reports whether role grants at least the permissions of required.
+func HasOrgRole(role, required string) bool {
+	return IsOrgRole(role) && orgRoleRanks[role] >= orgRoleRanks[required]
+}
+
+// Organization is a team that can own pastes. Role is the role of the user the
+// organization was loaded for, and is empty otherwise.
+type Organization struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"createdat"`
+	Role      string    `db:"role"`
+}
+
+type OrganizationMember struct {
+	OrgID     int64     `db:"orgid"`
+	UserID    int64     `db:"userid"`
+	Username  string    `db:"username"`
+	Role      string    `db:"role"`
+	CreatedAt time.Time `db:"createdat"`
+}
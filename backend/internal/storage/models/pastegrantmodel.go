@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Access levels of paste grants. Write access also allows reading.
+const (
+	AccessRead  = "read"
+	AccessWrite = "write"
+)
+
+// PasteGrant gives a user or the members of an organization access to a paste, regardless of its visibility.
+// Exactly one of UserID and OrgID is set. GranteeName is the username or organization name when loaded for listing.
+type PasteGrant struct {
+	ID          int64     `db:"id"`
+	PasteID     string    `db:"pasteid"`
+	UserID      *int64    `db:"userid"`
+	OrgID       *int64    `db:"orgid"`
+	Access      string    `db:"access"`
+	GrantedBy   int64     `db:"grantedby"`
+	CreatedAt   time.Time `db:"createdat"`
+	GranteeName string    `db:"granteename"`
+}
@@ -1,8 +1,23 @@
 package models
 
+import "time"
+
+// Paste visibility levels. Private pastes are only readable by their author, or by the members
+// of the organization that owns them.
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
 type Paste struct {
-	ID       string `db:"id"`
-	Title    string `db:"title"`
-	Language string `db:"language"`
-	AuthorID int64  `db:"authorid"`
+	ID         string `db:"id"`
+	Title      string `db:"title"`
+	Language   string `db:"language"`
+	AuthorID   int64  `db:"authorid"`
+	Visibility string `db:"visibility"`
+	OrgID      *int64 `db:"orgid"`
+
+	// HiddenAt is set while the paste is hidden by moderation.
+	HiddenAt *time.Time `db:"hiddenat"`
 }
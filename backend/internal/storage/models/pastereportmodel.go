@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Statuses of paste reports. A report is open until a moderator resolves it by hiding or deleting
+// the paste, or by dismissing the report.
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusHidden    = "hidden"
+	ReportStatusDeleted   = "deleted"
+	ReportStatusDismissed = "dismissed"
+)
+
+// PasteReport is a report of an abusive paste. Reporter identifies the reporting user or anonymous client,
+// and ReporterID is only set for users. PasteTitle, PasteHiddenAt and OpenReports are set when loaded for
+// the moderation queue; OpenReports counts the open reports of the paste. Reports outlive their paste,
+// and PasteDeleted tells whether it has been deleted since.
+type PasteReport struct {
+	ID            int64      `db:"id"`
+	PasteID       string     `db:"pasteid"`
+	PasteTitle    string     `db:"pastetitle"`
+	PasteHiddenAt *time.Time `db:"pastehiddenat"`
+	PasteDeleted  bool       `db:"pastedeleted"`
+	ReporterID    *int64     `db:"reporterid"`
+	Reporter      string     `db:"reporter"`
+	Category      string     `db:"category"`
+	Details       string     `db:"details"`
+	Status        string     `db:"status"`
+	ResolvedBy    *int64     `db:"resolvedby"`
+	ResolvedAt    *time.Time `db:"resolvedat"`
+	CreatedAt     time.Time  `db:"createdat"`
+	OpenReports   int64      `db:"openreports"`
+}
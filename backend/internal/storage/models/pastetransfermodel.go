@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PasteTransfer is a pending transfer of a paste to a user or an organization. It takes effect once
+// the recipient accepts it. Exactly one of ToUserID and ToOrgID is set.
+type PasteTransfer struct {
+	ID           int64     `db:"id"`
+	PasteID      string    `db:"pasteid"`
+	PasteTitle   string    `db:"pastetitle"`
+	FromUserID   int64     `db:"fromuserid"`
+	FromUsername string    `db:"fromusername"`
+	ToUserID     *int64    `db:"touserid"`
+	ToOrgID      *int64    `db:"toorgid"`
+	CreatedAt    time.Time `db:"createdat"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RateLimitResult is the outcome of taking one request from a rate limit. Remaining is the number of
+// further requests allowed right now, RetryAfter how long a rejected request must wait, and ResetAfter
+// how long until the limit is fully replenished.
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
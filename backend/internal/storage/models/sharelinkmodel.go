@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ShareLink gives anyone holding its signed URL read access to a paste until it expires, runs out
+// of views or is revoked. A nil MaxViews allows unlimited views.
+type ShareLink struct {
+	ID        string     `db:"id"`
+	PasteID   string     `db:"pasteid"`
+	CreatedBy int64      `db:"createdby"`
+	ExpiresAt time.Time  `db:"expiresat"`
+	MaxViews  *int       `db:"maxviews"`
+	Views     int        `db:"views"`
+	RevokedAt *time.Time `db:"revokedat"`
+	CreatedAt time.Time  `db:"createdat"`
+}
+
+// ShareLinkUse records a view of a paste through a share link.
+type ShareLinkUse struct {
+	ID        int64     `db:"id"`
+	LinkID    string    `db:"linkid"`
+	IP        string    `db:"ip"`
+	UserAgent string    `db:"useragent"`
+	UsedAt    time.Time `db:"usedat"`
+}
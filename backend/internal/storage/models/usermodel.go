@@ -1,10 +1,24 @@
 package models
 
+import "time"
+
 type User struct {
-	ID           int64  `db:"id"`
-	Username     string `db:"username"`
-	Email        string `db:"email"`
-	PasswordHash string `db:"passwordhash"`
-	IsAdmin      bool   `db:"isadmin"`
-	IsBanned     bool   `db:"isbanned"`
+	ID           int64      `db:"id"`
+	Username     string     `db:"username"`
+	Email        string     `db:"email"`
+	PasswordHash string     `db:"passwordhash"`
+	IsAdmin      bool       `db:"isadmin"`
+	IsBanned     bool       `db:"isbanned"`
+	IsVerified   bool       `db:"isverified"`
+	TokenVersion int64      `db:"tokenversion"`
+	TOTPSecret   string     `db:"totpsecret"`
+	TOTPEnabled  bool       `db:"totpenabled"`
+	BanReason    string     `db:"banreason"`
+	BannedUntil  *time.Time `db:"banneduntil"`
+}
+
+// HasPassword reports whether the user can log in with a password. Users provisioned by an OpenID Connect
+// provider have none until they set one with a password reset.
+func (u User) HasPassword() bool {
+	return u.PasswordHash != ""
 }
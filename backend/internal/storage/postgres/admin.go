@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"TextVault/internal/storage/models"
+	"context"
+)
+
+// SaveAdminAction records an action performed by an admin.
+func (s *Storage) SaveAdminAction(ctx context.Context, action *models.AdminAction) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "INSERT INTO AdminActions (adminid, action, targettype, targetid, details) VALUES ($1, $2, $3, $4, $5)"
+
+	_, err := s.conn.Exec(ctx, stmt, action.AdminID, action.Action, action.TargetType, action.TargetID, action.Details)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"TextVault/internal/storage/models"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+// SaveAuditEvent appends an event to the audit log.
+func (s *Storage) SaveAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := `INSERT INTO audit_events (action, outcome, actorid, targettype, targetid, ip, useragent, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := s.conn.Exec(ctx, stmt, event.Action, event.Outcome, event.ActorID, event.TargetType, event.TargetID,
+		event.IP, event.UserAgent, event.Details)
+
+	return err
+}
+
+// GetAuditEvents returns the audit events matching the filter, newest first.
+func (s *Storage) GetAuditEvents(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]models.AuditEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var conditions []string
+	var args []any
+
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID, models.AuditTargetUser, strconv.FormatInt(*filter.UserID, 10))
+		n := len(args)
+		conditions = append(conditions, fmt.Sprintf("(actorid = $%d OR (targettype = $%d AND targetid = $%d))", n-2, n-1, n))
+	}
+	if filter.ActorID != nil {
+		where("actorid = $%d", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.Outcome != "" {
+		where("outcome = $%d", filter.Outcome)
+	}
+	if filter.TargetType != "" {
+		where("targettype = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		where("targetid = $%d", filter.TargetID)
+	}
+	if filter.IP != "" {
+		where("ip = $%d", filter.IP)
+	}
+	if filter.Since != nil {
+		where("createdat >= $%d", *filter.Since)
+	}
+	if filter.Until != nil {
+		where("createdat < $%d", *filter.Until)
+	}
+
+	stmt := "SELECT * FROM audit_events"
+	if len(conditions) > 0 {
+		stmt += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, limit, offset)
+	stmt += fmt.Sprintf(" ORDER BY createdat DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	var events []models.AuditEvent
+	err := pgxscan.Select(ctx, s.conn, &events, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"TextVault/internal/storage/models"
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetAuditEvents(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	saveUser := func(prefix string) int64 {
+		t.Helper()
+
+		name := uniqueName(prefix)
+		id, err := s.SaveUser(ctx, name, name+"@example.com", "hash")
+		if err != nil {
+			t.Fatalf("SaveUser() error = %v", err)
+		}
+
+		return id
+	}
+
+	alice := saveUser("alice")
+	bob := saveUser("bob")
+	carol := saveUser("carol")
+
+	// @NOTE: The table is append-only and shared by test runs, so the filters are scoped to this run's IP
+	ip := uniqueName("ip")
+	start := time.Now().Add(-time.Minute)
+
+	events := []models.AuditEvent{
+		{Details: "e1", Action: models.AuditLogin, Outcome: models.AuditSuccess, ActorID: &alice, TargetType: models.AuditTargetUser, TargetID: strconv.FormatInt(alice, 10)},
+		{Details: "e2", Action: models.AuditLogin, Outcome: models.AuditFailure, TargetType: models.AuditTargetUser, TargetID: strconv.FormatInt(alice, 10)},
+		{Details: "e3", Action: models.AuditPasteClaim, Outcome: models.AuditSuccess, ActorID: &bob, TargetType: models.AuditTargetPaste, TargetID: strconv.FormatInt(alice, 10)},
+		{Details: "e4", Action: models.AuditLogin, Outcome: models.AuditSuccess, ActorID: &bob, TargetType: models.AuditTargetUser, TargetID: strconv.FormatInt(bob, 10)},
+	}
+
+	for _, event := range events {
+		event.IP = ip
+		if err := s.SaveAuditEvent(ctx, &event); err != nil {
+			t.Fatalf("SaveAuditEvent() error = %v", err)
+		}
+	}
+
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		filter models.AuditFilter
+		want   []string
+	}{
+		{name: "all", filter: models.AuditFilter{}, want: []string{"e4", "e3", "e2", "e1"}},
+		// @NOTE: e3 targets a paste whose ID happens to equal alice's, which must not count as targeting her
+		{name: "user", filter: models.AuditFilter{UserID: &alice}, want: []string{"e2", "e1"}},
+		{name: "user as actor", filter: models.AuditFilter{UserID: &bob}, want: []string{"e4", "e3"}},
+		{name: "user without events", filter: models.AuditFilter{UserID: &carol}, want: nil},
+		{name: "actor", filter: models.AuditFilter{ActorID: &bob}, want: []string{"e4", "e3"}},
+		{name: "action and outcome", filter: models.AuditFilter{Action: models.AuditLogin, Outcome: models.AuditSuccess}, want: []string{"e4", "e1"}},
+		{name: "target", filter: models.AuditFilter{TargetType: models.AuditTargetUser, TargetID: strconv.FormatInt(alice, 10)}, want: []string{"e2", "e1"}},
+		{name: "user and actor", filter: models.AuditFilter{UserID: &alice, ActorID: &alice}, want: []string{"e1"}},
+		{name: "since", filter: models.AuditFilter{Since: &start}, want: []string{"e4", "e3", "e2", "e1"}},
+		{name: "since later", filter: models.AuditFilter{Since: &future}, want: nil},
+		{name: "until", filter: models.AuditFilter{Until: &start}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			filter.IP = ip
+
+			got, err := s.GetAuditEvents(ctx, filter, 10, 0)
+			if err != nil {
+				t.Fatalf("GetAuditEvents() error = %v", err)
+			}
+
+			var details []string
+			for _, event := range got {
+				details = append(details, event.Details)
+			}
+
+			if !slices.Equal(details, tt.want) {
+				t.Errorf("GetAuditEvents() = %v, want %v", details, tt.want)
+			}
+		})
+	}
+
+	t.Run("pagination", func(t *testing.T) {
+		got, err := s.GetAuditEvents(ctx, models.AuditFilter{IP: ip}, 2, 1)
+		if err != nil {
+			t.Fatalf("GetAuditEvents() error = %v", err)
+		}
+
+		if len(got) != 2 || got[0].Details != "e3" || got[1].Details != "e2" {
+			t.Errorf("GetAuditEvents() page = %+v, want e3 and e2", got)
+		}
+	})
+}
+
+func TestAuditEventsAppendOnly(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	ip := uniqueName("ip")
+	if err := s.SaveAuditEvent(ctx, &models.AuditEvent{Action: models.AuditLogin, Outcome: models.AuditFailure, IP: ip}); err != nil {
+		t.Fatalf("SaveAuditEvent() error = %v", err)
+	}
+
+	if _, err := s.conn.Exec(ctx, "UPDATE audit_events SET outcome = $1 WHERE ip = $2", models.AuditSuccess, ip); err == nil {
+		t.Error("update of an audit event succeeded, want it rejected")
+	}
+	if _, err := s.conn.Exec(ctx, "DELETE FROM audit_events WHERE ip = $1", ip); err == nil {
+		t.Error("deletion of an audit event succeeded, want it rejected")
+	}
+}
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	uniqueViolation           = "23505"
+	invalidTextRepresentation = "22P02"
+)
+
+// userConstraintErrors maps unique constraints of the Users table to the storage errors they mean.
+var userConstraintErrors = map[string]error{
+	"users_username_lower_key": storage.ErrUsernameTaken,
+	"users_email_lower_key":    storage.ErrEmailTaken,
+	"users_email_key":          storage.ErrEmailTaken,
+}
+
+// mapUserError translates unique violations of the Users table into ErrUsernameTaken or ErrEmailTaken.
+// Other errors are returned unchanged.
+func mapUserError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return err
+	}
+
+	if mapped, ok := userConstraintErrors[pgErr.ConstraintName]; ok {
+		return mapped
+	}
+
+	return err
+}
+
+// mapShareLinkError translates the invalid text representation error of an ID that isn't a UUID
+// into ErrShareLinkNotFound. Other errors are returned unchanged.
+func mapShareLinkError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == invalidTextRepresentation {
+		return storage.ErrShareLinkNotFound
+	}
+
+	return err
+}
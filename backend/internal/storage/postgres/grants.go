@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SavePasteGrant grants a user or an organization access to a paste and returns the ID of the grant.
+// An existing grant for the same grantee is updated to the new access level.
+// If the organization doesn't exist, the function returns ErrOrgNotFound.
+func (s *Storage) SavePasteGrant(ctx context.Context, grant *models.PasteGrant) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	conflict := "(pasteid, userid) WHERE userid IS NOT NULL"
+	if grant.OrgID != nil {
+		conflict = "(pasteid, orgid) WHERE orgid IS NOT NULL"
+	}
+
+	stmt := `INSERT INTO paste_grants (pasteid, userid, orgid, access, grantedby) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT ` + conflict + ` DO UPDATE SET access = EXCLUDED.access, grantedby = EXCLUDED.grantedby
+		RETURNING id`
+
+	var id int64
+	err := s.conn.QueryRow(ctx, stmt, grant.PasteID, grant.UserID, grant.OrgID, grant.Access, grant.GrantedBy).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			switch {
+			case grant.OrgID != nil && pgErr.ConstraintName == "paste_grants_orgid_fkey":
+				return 0, storage.ErrOrgNotFound
+			case pgErr.ConstraintName == "paste_grants_userid_fkey":
+				return 0, storage.ErrUserNotFound
+			default:
+				return 0, storage.ErrPasteNotFound
+			}
+		}
+
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetPasteGrants returns the grants of a paste with the names of their grantees.
+func (s *Storage) GetPasteGrants(ctx context.Context, pasteID string) ([]models.PasteGrant, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := `SELECT g.id, g.pasteid, g.userid, g.orgid, g.access, g.grantedby, g.createdat,
+		COALESCE(u.username, o.name, '') AS granteename
+		FROM paste_grants g
+		LEFT JOIN Users u ON u.id = g.userid
+		LEFT JOIN Organizations o ON o.id = g.orgid
+		WHERE g.pasteid = $1 ORDER BY g.createdat`
+
+	var grants []models.PasteGrant
+	if err := pgxscan.Select(ctx, s.conn, &grants, stmt, pasteID); err != nil {
+		return nil, err
+	}
+
+	return grants, nil
+}
+
+// DeletePasteGrant revokes a grant of a paste. If the paste has no such grant, the function returns ErrGrantNotFound.
+func (s *Storage) DeletePasteGrant(ctx context.Context, pasteID string, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tag, err := s.conn.Exec(ctx, "DELETE FROM paste_grants WHERE pasteid = $1 AND id = $2", pasteID, id)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrGrantNotFound
+	}
+
+	return nil
+}
+
+// GetGrantedAccess returns the highest access level the user was granted to a paste, directly or through
+// an organization they are a member of. It returns an empty string if the user has no grant.
+func (s *Storage) GetGrantedAccess(ctx context.Context, pasteID string, userID int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := `SELECT access FROM paste_grants
+		WHERE pasteid = $1 AND (
+			userid = $2 OR orgid IN (SELECT orgid FROM OrganizationMembers WHERE userid = $2)
+		)
+		ORDER BY access = $3 DESC LIMIT 1`
+
+	var access string
+	err := s.conn.QueryRow(ctx, stmt, pasteID, userID, models.AccessWrite).Scan(&access)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return access, nil
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// GetUserByIdentity retrieves the User linked to the external identity.
+// If no user is linked, the function returns ErrUserNotFound.
+func (s *Storage) GetUserByIdentity(ctx context.Context, provider, subject string) (models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "SELECT " + userColumns + ` FROM Users
+		WHERE id = (SELECT userid FROM user_identities WHERE provider = $1 AND subject = $2)`
+
+	var user models.User
+	err := pgxscan.Get(ctx, s.conn, &user, stmt, provider, subject)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.User{}, storage.ErrUserNotFound
+		}
+
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// SaveUserWithIdentity creates a new user linked to the external identity and returns the ID of the user.
+// If the username or email is taken, the function returns ErrUsernameTaken or ErrEmailTaken.
+func (s *Storage) SaveUserWithIdentity(ctx context.Context, user *models.User, identity *models.Identity) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	stmt := "INSERT INTO Users (username, email, passwordhash, isverified) VALUES ($1, $2, $3, $4) RETURNING id"
+
+	var id int64
+	err = tx.QueryRow(ctx, stmt, user.Username, user.Email, user.PasswordHash, user.IsVerified).Scan(&id)
+	if err != nil {
+		return 0, mapUserError(err)
+	}
+
+	stmt = "INSERT INTO user_identities (userid, provider, subject, email) VALUES ($1, $2, $3, $4)"
+	if _, err := tx.Exec(ctx, stmt, id, identity.Provider, identity.Subject, identity.Email); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// SaveIdentity links the external identity to an existing user.
+// If the identity is linked to a user already, the function returns ErrIdentityTaken.
+func (s *Storage) SaveIdentity(ctx context.Context, identity *models.Identity) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "INSERT INTO user_identities (userid, provider, subject, email) VALUES ($1, $2, $3, $4)"
+
+	_, err := s.conn.Exec(ctx, stmt, identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return storage.ErrIdentityTaken
+		}
+
+		return err
+	}
+
+	return nil
+}
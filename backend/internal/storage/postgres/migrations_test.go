@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"TextVault/internal/storage/models"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// migrationUp returns the statements of the Up section of a migration.
+func migrationUp(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile("../../../migrations/" + name)
+	if err != nil {
+		t.Fatalf("read migration: %v", err)
+	}
+
+	up, _, _ := strings.Cut(string(data), "-- +goose Down")
+
+	return up
+}
+
+func TestRemoveUnusableOIDCPasswords(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	saveUser := func(prefix string, linked bool) int64 {
+		t.Helper()
+
+		name := uniqueName(prefix)
+		id, err := s.SaveUser(ctx, name, name+"@example.com", "random-hash")
+		if err != nil {
+			t.Fatalf("SaveUser() error = %v", err)
+		}
+
+		if linked {
+			identity := &models.Identity{UserID: id, Provider: "mock", Subject: name, Email: name + "@example.com"}
+			if err := s.SaveIdentity(ctx, identity); err != nil {
+				t.Fatalf("SaveIdentity() error = %v", err)
+			}
+		}
+
+		return id
+	}
+
+	provisioned := saveUser("provisioned", true)
+	local := saveUser("local", false)
+
+	// @NOTE: A provisioned user who chose a password through a reset must keep it
+	reset := saveUser("reset", true)
+	tokenHash := uniqueName("token")
+	if err := s.SavePasswordReset(ctx, reset, tokenHash, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("SavePasswordReset() error = %v", err)
+	}
+	if _, err := s.ResetPassword(ctx, tokenHash, "chosen-hash"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+
+	if _, err := s.conn.Exec(ctx, migrationUp(t, "00016_remove_unusable_oidc_passwords.sql")); err != nil {
+		t.Fatalf("run migration: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		id   int64
+		want string
+	}{
+		{name: "provisioned", id: provisioned, want: ""},
+		{name: "local", id: local, want: "random-hash"},
+		{name: "reset", id: reset, want: "chosen-hash"},
+	}
+
+	for _, tt := range tests {
+		user, err := s.GetUserByID(ctx, tt.id)
+		if err != nil {
+			t.Fatalf("GetUserByID() error = %v", err)
+		}
+		if user.PasswordHash != tt.want {
+			t.Errorf("%s user password hash = %q, want %q", tt.name, user.PasswordHash, tt.want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const foreignKeyViolation = "23503"
+
+// SaveOrganization creates an organization with the given user as its owner and returns its ID.
+// If the name is taken, the function returns ErrOrgNameTaken.
+func (s *Storage) SaveOrganization(ctx context.Context, name string, ownerID int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, "INSERT INTO Organizations (name) VALUES ($1) RETURNING id", name).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return 0, storage.ErrOrgNameTaken
+		}
+
+		return 0, err
+	}
+
+	stmt := "INSERT INTO OrganizationMembers (orgid, userid, role) VALUES ($1, $2, $3)"
+	if _, err := tx.Exec(ctx, stmt, id, ownerID, models.OrgRoleOwner); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetOrganization returns the organization with the given ID.
+// If it doesn't exist, the function returns ErrOrgNotFound.
+func (s *Storage) GetOrganization(ctx context.Context, id int64) (models.Organization, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "SELECT id, name, createdat FROM Organizations WHERE id = $1"
+
+	var org models.Organization
+	err := pgxscan.Get(ctx, s.conn, &org, stmt, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Organization{}, storage.ErrOrgNotFound
+		}
+
+		return models.Organization{}, err
+	}
+
+	return org, nil
+}
+
+// GetUserOrganizations returns the organizations the user is a member of, with the user's role in each.
+func (s *Storage) GetUserOrganizations(ctx context.Context, userID int64) ([]models.Organization, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := `SELECT o.id, o.name, o.createdat, m.role FROM Organizations o
+		JOIN OrganizationMembers m ON m.orgid = o.id
+		WHERE m.userid = $1 ORDER BY o.name`
+
+	var orgs []models.Organization
+	if err := pgxscan.Select(ctx, s.conn, &orgs, stmt, userID); err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+// GetOrganizationMembers returns the members of the organization, owners first.
+func (s *Storage) GetOrganizationMembers(ctx context.Context, orgID int64) ([]models.OrganizationMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := `SELECT m.orgid, m.userid, u.username, m.role, m.createdat FROM OrganizationMembers m
+		JOIN Users u ON u.id = m.userid
+		WHERE m.orgid = $1
+		ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 WHEN 'member' THEN 2 ELSE 3 END, u.username`
+
+	var members []models.OrganizationMember
+	if err := pgxscan.Select(ctx, s.conn, &members, stmt, orgID); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// GetMemberRole returns the role of the user in the organization.
+// If the user is not a member, the function returns ErrNotOrgMember.
+func (s *Storage) GetMemberRole(ctx context.Context, orgID, userID int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "SELECT role FROM OrganizationMembers WHERE orgid = $1 AND userid = $2"
+
+	var role string
+	err := s.conn.QueryRow(ctx, stmt, orgID, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", storage.ErrNotOrgMember
+		}
+
+		return "", err
+	}
+
+	return role, nil
+}
+
+// AddOrganizationMember adds the user to the organization with the given role.
+// If the user is already a member, the function returns ErrAlreadyOrgMember.
+func (s *Storage) AddOrganizationMember(ctx context.Context, orgID, userID int64, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "INSERT INTO OrganizationMembers (orgid, userid, role) VALUES ($1, $2, $3)"
+
+	_, err := s.conn.Exec(ctx, stmt, orgID, userID, role)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case uniqueViolation:
+				return storage.ErrAlreadyOrgMember
+			case foreignKeyViolation:
+				return storage.ErrOrgNotFound
+			}
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// UpdateMemberRole changes the role of a member of the organization.
+// If the user is not a member, the function returns ErrNotOrgMember, and if the last owner
+// would be demoted, it returns ErrLastOrgOwner.
+func (s *Storage) UpdateMemberRole(ctx context.Context, orgID, userID int64, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if role != models.OrgRoleOwner {
+		if err := ensureOtherOwner(ctx, tx, orgID, userID); err != nil {
+			return err
+		}
+	}
+
+	stmt := "UPDATE OrganizationMembers SET role = $3 WHERE orgid = $1 AND userid = $2"
+
+	tag, err := tx.Exec(ctx, stmt, orgID, userID, role)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotOrgMember
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RemoveOrganizationMember removes the user from the organization. Their pastes stay with the organization.
+// If the user is not a member, the function returns ErrNotOrgMember, and if they are the last owner,
+// it returns ErrLastOrgOwner.
+func (s *Storage) RemoveOrganizationMember(ctx context.Context, orgID, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := ensureOtherOwner(ctx, tx, orgID, userID); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, "DELETE FROM OrganizationMembers WHERE orgid = $1 AND userid = $2", orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotOrgMember
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteOrganization deletes the organization together with its pastes and returns the IDs of the deleted pastes.
+// If it doesn't exist, the function returns ErrOrgNotFound.
+func (s *Storage) DeleteOrganization(ctx context.Context, id int64) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var ids []string
+	if err := pgxscan.Select(ctx, tx, &ids, "DELETE FROM Pastes WHERE orgid = $1 RETURNING id", id); err != nil {
+		return nil, err
+	}
+
+	tag, err := tx.Exec(ctx, "DELETE FROM Organizations WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil, storage.ErrOrgNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// GetOrganizationPastes returns every paste owned by the organization, including private ones.
+func (s *Storage) GetOrganizationPastes(ctx context.Context, orgID int64) ([]models.Paste, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "SELECT ID, title, language, authorid, visibility, orgid FROM Pastes WHERE orgid = $1"
+
+	var pastes []models.Paste
+	if err := pgxscan.Select(ctx, s.conn, &pastes, stmt, orgID); err != nil {
+		return nil, err
+	}
+
+	return pastes, nil
+}
+
+// ensureOtherOwner returns ErrLastOrgOwner if the user is the only owner of the organization.
+// The owners are locked until the transaction ends, so concurrent demotions can't both pass.
+func ensureOtherOwner(ctx context.Context, tx pgx.Tx, orgID, userID int64) error {
+	stmt := "SELECT userid FROM OrganizationMembers WHERE orgid = $1 AND role = $2 FOR UPDATE"
+
+	var owners []int64
+	if err := pgxscan.Select(ctx, tx, &owners, stmt, orgID, models.OrgRoleOwner); err != nil {
+		return err
+	}
+
+	if len(owners) == 1 && owners[0] == userID {
+		return storage.ErrLastOrgOwner
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLastOrganizationOwner(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	saveUser := func(prefix string) int64 {
+		t.Helper()
+
+		name := uniqueName(prefix)
+		id, err := s.SaveUser(ctx, name, name+"@example.com", "hash")
+		if err != nil {
+			t.Fatalf("SaveUser() error = %v", err)
+		}
+
+		return id
+	}
+
+	ownerID := saveUser("owner")
+	otherID := saveUser("other")
+
+	orgID, err := s.SaveOrganization(ctx, uniqueName("org"), ownerID)
+	if err != nil {
+		t.Fatalf("SaveOrganization() error = %v", err)
+	}
+
+	if err := s.UpdateMemberRole(ctx, orgID, ownerID, models.OrgRoleAdmin); !errors.Is(err, storage.ErrLastOrgOwner) {
+		t.Errorf("UpdateMemberRole() of the last owner error = %v, want %v", err, storage.ErrLastOrgOwner)
+	}
+	if err := s.RemoveOrganizationMember(ctx, orgID, ownerID); !errors.Is(err, storage.ErrLastOrgOwner) {
+		t.Errorf("RemoveOrganizationMember() of the last owner error = %v, want %v", err, storage.ErrLastOrgOwner)
+	}
+
+	if err := s.AddOrganizationMember(ctx, orgID, otherID, models.OrgRoleOwner); err != nil {
+		t.Fatalf("AddOrganizationMember() error = %v", err)
+	}
+
+	// @NOTE: With a second owner, either of them can step down
+	if err := s.UpdateMemberRole(ctx, orgID, ownerID, models.OrgRoleAdmin); err != nil {
+		t.Fatalf("UpdateMemberRole() with another owner error = %v", err)
+	}
+	if err := s.RemoveOrganizationMember(ctx, orgID, otherID); !errors.Is(err, storage.ErrLastOrgOwner) {
+		t.Errorf("RemoveOrganizationMember() of the new last owner error = %v, want %v", err, storage.ErrLastOrgOwner)
+	}
+
+	role, err := s.GetMemberRole(ctx, orgID, ownerID)
+	if err != nil {
+		t.Fatalf("GetMemberRole() error = %v", err)
+	}
+	if role != models.OrgRoleAdmin {
+		t.Errorf("role = %q, want %q", role, models.OrgRoleAdmin)
+	}
+}
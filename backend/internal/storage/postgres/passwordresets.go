@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+// SavePasswordReset stores the hash of a password reset token issued for the user.
+func (s *Storage) SavePasswordReset(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "INSERT INTO PasswordResets (userid, tokenhash, expiresat) VALUES ($1, $2, $3)"
+
+	_, err := s.conn.Exec(ctx, stmt, userID, tokenHash, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetUserByResetToken returns the user of an unused and unexpired reset token without consuming it.
+// If the token is unknown, used or expired, the function returns ErrInvalidResetToken.
+func (s *Storage) GetUserByResetToken(ctx context.Context, tokenHash string) (models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "SELECT " + userColumns + ` FROM Users
+		WHERE id = (SELECT userid FROM PasswordResets WHERE tokenhash = $1 AND usedat IS NULL AND expiresat > NOW())`
+
+	var user models.User
+	err := pgxscan.Get(ctx, s.conn, &user, stmt, tokenHash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.User{}, storage.ErrInvalidResetToken
+		}
+
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// ResetPassword consumes an unused and unexpired reset token, sets the new password hash of its user
+// and revokes all the user's sessions and outstanding reset tokens. It returns the ID of the user.
+// If the token is unknown, used or expired, the function returns ErrInvalidResetToken.
+func (s *Storage) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	stmt := `UPDATE PasswordResets SET usedat = NOW()
+		WHERE tokenhash = $1 AND usedat IS NULL AND expiresat > NOW() RETURNING userid`
+
+	var userID int64
+	err = tx.QueryRow(ctx, stmt, tokenHash).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, storage.ErrInvalidResetToken
+		}
+
+		return 0, err
+	}
+
+	stmt = "UPDATE Users SET passwordhash = $2, tokenversion = tokenversion + 1 WHERE id = $1"
+	if _, err := tx.Exec(ctx, stmt, userID, passwordHash); err != nil {
+		return 0, err
+	}
+
+	stmt = "UPDATE PasswordResets SET usedat = NOW() WHERE userid = $1 AND usedat IS NULL"
+	if _, err := tx.Exec(ctx, stmt, userID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetUserByResetToken(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	name := uniqueName("reset")
+	userID, err := s.SaveUser(ctx, name, name+"@example.com", "hash")
+	if err != nil {
+		t.Fatalf("SaveUser() error = %v", err)
+	}
+
+	tokenHash := uniqueName("token")
+	if err := s.SavePasswordReset(ctx, userID, tokenHash, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("SavePasswordReset() error = %v", err)
+	}
+
+	user, err := s.GetUserByResetToken(ctx, tokenHash)
+	if err != nil {
+		t.Fatalf("GetUserByResetToken() error = %v", err)
+	}
+	if user.ID != userID || user.Username != name {
+		t.Errorf("GetUserByResetToken() = user %d %q, want %d %q", user.ID, user.Username, userID, name)
+	}
+
+	if _, err := s.ResetPassword(ctx, tokenHash, "new-hash"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+
+	if _, err := s.GetUserByResetToken(ctx, tokenHash); !errors.Is(err, storage.ErrInvalidResetToken) {
+		t.Errorf("GetUserByResetToken() of used token error = %v, want %v", err, storage.ErrInvalidResetToken)
+	}
+}
@@ -33,10 +33,10 @@ func (s *Storage) SavePaste(ctx context.Context, paste *models.Paste) (string, e
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	stmt := "INSERT INTO Pastes (title, language, authorid) VALUES ($1, $2, $3) RETURNING id"
+	stmt := "INSERT INTO Pastes (title, language, authorid, visibility, orgid) VALUES ($1, $2, $3, $4, $5) RETURNING id"
 
 	var id string
-	err := s.conn.QueryRow(ctx, stmt, paste.Title, paste.Language, paste.AuthorID).Scan(&id)
+	err := s.conn.QueryRow(ctx, stmt, paste.Title, paste.Language, paste.AuthorID, paste.Visibility, paste.OrgID).Scan(&id)
 	if err != nil {
 		return "", err
 	}
@@ -44,6 +44,26 @@ func (s *Storage) SavePaste(ctx context.Context, paste *models.Paste) (string, e
 	return id, nil
 }
 
+// UpdatePaste saves the title, language and visibility of a paste.
+// If the paste doesn't exist, the function returns ErrPasteNotFound.
+func (s *Storage) UpdatePaste(ctx context.Context, paste *models.Paste) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "UPDATE Pastes SET title = $2, language = $3, visibility = $4 WHERE id = $1"
+
+	tag, err := s.conn.Exec(ctx, stmt, paste.ID, paste.Title, paste.Language, paste.Visibility)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrPasteNotFound
+	}
+
+	return nil
+}
+
 func (s *Storage) DeletePaste(ctx context.Context, id string) error {
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
@@ -57,3 +77,20 @@ func (s *Storage) DeletePaste(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// DeleteUserPastes deletes every personal paste of the given author and returns the IDs of the deleted pastes.
+// Pastes owned by an organization are kept.
+func (s *Storage) DeleteUserPastes(ctx context.Context, authorID int64) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "DELETE FROM Pastes WHERE authorid = $1 AND orgid IS NULL RETURNING id"
+
+	var ids []string
+	err := pgxscan.Select(ctx, s.conn, &ids, stmt, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
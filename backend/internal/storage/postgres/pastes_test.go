@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDeleteUserPastesKeepsOrganizationPastes(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	name := uniqueName("author")
+	authorID, err := s.SaveUser(ctx, name, name+"@example.com", "hash")
+	if err != nil {
+		t.Fatalf("SaveUser() error = %v", err)
+	}
+
+	orgID, err := s.SaveOrganization(ctx, uniqueName("org"), authorID)
+	if err != nil {
+		t.Fatalf("SaveOrganization() error = %v", err)
+	}
+
+	personalID, err := s.SavePaste(ctx, &models.Paste{Title: "personal", Language: "text", Visibility: models.VisibilityPublic, AuthorID: authorID})
+	if err != nil {
+		t.Fatalf("SavePaste() error = %v", err)
+	}
+
+	orgPasteID, err := s.SavePaste(ctx, &models.Paste{Title: "org", Language: "text", Visibility: models.VisibilityPrivate, AuthorID: authorID, OrgID: &orgID})
+	if err != nil {
+		t.Fatalf("SavePaste() error = %v", err)
+	}
+
+	ids, err := s.DeleteUserPastes(ctx, authorID)
+	if err != nil {
+		t.Fatalf("DeleteUserPastes() error = %v", err)
+	}
+	if len(ids) != 1 || ids[0] != personalID {
+		t.Errorf("DeleteUserPastes() = %v, want [%s]", ids, personalID)
+	}
+
+	if _, err := s.GetPaste(ctx, personalID); !errors.Is(err, storage.ErrPasteNotFound) {
+		t.Errorf("GetPaste() of purged paste error = %v, want %v", err, storage.ErrPasteNotFound)
+	}
+	if _, err := s.GetPaste(ctx, orgPasteID); err != nil {
+		t.Errorf("GetPaste() of organization paste error = %v, want nil", err)
+	}
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"TextVault/internal/config"
+	"TextVault/internal/tracing"
 	"context"
 	"fmt"
 	"log/slog"
@@ -22,12 +23,19 @@ func New(ctx context.Context, log *slog.Logger, cfg *config.PostgresConfig) (*St
 	const timeout = 5 * time.Second
 
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Database)
-	log.Debug("Connecting to database", "dsn", dsn)
+	log.Debug("Connecting to database",
+		slog.String("host", cfg.Host),
+		slog.String("port", cfg.Port),
+		slog.String("database", cfg.Database),
+		slog.String("user", cfg.User),
+	)
 	poolConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, err
 	}
 
+	poolConfig.ConnConfig.Tracer = tracing.NewQueryTracer()
+
 	conn, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, err
@@ -43,6 +51,11 @@ func (s *Storage) Close() {
 	s.conn.Close()
 }
 
+// Stat returns a snapshot of the connection pool statistics.
+func (s *Storage) Stat() *pgxpool.Stat {
+	return s.conn.Stat()
+}
+
 func (s *Storage) Ping(ctx context.Context) error {
 	return s.conn.Ping(ctx)
 }
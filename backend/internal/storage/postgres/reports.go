@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+// pasteReportSelect selects models.PasteReport rows together with the paste's title, hidden time
+// and number of open reports. Reports of deleted pastes are kept, so the paste is joined optionally.
+const pasteReportSelect = `SELECT r.id, r.pasteid, COALESCE(p.title, '') AS pastetitle, p.hiddenat AS pastehiddenat,
+	p.id IS NULL AS pastedeleted, r.reporterid, r.reporter, r.category, r.details, r.status, r.resolvedby, r.resolvedat, r.createdat,
+	(SELECT COUNT(*) FROM paste_reports o WHERE o.pasteid = r.pasteid AND o.status = 'open') AS openreports
+	FROM paste_reports r
+	LEFT JOIN Pastes p ON p.id = r.pasteid`
+
+// SavePasteReport saves a report of a paste and returns the number of its open reports, including the new one.
+// If the reporter already reported the paste, the function returns ErrAlreadyReported.
+// If the paste doesn't exist, the function returns ErrPasteNotFound.
+func (s *Storage) SavePasteReport(ctx context.Context, report *models.PasteReport) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// @NOTE: Reports don't reference the paste, so its existence is checked here and locked until the report is saved
+	var exists int
+	err = tx.QueryRow(ctx, "SELECT 1 FROM Pastes WHERE id = $1 FOR SHARE", report.PasteID).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, storage.ErrPasteNotFound
+		}
+
+		return 0, err
+	}
+
+	stmt := `INSERT INTO paste_reports (pasteid, reporterid, reporter, category, details) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (pasteid, reporter) DO NOTHING
+		RETURNING id`
+
+	err = tx.QueryRow(ctx, stmt, report.PasteID, report.ReporterID, report.Reporter, report.Category, report.Details).Scan(&report.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, storage.ErrAlreadyReported
+		}
+
+		return 0, err
+	}
+
+	var open int64
+	stmt = "SELECT COUNT(*) FROM paste_reports WHERE pasteid = $1 AND status = $2"
+	if err := tx.QueryRow(ctx, stmt, report.PasteID, models.ReportStatusOpen).Scan(&open); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return open, nil
+}
+
+// GetPasteReport returns a report of a paste.
+// If the report doesn't exist, the function returns ErrReportNotFound.
+func (s *Storage) GetPasteReport(ctx context.Context, id int64) (models.PasteReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var report models.PasteReport
+	err := pgxscan.Get(ctx, s.conn, &report, pasteReportSelect+" WHERE r.id = $1", id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.PasteReport{}, storage.ErrReportNotFound
+		}
+
+		return models.PasteReport{}, err
+	}
+
+	return report, nil
+}
+
+// GetPasteReports returns the reports with the given status. The reports of the most reported pastes come first,
+// and the reports of a paste are listed together, oldest first.
+func (s *Storage) GetPasteReports(ctx context.Context, status string, limit, offset int) ([]models.PasteReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := pasteReportSelect + ` WHERE r.status = $1
+		ORDER BY openreports DESC, r.pasteid, r.createdat
+		LIMIT $2 OFFSET $3`
+
+	var reports []models.PasteReport
+	err := pgxscan.Select(ctx, s.conn, &reports, stmt, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// ResolvePasteReports resolves every open report of a paste with the given status and returns how many there were.
+func (s *Storage) ResolvePasteReports(ctx context.Context, pasteID, status string, resolvedBy int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := `UPDATE paste_reports SET status = $2, resolvedby = $3, resolvedat = NOW()
+		WHERE pasteid = $1 AND status = $4`
+
+	tag, err := s.conn.Exec(ctx, stmt, pasteID, status, resolvedBy, models.ReportStatusOpen)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// SetPasteHidden hides a paste from everyone but admins, or makes a hidden paste visible again.
+// Hiding an already hidden paste keeps its original hidden time.
+// If the paste doesn't exist, the function returns ErrPasteNotFound.
+func (s *Storage) SetPasteHidden(ctx context.Context, id string, hidden bool) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "UPDATE Pastes SET hiddenat = CASE WHEN $2 THEN COALESCE(hiddenat, NOW()) END WHERE id = $1"
+
+	tag, err := s.conn.Exec(ctx, stmt, id, hidden)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrPasteNotFound
+	}
+
+	return nil
+}
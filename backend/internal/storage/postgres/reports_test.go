@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPasteReportsOutliveDeletedPaste(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	name := uniqueName("reporter")
+	reporterID, err := s.SaveUser(ctx, name, name+"@example.com", "hash")
+	if err != nil {
+		t.Fatalf("SaveUser() error = %v", err)
+	}
+
+	pasteID, err := s.SavePaste(ctx, &models.Paste{Title: "spam", Language: "text", Visibility: models.VisibilityPublic})
+	if err != nil {
+		t.Fatalf("SavePaste() error = %v", err)
+	}
+
+	report := &models.PasteReport{
+		PasteID:    pasteID,
+		ReporterID: &reporterID,
+		Reporter:   "user:" + name,
+		Category:   "spam",
+	}
+	if _, err := s.SavePasteReport(ctx, report); err != nil {
+		t.Fatalf("SavePasteReport() error = %v", err)
+	}
+
+	if err := s.DeletePaste(ctx, pasteID); err != nil {
+		t.Fatalf("DeletePaste() error = %v", err)
+	}
+
+	resolved, err := s.ResolvePasteReports(ctx, pasteID, models.ReportStatusDeleted, reporterID)
+	if err != nil {
+		t.Fatalf("ResolvePasteReports() error = %v", err)
+	}
+	if resolved != 1 {
+		t.Errorf("ResolvePasteReports() = %d, want 1", resolved)
+	}
+
+	got, err := s.GetPasteReport(ctx, report.ID)
+	if err != nil {
+		t.Fatalf("GetPasteReport() error = %v", err)
+	}
+	if got.Status != models.ReportStatusDeleted || !got.PasteDeleted || got.PasteID != pasteID {
+		t.Errorf("GetPasteReport() = status %q, paste deleted %v, paste %q; want deleted report of deleted paste %q",
+			got.Status, got.PasteDeleted, got.PasteID, pasteID)
+	}
+
+	reports, err := s.GetPasteReports(ctx, models.ReportStatusDeleted, 200, 0)
+	if err != nil {
+		t.Fatalf("GetPasteReports() error = %v", err)
+	}
+
+	found := false
+	for _, r := range reports {
+		found = found || r.ID == report.ID
+	}
+	if !found {
+		t.Errorf("GetPasteReports(%q) doesn't list report %d", models.ReportStatusDeleted, report.ID)
+	}
+
+	_, err = s.SavePasteReport(ctx, &models.PasteReport{PasteID: pasteID, Reporter: "ip:192.0.2.1", Category: "spam"})
+	if !errors.Is(err, storage.ErrPasteNotFound) {
+		t.Errorf("SavePasteReport() of deleted paste error = %v, want %v", err, storage.ErrPasteNotFound)
+	}
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+const shareLinkColumns = "id, pasteid, createdby, expiresat, maxviews, views, revokedat, createdat"
+
+// SaveShareLink creates a share link and returns its ID.
+func (s *Storage) SaveShareLink(ctx context.Context, link *models.ShareLink) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "INSERT INTO share_links (pasteid, createdby, expiresat, maxviews) VALUES ($1, $2, $3, $4) RETURNING id"
+
+	var id string
+	err := s.conn.QueryRow(ctx, stmt, link.PasteID, link.CreatedBy, link.ExpiresAt, link.MaxViews).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// GetShareLinks returns every share link of a paste, including expired and revoked ones, newest first.
+func (s *Storage) GetShareLinks(ctx context.Context, pasteID string) ([]models.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "SELECT " + shareLinkColumns + " FROM share_links WHERE pasteid = $1 ORDER BY createdat DESC"
+
+	var links []models.ShareLink
+	if err := pgxscan.Select(ctx, s.conn, &links, stmt, pasteID); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// RevokeShareLink revokes a share link of a paste. Revoking a revoked link keeps the original revocation time.
+// If the paste has no such link or the ID isn't a UUID, the function returns ErrShareLinkNotFound.
+func (s *Storage) RevokeShareLink(ctx context.Context, pasteID, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "UPDATE share_links SET revokedat = COALESCE(revokedat, NOW()) WHERE id = $1 AND pasteid = $2"
+
+	tag, err := s.conn.Exec(ctx, stmt, id, pasteID)
+	if err != nil {
+		return mapShareLinkError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrShareLinkNotFound
+	}
+
+	return nil
+}
+
+// UseShareLink counts a view through a share link of a paste and records the use.
+// If the link doesn't exist, belongs to another paste, is revoked, expired or out of views,
+// the function returns ErrInvalidShareLink.
+func (s *Storage) UseShareLink(ctx context.Context, id, pasteID, ip, userAgent string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// @NOTE: The view limit is checked and counted in one statement, so concurrent uses can't exceed it
+	stmt := `UPDATE share_links SET views = views + 1
+		WHERE id = $1 AND pasteid = $2 AND revokedat IS NULL AND expiresat > NOW()
+		AND (maxviews IS NULL OR views < maxviews)`
+
+	tag, err := tx.Exec(ctx, stmt, id, pasteID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrInvalidShareLink
+	}
+
+	stmt = "INSERT INTO share_link_uses (linkid, ip, useragent) VALUES ($1, $2, $3)"
+	if _, err := tx.Exec(ctx, stmt, id, ip, userAgent); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetShareLinkUses returns the recorded uses of a share link of a paste, newest first.
+// If the paste has no such link or the ID isn't a UUID, the function returns ErrShareLinkNotFound.
+func (s *Storage) GetShareLinkUses(ctx context.Context, pasteID, id string) ([]models.ShareLinkUse, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var exists bool
+	stmt := "SELECT EXISTS (SELECT 1 FROM share_links WHERE id = $1 AND pasteid = $2)"
+	if err := s.conn.QueryRow(ctx, stmt, id, pasteID).Scan(&exists); err != nil {
+		return nil, mapShareLinkError(err)
+	}
+
+	if !exists {
+		return nil, storage.ErrShareLinkNotFound
+	}
+
+	stmt = "SELECT id, linkid, ip, useragent, usedat FROM share_link_uses WHERE linkid = $1 ORDER BY usedat DESC"
+
+	var uses []models.ShareLinkUse
+	if err := pgxscan.Select(ctx, s.conn, &uses, stmt, id); err != nil {
+		return nil, err
+	}
+
+	return uses, nil
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestShareLinkMalformedID(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+
+	name := uniqueName("author")
+	authorID, err := s.SaveUser(ctx, name, name+"@example.com", "hash")
+	if err != nil {
+		t.Fatalf("SaveUser() error = %v", err)
+	}
+
+	pasteID, err := s.SavePaste(ctx, &models.Paste{Title: "linked", Language: "text", Visibility: models.VisibilityPrivate, AuthorID: authorID})
+	if err != nil {
+		t.Fatalf("SavePaste() error = %v", err)
+	}
+
+	if err := s.RevokeShareLink(ctx, pasteID, "not-a-uuid"); !errors.Is(err, storage.ErrShareLinkNotFound) {
+		t.Errorf("RevokeShareLink() error = %v, want %v", err, storage.ErrShareLinkNotFound)
+	}
+
+	if _, err := s.GetShareLinkUses(ctx, pasteID, "not-a-uuid"); !errors.Is(err, storage.ErrShareLinkNotFound) {
+		t.Errorf("GetShareLinkUses() error = %v, want %v", err, storage.ErrShareLinkNotFound)
+	}
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+)
+
+// testDSNEnv names the environment variable with the DSN of a disposable database for the storage tests.
+// The tests are skipped if it is not set.
+const testDSNEnv = "TEXTVAULT_TEST_POSTGRES_DSN"
+
+// newTestStorage connects to the test database and migrates it to the latest version.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	conn, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(conn.Close)
+
+	if err := goose.SetDialect("postgres"); err != nil {
+		t.Fatalf("set goose dialect: %v", err)
+	}
+	goose.SetLogger(goose.NopLogger())
+
+	if err := goose.Up(stdlib.OpenDBFromPool(conn), "../../../migrations"); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	return &Storage{
+		conn:    conn,
+		timeout: 5 * time.Second,
+	}
+}
+
+// uniqueName returns a name that is unique across test runs against the same database.
+func uniqueName(prefix string) string {
+	return fmt.Sprintf("%s%d", prefix, time.Now().UnixNano())
+}
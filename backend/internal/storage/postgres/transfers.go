@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
+	"context"
+	"errors"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pasteTransferSelect selects models.PasteTransfer rows together with the paste title and the sender's username.
+const pasteTransferSelect = `SELECT t.id, t.pasteid, p.title AS pastetitle, t.fromuserid, u.username AS fromusername,
+	t.touserid, t.toorgid, t.createdat
+	FROM paste_transfers t
+	JOIN Pastes p ON p.id = t.pasteid
+	JOIN Users u ON u.id = t.fromuserid`
+
+// SaveClaimToken stores the hash of the claim token of an anonymous paste.
+func (s *Storage) SaveClaimToken(ctx context.Context, pasteID, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.conn.Exec(ctx, "INSERT INTO paste_claims (pasteid, tokenhash) VALUES ($1, $2)", pasteID, tokenHash)
+
+	return err
+}
+
+// ClaimPaste makes the user the author of an anonymous paste and consumes its claim token.
+// If the token is wrong, already used or the paste is not anonymous anymore, the function returns ErrInvalidClaimToken.
+func (s *Storage) ClaimPaste(ctx context.Context, pasteID, tokenHash string, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "DELETE FROM paste_claims WHERE pasteid = $1 AND tokenhash = $2", pasteID, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrInvalidClaimToken
+	}
+
+	stmt := "UPDATE Pastes SET authorid = $2 WHERE id = $1 AND authorid = 0 AND orgid IS NULL"
+
+	tag, err = tx.Exec(ctx, stmt, pasteID, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrInvalidClaimToken
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SavePasteTransfer starts a transfer of a paste and returns its ID. A pending transfer of the same paste is replaced.
+// If the recipient doesn't exist, the function returns ErrUserNotFound or ErrOrgNotFound.
+func (s *Storage) SavePasteTransfer(ctx context.Context, transfer *models.PasteTransfer) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := `INSERT INTO paste_transfers (pasteid, fromuserid, touserid, toorgid) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (pasteid) DO UPDATE SET fromuserid = EXCLUDED.fromuserid, touserid = EXCLUDED.touserid,
+		toorgid = EXCLUDED.toorgid, createdat = NOW()
+		RETURNING id`
+
+	var id int64
+	err := s.conn.QueryRow(ctx, stmt, transfer.PasteID, transfer.FromUserID, transfer.ToUserID, transfer.ToOrgID).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			switch pgErr.ConstraintName {
+			case "paste_transfers_toorgid_fkey":
+				return 0, storage.ErrOrgNotFound
+			case "paste_transfers_touserid_fkey":
+				return 0, storage.ErrUserNotFound
+			default:
+				return 0, storage.ErrPasteNotFound
+			}
+		}
+
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetPasteTransfer returns the pending transfer of a paste.
+// If the paste has none, the function returns ErrTransferNotFound.
+func (s *Storage) GetPasteTransfer(ctx context.Context, pasteID string) (models.PasteTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var transfer models.PasteTransfer
+	err := pgxscan.Get(ctx, s.conn, &transfer, pasteTransferSelect+" WHERE t.pasteid = $1", pasteID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.PasteTransfer{}, storage.ErrTransferNotFound
+		}
+
+		return models.PasteTransfer{}, err
+	}
+
+	return transfer, nil
+}
+
+// GetIncomingTransfers returns the pending transfers the user can accept: those to the user
+// and those to organizations the user is an admin or owner of.
+func (s *Storage) GetIncomingTransfers(ctx context.Context, userID int64) ([]models.PasteTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := pasteTransferSelect + ` WHERE t.touserid = $1 OR t.toorgid IN (
+		SELECT orgid FROM OrganizationMembers WHERE userid = $1 AND role IN ($2, $3)
+	) ORDER BY t.createdat DESC`
+
+	var transfers []models.PasteTransfer
+	err := pgxscan.Select(ctx, s.conn, &transfers, stmt, userID, models.OrgRoleOwner, models.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+// DeletePasteTransfer cancels or declines a pending transfer.
+// If the transfer doesn't exist anymore, the function returns ErrTransferNotFound.
+func (s *Storage) DeletePasteTransfer(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tag, err := s.conn.Exec(ctx, "DELETE FROM paste_transfers WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrTransferNotFound
+	}
+
+	return nil
+}
+
+// AcceptPasteTransfer completes a pending transfer. A paste transferred to a user becomes their personal paste;
+// a paste transferred to an organization becomes owned by it and keeps its author.
+// If the transfer doesn't exist anymore, the function returns ErrTransferNotFound.
+func (s *Storage) AcceptPasteTransfer(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var transfer models.PasteTransfer
+	stmt := "DELETE FROM paste_transfers WHERE id = $1 RETURNING pasteid, touserid, toorgid"
+	if err := tx.QueryRow(ctx, stmt, id).Scan(&transfer.PasteID, &transfer.ToUserID, &transfer.ToOrgID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrTransferNotFound
+		}
+
+		return err
+	}
+
+	if transfer.ToUserID != nil {
+		stmt = "UPDATE Pastes SET authorid = $2, orgid = NULL WHERE id = $1"
+		_, err = tx.Exec(ctx, stmt, transfer.PasteID, *transfer.ToUserID)
+	} else {
+		stmt = "UPDATE Pastes SET orgid = $2 WHERE id = $1"
+		_, err = tx.Exec(ctx, stmt, transfer.PasteID, *transfer.ToOrgID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"TextVault/internal/storage"
+	"context"
+)
+
+// SetTOTPSecret stores a pending TOTP secret for the user. The secret is only used for
+// logins after EnableTOTP confirms it. If 2FA is already enabled, the function returns ErrTwoFactorEnabled.
+func (s *Storage) SetTOTPSecret(ctx context.Context, id int64, secret string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "UPDATE Users SET totpsecret = $2, totplaststep = 0 WHERE id = $1 AND NOT totpenabled"
+
+	tag, err := s.conn.Exec(ctx, stmt, id, secret)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrTwoFactorEnabled
+	}
+
+	return nil
+}
+
+// EnableTOTP enables 2FA for the user and replaces their recovery codes with the given hashes.
+func (s *Storage) EnableTOTP(ctx context.Context, id int64, recoveryCodeHashes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "UPDATE Users SET totpenabled = TRUE WHERE id = $1 AND NOT totpenabled AND totpsecret <> ''", id)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrTwoFactorEnabled
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM RecoveryCodes WHERE userid = $1", id); err != nil {
+		return err
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(ctx, "INSERT INTO RecoveryCodes (userid, codehash) VALUES ($1, $2)", id, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DisableTOTP disables 2FA for the user and removes their secret and recovery codes.
+// If the user is not found, the function returns ErrUserNotFound.
+func (s *Storage) DisableTOTP(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "UPDATE Users SET totpsecret = '', totpenabled = FALSE, totplaststep = 0 WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM RecoveryCodes WHERE userid = $1", id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseTOTPStep records the time step of an accepted TOTP code. Steps must increase, so a code
+// can't be replayed. If the step was already used, the function returns ErrInvalidTwoFactor.
+func (s *Storage) UseTOTPStep(ctx context.Context, id int64, step int64) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "UPDATE Users SET totplaststep = $2 WHERE id = $1 AND totplaststep < $2"
+
+	tag, err := s.conn.Exec(ctx, stmt, id, step)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrInvalidTwoFactor
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code of the user as used.
+// If no such code exists, the function returns ErrInvalidTwoFactor.
+func (s *Storage) UseRecoveryCode(ctx context.Context, id int64, codeHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "UPDATE RecoveryCodes SET usedat = NOW() WHERE userid = $1 AND codehash = $2 AND usedat IS NULL"
+
+	tag, err := s.conn.Exec(ctx, stmt, id, codeHash)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrInvalidTwoFactor
+	}
+
+	return nil
+}
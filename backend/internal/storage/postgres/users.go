@@ -4,14 +4,21 @@ import (
 	"TextVault/internal/storage"
 	"TextVault/internal/storage/models"
 	"context"
+	"errors"
+	"time"
 
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5"
 )
 
+// userColumns is the column list used to scan a models.User. A ban is only reported
+// as active until its expiry time passes.
+const userColumns = `ID, username, email, passwordhash, isadmin,
+	(isbanned AND (banneduntil IS NULL OR banneduntil > NOW())) AS isbanned, banreason, banneduntil, isverified, tokenversion,
+	totpsecret, totpenabled`
+
 // SaveUser creates a new user in the database and returns the ID of the newly created user.
-// If any error occurs during the execution of the function, it is wrapped in a custom error
-// with the prefix "storage.postgresql.SaveUser" and returned.
+// If the username or email is taken, the function returns ErrUsernameTaken or ErrEmailTaken.
 // The context is used to set a timeout for the execution of the function, which is set to the
 // value of the timeout field of the receiver.
 func (s *Storage) SaveUser(ctx context.Context, username, email, password string) (int64, error) {
@@ -23,13 +30,13 @@ func (s *Storage) SaveUser(ctx context.Context, username, email, password string
 	var id int64
 	err := s.conn.QueryRow(ctx, stmt, username, email, password).Scan(&id)
 	if err != nil {
-		return 0, err
+		return 0, mapUserError(err)
 	}
 
 	return id, nil
 }
 
-// GetUser retrieves a User from the database based on the provided username.
+// GetUser retrieves a User from the database based on the provided username or email, ignoring case.
 // If the username is not found, the function returns ErrUserNotFound.
 // If any other error occurs, the function returns the error wrapped in a custom error
 // with the prefix "storage.postgresql.GetUser".
@@ -37,7 +44,7 @@ func (s *Storage) GetUser(ctx context.Context, username string) (models.User, er
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	stmt := "SELECT ID, username, email, passwordhash FROM Users WHERE username = $1 OR email = $1"
+	stmt := "SELECT " + userColumns + " FROM Users WHERE LOWER(username) = LOWER($1) OR LOWER(email) = LOWER($1)"
 
 	var user models.User
 	err := pgxscan.Get(ctx, s.conn, &user, stmt, username)
@@ -52,11 +59,32 @@ func (s *Storage) GetUser(ctx context.Context, username string) (models.User, er
 	return user, nil
 }
 
+// GetUserByID retrieves a User from the database based on the provided ID.
+// If the user is not found, the function returns ErrUserNotFound.
+func (s *Storage) GetUserByID(ctx context.Context, id int64) (models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "SELECT " + userColumns + " FROM Users WHERE id = $1"
+
+	var user models.User
+	err := pgxscan.Get(ctx, s.conn, &user, stmt, id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.User{}, storage.ErrUserNotFound
+		}
+
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
 func (s *Storage) GetUserPastes(ctx context.Context, userID int64) ([]models.Paste, error) {
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	stmt := "SELECT ID, title, hash, language, authorid FROM Pastes WHERE authorid = $1"
+	stmt := "SELECT ID, title, language, authorid, visibility, orgid FROM Pastes WHERE authorid = $1"
 
 	var pastes []models.Paste
 	err := pgxscan.Select(ctx, s.conn, &pastes, stmt, userID)
@@ -71,6 +99,203 @@ func (s *Storage) GetUserPastes(ctx context.Context, userID int64) ([]models.Pas
 	return pastes, nil
 }
 
+// ListUsers returns users whose username or email contains the query, ordered by ID.
+// An empty query matches every user.
+func (s *Storage) ListUsers(ctx context.Context, query string, limit, offset int) ([]models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "SELECT " + userColumns + ` FROM Users
+		WHERE $1 = '' OR username ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%'
+		ORDER BY ID LIMIT $2 OFFSET $3`
+
+	var users []models.User
+	err := pgxscan.Select(ctx, s.conn, &users, stmt, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// BanUser bans the user with the given ID. A nil until bans the user permanently.
+// If the user is not found, the function returns ErrUserNotFound.
+func (s *Storage) BanUser(ctx context.Context, id int64, reason string, until *time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "UPDATE Users SET isbanned = TRUE, banreason = $2, banneduntil = $3 WHERE id = $1"
+
+	tag, err := s.conn.Exec(ctx, stmt, id, reason, until)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UnbanUser lifts the ban of the user with the given ID.
+// If the user is not found, the function returns ErrUserNotFound.
+func (s *Storage) UnbanUser(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "UPDATE Users SET isbanned = FALSE, banreason = '', banneduntil = NULL WHERE id = $1"
+
+	tag, err := s.conn.Exec(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// VerifyUser marks the email of the user as verified. The email must still match the one the
+// verification was issued for. If no such user is found, the function returns ErrUserNotFound.
+func (s *Storage) VerifyUser(ctx context.Context, id int64, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "UPDATE Users SET isverified = TRUE WHERE id = $1 AND email = $2"
+
+	tag, err := s.conn.Exec(ctx, stmt, id, email)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateUser updates the username, email and verification state of the user.
+// If the user is not found, the function returns ErrUserNotFound.
+// If the username or email is taken, the function returns ErrUsernameTaken or ErrEmailTaken.
 func (s *Storage) UpdateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "UPDATE Users SET username = $2, email = $3, isverified = $4 WHERE id = $1"
+
+	tag, err := s.conn.Exec(ctx, stmt, user.ID, user.Username, user.Email, user.IsVerified)
+	if err != nil {
+		return mapUserError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdatePassword sets the password hash of the user and revokes all the user's sessions.
+// It returns the new token version of the user.
+// If the user is not found, the function returns ErrUserNotFound.
+func (s *Storage) UpdatePassword(ctx context.Context, id int64, passwordHash string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "UPDATE Users SET passwordhash = $2, tokenversion = tokenversion + 1 WHERE id = $1 RETURNING tokenversion"
+
+	var version int64
+	err := s.conn.QueryRow(ctx, stmt, id, passwordHash).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, storage.ErrUserNotFound
+		}
+
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// RehashPassword replaces the password hash of the user with an equivalent hash of the same password,
+// e.g. one made with newer parameters. Sessions stay valid. It does nothing if the hash changed meanwhile.
+func (s *Storage) RehashPassword(ctx context.Context, id int64, oldHash, newHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stmt := "UPDATE Users SET passwordhash = $3 WHERE id = $1 AND passwordhash = $2"
+
+	_, err := s.conn.Exec(ctx, stmt, id, oldHash, newHash)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// DeleteUser deletes the user together with their pastes and returns the IDs of the deleted pastes.
+// With anonymisePastes, public and unlisted pastes are kept with no author instead;
+// private pastes are always deleted, because nobody could read them anymore.
+// Pastes owned by an organization always stay with it, without an author.
+// If the user is the last owner of an organization, the function returns ErrLastOrgOwner.
+func (s *Storage) DeleteUser(ctx context.Context, id int64, anonymisePastes bool) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var lastOwner bool
+	stmt := `SELECT EXISTS (
+		SELECT 1 FROM OrganizationMembers m WHERE m.userid = $1 AND m.role = $2 AND NOT EXISTS (
+			SELECT 1 FROM OrganizationMembers o WHERE o.orgid = m.orgid AND o.role = $2 AND o.userid <> $1
+		)
+	)`
+	if err := tx.QueryRow(ctx, stmt, id, models.OrgRoleOwner).Scan(&lastOwner); err != nil {
+		return nil, err
+	}
+
+	if lastOwner {
+		return nil, storage.ErrLastOrgOwner
+	}
+
+	stmt = "UPDATE Pastes SET authorid = 0 WHERE authorid = $1 AND orgid IS NOT NULL"
+	if _, err := tx.Exec(ctx, stmt, id); err != nil {
+		return nil, err
+	}
+
+	if anonymisePastes {
+		stmt := "UPDATE Pastes SET authorid = 0 WHERE authorid = $1 AND visibility <> $2"
+		if _, err := tx.Exec(ctx, stmt, id, models.VisibilityPrivate); err != nil {
+			return nil, err
+		}
+	}
+
+	var ids []string
+	stmt = "DELETE FROM Pastes WHERE authorid = $1 RETURNING id"
+	if err := pgxscan.Select(ctx, tx, &ids, stmt, id); err != nil {
+		return nil, err
+	}
+
+	tag, err := tx.Exec(ctx, "DELETE FROM Users WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil, storage.ErrUserNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
@@ -2,13 +2,54 @@ package redis
 
 import (
 	"TextVault/internal/config"
+	"TextVault/internal/storage"
+	"TextVault/internal/storage/models"
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// gcraScript implements the generic cell rate algorithm. The key holds the theoretical arrival time (TAT)
+// in microseconds of Redis' clock, so every instance of the service shares one clock. A request is allowed
+// if it doesn't push the TAT more than burst intervals ahead of now.
+var gcraScript = redis.NewScript(`
+local now_parts = redis.call('TIME')
+local now = tonumber(now_parts[1]) * 1000000 + tonumber(now_parts[2])
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+
+local tat = now
+local stored = redis.call('GET', KEYS[1])
+if stored then
+	tat = math.max(tonumber(stored), now)
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - interval * burst
+
+if now < allow_at then
+	return {0, 0, allow_at - now, tat - now}
+end
+
+redis.call('SET', KEYS[1], new_tat, 'PX', math.ceil((new_tat - now) / 1000))
+
+return {1, math.floor((now - allow_at) / interval), 0, new_tat - now}
+`)
+
+// incrScript increments the counter at KEYS[1] and gives it a TTL of ARGV[1] milliseconds if it has none,
+// in one step, so a crash between the two commands can't leave a counter that never expires.
+var incrScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+
+return count
+`)
+
 type Storage struct {
 	rdb *redis.Client
 }
@@ -35,8 +76,14 @@ func (s *Storage) Set(ctx context.Context, key string, value string) error {
 	return s.rdb.Set(ctx, key, value, time.Hour*12).Err()
 }
 
+// Get returns the value at key, or storage.ErrCacheMiss if the key doesn't exist.
 func (s *Storage) Get(ctx context.Context, key string) (string, error) {
-	return s.rdb.Get(ctx, key).Result()
+	value, err := s.rdb.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", storage.ErrCacheMiss
+	}
+
+	return value, err
 }
 
 func (s *Storage) Delete(ctx context.Context, key string) error {
@@ -46,3 +93,46 @@ func (s *Storage) Delete(ctx context.Context, key string) error {
 func (s *Storage) Exists(ctx context.Context, key string) error {
 	return s.rdb.Exists(ctx, key).Err()
 }
+
+// Incr increments the counter stored at key and returns its new value.
+// The expiry is only set when the counter is created, so the counter resets ttl after its first increment.
+func (s *Storage) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrScript.Run(ctx, s.rdb, []string{key}, ttl.Milliseconds()).Int64()
+}
+
+// SetWithTTL stores the value at key for the given duration.
+func (s *Storage) SetWithTTL(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return s.rdb.Set(ctx, key, value, ttl).Err()
+}
+
+// GetDel returns the value at key and deletes it, so the value can be consumed only once.
+// It returns storage.ErrCacheMiss if the key doesn't exist.
+func (s *Storage) GetDel(ctx context.Context, key string) (string, error) {
+	value, err := s.rdb.GetDel(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", storage.ErrCacheMiss
+	}
+
+	return value, err
+}
+
+// TTL returns the remaining time to live of key. It is not positive if the key doesn't exist or doesn't expire.
+func (s *Storage) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return s.rdb.TTL(ctx, key).Result()
+}
+
+// AllowRate takes one request from the rate limit at key, which allows burst requests at once and
+// replenishes one request every interval.
+func (s *Storage) AllowRate(ctx context.Context, key string, interval time.Duration, burst int) (models.RateLimitResult, error) {
+	values, err := gcraScript.Run(ctx, s.rdb, []string{key}, interval.Microseconds(), burst).Int64Slice()
+	if err != nil {
+		return models.RateLimitResult{}, err
+	}
+
+	return models.RateLimitResult{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+		ResetAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
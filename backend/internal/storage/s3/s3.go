@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -26,12 +27,17 @@ type Storage struct {
 	S3Client   *awss3.Client
 	log        *slog.Logger
 	bucketName string
+
+	// @NOTE: The transport is kept so Close can release its pooled connections
+	transport *http.Transport
 }
 
 func New(log *slog.Logger, s3config cfg.S3Config) (*Storage, error) {
 	ctx := context.Background()
 
-	sdkConfig, err := config.LoadDefaultConfig(ctx)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	sdkConfig, err := config.LoadDefaultConfig(ctx, config.WithHTTPClient(&http.Client{Transport: transport}))
 	if err != nil {
 		return nil, err
 	}
@@ -42,9 +48,15 @@ func New(log *slog.Logger, s3config cfg.S3Config) (*Storage, error) {
 		S3Client:   s3Client,
 		log:        log,
 		bucketName: s3config.BucketName,
+		transport:  transport,
 	}, nil
 }
 
+// Close releases the idle connections to S3. Requests still in flight are not interrupted.
+func (c *Storage) Close() {
+	c.transport.CloseIdleConnections()
+}
+
 func (c *Storage) BucketExists(ctx context.Context) error {
 	_, err := c.S3Client.HeadBucket(ctx, &awss3.HeadBucketInput{
 		Bucket: aws.String(c.bucketName),
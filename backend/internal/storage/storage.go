@@ -5,6 +5,25 @@ import "errors"
 var (
 	ErrPasteNotFound      = errors.New("paste not found")
 	ErrUserNotFound       = errors.New("user not found")
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrEmailTaken         = errors.New("email is already taken")
 	ErrIncorrectPass      = errors.New("incorrect password")
 	ErrUserDontHavePastes = errors.New("user dont have pastes")
+	ErrInvalidResetToken  = errors.New("invalid or expired reset token")
+	ErrInvalidTwoFactor   = errors.New("invalid or already used two-factor code")
+	ErrTwoFactorEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrOrgNotFound        = errors.New("organization not found")
+	ErrOrgNameTaken       = errors.New("organization name is already taken")
+	ErrNotOrgMember       = errors.New("user is not a member of the organization")
+	ErrAlreadyOrgMember   = errors.New("user is already a member of the organization")
+	ErrLastOrgOwner       = errors.New("organization must keep at least one owner")
+	ErrGrantNotFound      = errors.New("grant not found")
+	ErrShareLinkNotFound  = errors.New("share link not found")
+	ErrInvalidShareLink   = errors.New("share link is invalid or expired")
+	ErrInvalidClaimToken  = errors.New("invalid or already used claim token")
+	ErrTransferNotFound   = errors.New("transfer not found")
+	ErrCacheMiss          = errors.New("key not found in cache")
+	ErrReportNotFound     = errors.New("report not found")
+	ErrAlreadyReported    = errors.New("paste is already reported")
+	ErrIdentityTaken      = errors.New("identity is already linked to an account")
 )
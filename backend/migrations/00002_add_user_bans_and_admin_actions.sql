@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE Users ADD COLUMN BanReason TEXT NOT NULL DEFAULT '';
+ALTER TABLE Users ADD COLUMN BannedUntil TIMESTAMPTZ;
+
+CREATE TABLE AdminActions (
+    ID BIGSERIAL PRIMARY KEY,
+    AdminID INTEGER NOT NULL REFERENCES Users (ID),
+    Action VARCHAR(50) NOT NULL,
+    TargetType VARCHAR(50) NOT NULL,
+    TargetID VARCHAR(100) NOT NULL,
+    Details TEXT NOT NULL DEFAULT '',
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_admin_action_admin_id ON AdminActions (AdminID);
+CREATE INDEX idx_admin_action_target ON AdminActions (TargetType, TargetID);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_admin_action_target;
+DROP INDEX IF EXISTS idx_admin_action_admin_id;
+DROP TABLE IF EXISTS AdminActions;
+
+ALTER TABLE Users DROP COLUMN IF EXISTS BannedUntil;
+ALTER TABLE Users DROP COLUMN IF EXISTS BanReason;
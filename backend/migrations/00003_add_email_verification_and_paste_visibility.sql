@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE Users ADD COLUMN IsVerified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- @NOTE: Accounts created before email verification existed are trusted
+UPDATE Users SET IsVerified = TRUE;
+
+ALTER TABLE Pastes ADD COLUMN Visibility VARCHAR(10) NOT NULL DEFAULT 'public';
+
+-- +goose Down
+ALTER TABLE Pastes DROP COLUMN IF EXISTS Visibility;
+ALTER TABLE Users DROP COLUMN IF EXISTS IsVerified;
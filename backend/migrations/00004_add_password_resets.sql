@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE Users ADD COLUMN TokenVersion INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE PasswordResets (
+    ID BIGSERIAL PRIMARY KEY,
+    UserID INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+    TokenHash CHAR(64) UNIQUE NOT NULL,
+    ExpiresAt TIMESTAMPTZ NOT NULL,
+    UsedAt TIMESTAMPTZ,
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_password_reset_user_id ON PasswordResets (UserID);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_password_reset_user_id;
+DROP TABLE IF EXISTS PasswordResets;
+
+ALTER TABLE Users DROP COLUMN IF EXISTS TokenVersion;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- @NOTE: Admin actions must outlive the admin account that performed them
+ALTER TABLE AdminActions DROP CONSTRAINT IF EXISTS adminactions_adminid_fkey;
+
+-- +goose Down
+ALTER TABLE AdminActions ADD CONSTRAINT adminactions_adminid_fkey FOREIGN KEY (AdminID) REFERENCES Users (ID);
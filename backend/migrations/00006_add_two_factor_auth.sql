@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE Users ADD COLUMN TOTPSecret VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE Users ADD COLUMN TOTPEnabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE Users ADD COLUMN TOTPLastStep BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE RecoveryCodes (
+    ID BIGSERIAL PRIMARY KEY,
+    UserID INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+    CodeHash CHAR(64) NOT NULL,
+    UsedAt TIMESTAMPTZ,
+
+    UNIQUE (UserID, CodeHash)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS RecoveryCodes;
+
+ALTER TABLE Users DROP COLUMN IF EXISTS TOTPLastStep;
+ALTER TABLE Users DROP COLUMN IF EXISTS TOTPEnabled;
+ALTER TABLE Users DROP COLUMN IF EXISTS TOTPSecret;
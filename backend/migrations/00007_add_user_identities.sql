@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE user_identities (
+    ID BIGSERIAL PRIMARY KEY,
+    UserID INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+    Provider VARCHAR(50) NOT NULL,
+    Subject VARCHAR(255) NOT NULL,
+    Email VARCHAR(100) NOT NULL DEFAULT '',
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    UNIQUE (Provider, Subject)
+);
+
+CREATE INDEX idx_user_identity_user_id ON user_identities (UserID);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_user_identity_user_id;
+DROP TABLE IF EXISTS user_identities;
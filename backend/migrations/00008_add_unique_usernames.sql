@@ -0,0 +1,8 @@
+-- +goose Up
+-- @NOTE: Usernames and emails are unique regardless of case, as logins match them case-insensitively
+CREATE UNIQUE INDEX users_username_lower_key ON Users (LOWER(Username));
+CREATE UNIQUE INDEX users_email_lower_key ON Users (LOWER(Email));
+
+-- +goose Down
+DROP INDEX IF EXISTS users_email_lower_key;
+DROP INDEX IF EXISTS users_username_lower_key;
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE Organizations (
+    ID SERIAL PRIMARY KEY,
+    Name VARCHAR(50) NOT NULL,
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX organizations_name_lower_key ON Organizations (LOWER(Name));
+
+CREATE TABLE OrganizationMembers (
+    OrgID INTEGER NOT NULL REFERENCES Organizations (ID) ON DELETE CASCADE,
+    UserID INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+    Role VARCHAR(20) NOT NULL,
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (OrgID, UserID)
+);
+
+CREATE INDEX idx_organization_member_user_id ON OrganizationMembers (UserID);
+
+-- @NOTE: A paste with an OrgID is owned by the organization; AuthorID still names who created it
+ALTER TABLE Pastes ADD COLUMN OrgID INTEGER REFERENCES Organizations (ID) ON DELETE CASCADE;
+
+CREATE INDEX idx_paste_org_id ON Pastes (OrgID);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_paste_org_id;
+ALTER TABLE Pastes DROP COLUMN IF EXISTS OrgID;
+
+DROP INDEX IF EXISTS idx_organization_member_user_id;
+DROP TABLE IF EXISTS OrganizationMembers;
+
+DROP INDEX IF EXISTS organizations_name_lower_key;
+DROP TABLE IF EXISTS Organizations;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE paste_grants (
+    ID BIGSERIAL PRIMARY KEY,
+    PasteID UUID NOT NULL REFERENCES Pastes (ID) ON DELETE CASCADE,
+    UserID INTEGER REFERENCES Users (ID) ON DELETE CASCADE,
+    OrgID INTEGER REFERENCES Organizations (ID) ON DELETE CASCADE,
+    Access VARCHAR(10) NOT NULL,
+    GrantedBy INTEGER NOT NULL,
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    -- @NOTE: A grant is for exactly one user or one organization
+    CHECK ((UserID IS NULL) <> (OrgID IS NULL))
+);
+
+CREATE UNIQUE INDEX paste_grants_user_key ON paste_grants (PasteID, UserID) WHERE UserID IS NOT NULL;
+CREATE UNIQUE INDEX paste_grants_org_key ON paste_grants (PasteID, OrgID) WHERE OrgID IS NOT NULL;
+CREATE INDEX idx_paste_grant_user_id ON paste_grants (UserID);
+CREATE INDEX idx_paste_grant_org_id ON paste_grants (OrgID);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_paste_grant_org_id;
+DROP INDEX IF EXISTS idx_paste_grant_user_id;
+DROP INDEX IF EXISTS paste_grants_org_key;
+DROP INDEX IF EXISTS paste_grants_user_key;
+DROP TABLE IF EXISTS paste_grants;
@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE share_links (
+    ID UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    PasteID UUID NOT NULL REFERENCES Pastes (ID) ON DELETE CASCADE,
+    CreatedBy INTEGER NOT NULL,
+    ExpiresAt TIMESTAMPTZ NOT NULL,
+    MaxViews INTEGER,
+    Views INTEGER NOT NULL DEFAULT 0,
+    RevokedAt TIMESTAMPTZ,
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_share_link_paste_id ON share_links (PasteID);
+
+CREATE TABLE share_link_uses (
+    ID BIGSERIAL PRIMARY KEY,
+    LinkID UUID NOT NULL REFERENCES share_links (ID) ON DELETE CASCADE,
+    IP VARCHAR(45) NOT NULL,
+    UserAgent VARCHAR(255) NOT NULL DEFAULT '',
+    UsedAt TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_share_link_use_link_id ON share_link_uses (LinkID);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_share_link_use_link_id;
+DROP TABLE IF EXISTS share_link_uses;
+DROP INDEX IF EXISTS idx_share_link_paste_id;
+DROP TABLE IF EXISTS share_links;
@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE paste_claims (
+    PasteID UUID PRIMARY KEY REFERENCES Pastes (ID) ON DELETE CASCADE,
+    TokenHash VARCHAR(64) NOT NULL,
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE paste_transfers (
+    ID BIGSERIAL PRIMARY KEY,
+    PasteID UUID NOT NULL UNIQUE REFERENCES Pastes (ID) ON DELETE CASCADE,
+    FromUserID INTEGER NOT NULL REFERENCES Users (ID) ON DELETE CASCADE,
+    ToUserID INTEGER REFERENCES Users (ID) ON DELETE CASCADE,
+    ToOrgID INTEGER REFERENCES Organizations (ID) ON DELETE CASCADE,
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    -- @NOTE: A paste is transferred to exactly one user or one organization
+    CHECK ((ToUserID IS NULL) <> (ToOrgID IS NULL))
+);
+
+CREATE INDEX idx_paste_transfer_to_user_id ON paste_transfers (ToUserID);
+CREATE INDEX idx_paste_transfer_to_org_id ON paste_transfers (ToOrgID);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_paste_transfer_to_org_id;
+DROP INDEX IF EXISTS idx_paste_transfer_to_user_id;
+DROP TABLE IF EXISTS paste_transfers;
+DROP TABLE IF EXISTS paste_claims;
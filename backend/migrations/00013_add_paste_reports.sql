@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE Pastes ADD COLUMN HiddenAt TIMESTAMPTZ;
+
+CREATE TABLE paste_reports (
+    ID BIGSERIAL PRIMARY KEY,
+    PasteID UUID NOT NULL REFERENCES Pastes (ID) ON DELETE CASCADE,
+    ReporterID INTEGER REFERENCES Users (ID) ON DELETE SET NULL,
+    -- @NOTE: "user:<id>" or "ip:<ip>", so every user or anonymous client reports a paste at most once
+    Reporter VARCHAR(64) NOT NULL,
+    Category VARCHAR(32) NOT NULL,
+    Details TEXT NOT NULL DEFAULT '',
+    Status VARCHAR(16) NOT NULL DEFAULT 'open',
+    ResolvedBy INTEGER REFERENCES Users (ID) ON DELETE SET NULL,
+    ResolvedAt TIMESTAMPTZ,
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    UNIQUE (PasteID, Reporter)
+);
+
+CREATE INDEX idx_paste_report_status ON paste_reports (Status, CreatedAt);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_paste_report_status;
+DROP TABLE IF EXISTS paste_reports;
+ALTER TABLE Pastes DROP COLUMN IF EXISTS HiddenAt;
@@ -0,0 +1,43 @@
+-- +goose Up
+-- @NOTE: Like AdminActions, audit events have no foreign keys, so they outlive the users and pastes they name
+CREATE TABLE audit_events (
+    ID BIGSERIAL PRIMARY KEY,
+    Action VARCHAR(64) NOT NULL,
+    Outcome VARCHAR(16) NOT NULL,
+    ActorID INTEGER,
+    TargetType VARCHAR(32) NOT NULL DEFAULT '',
+    TargetID VARCHAR(100) NOT NULL DEFAULT '',
+    IP VARCHAR(64) NOT NULL DEFAULT '',
+    UserAgent VARCHAR(512) NOT NULL DEFAULT '',
+    Details TEXT NOT NULL DEFAULT '',
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_event_created_at ON audit_events (CreatedAt);
+CREATE INDEX idx_audit_event_actor_id ON audit_events (ActorID, CreatedAt);
+CREATE INDEX idx_audit_event_target ON audit_events (TargetType, TargetID, CreatedAt);
+
+-- +goose StatementBegin
+CREATE FUNCTION reject_audit_event_change() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION reject_audit_event_change();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION reject_audit_event_change();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events;
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+DROP FUNCTION IF EXISTS reject_audit_event_change();
+DROP INDEX IF EXISTS idx_audit_event_target;
+DROP INDEX IF EXISTS idx_audit_event_actor_id;
+DROP INDEX IF EXISTS idx_audit_event_created_at;
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- @NOTE: Reports must outlive the paste they report, so the moderation history survives its deletion
+ALTER TABLE paste_reports DROP CONSTRAINT IF EXISTS paste_reports_pasteid_fkey;
+
+-- +goose Down
+DELETE FROM paste_reports WHERE PasteID NOT IN (SELECT ID FROM Pastes);
+ALTER TABLE paste_reports ADD CONSTRAINT paste_reports_pasteid_fkey FOREIGN KEY (PasteID) REFERENCES Pastes (ID) ON DELETE CASCADE;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- @NOTE: Accounts provisioned by an OpenID Connect provider got a random password nobody knows.
+-- An empty hash marks them as password-less, so they confirm sensitive actions another way.
+-- Identities could only be created by provisioning until now, so every linked account is such an account.
+-- Changing a password required the current one, so the random password could only be replaced through
+-- a password reset. Reset tokens are never deleted, so accounts with a used one keep their password.
+UPDATE Users SET PasswordHash = ''
+WHERE ID IN (SELECT UserID FROM user_identities)
+  AND NOT EXISTS (SELECT 1 FROM PasswordResets WHERE PasswordResets.UserID = Users.ID AND PasswordResets.UsedAt IS NOT NULL);
+
+-- +goose Down
+-- @NOTE: The random passwords were never usable, so password-less accounts are left as they are
@@ -0,0 +1,130 @@
+package passwordhash
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams are cheap argon2id parameters, the tests don't need slow hashes.
+var testParams = Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func mustHash(t *testing.T, h *Hasher, password string) string {
+	t.Helper()
+
+	hash, err := h.Hash(password)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	return hash
+}
+
+func TestHashFormat(t *testing.T) {
+	h := NewHasher(testParams)
+
+	hash := mustHash(t, h, "correct horse battery")
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("Hash() = %q, want a PHC argon2id hash with the parameters", hash)
+	}
+
+	if other := mustHash(t, h, "correct horse battery"); other == hash {
+		t.Error("Hash() returns the same hash twice, want a random salt")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	h := NewHasher(testParams)
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("correct horse battery"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+	}{
+		{name: "argon2id", password: "correct horse battery", hash: mustHash(t, h, "correct horse battery"), want: true},
+		{name: "argon2id wrong password", password: "correct horse battery!", hash: mustHash(t, h, "correct horse battery"), want: false},
+		{name: "argon2id of other parameters", password: "correct horse battery", hash: mustHash(t, NewHasher(Params{Memory: 128, Iterations: 2, Parallelism: 2, SaltLength: 8, KeyLength: 16}), "correct horse battery"), want: true},
+		{name: "bcrypt", password: "correct horse battery", hash: string(bcryptHash), want: true},
+		{name: "bcrypt wrong password", password: "correct horse", hash: string(bcryptHash), want: false},
+		{name: "empty hash", password: "", hash: "", want: false},
+		{name: "garbage", password: "correct horse battery", hash: "not a hash", want: false},
+		{name: "truncated argon2id", password: "correct horse battery", hash: "$argon2id$v=19$m=64,t=1,p=1$c2FsdA", want: false},
+		{name: "other argon2 version", password: "correct horse battery", hash: strings.Replace(mustHash(t, h, "correct horse battery"), "v=19", "v=16", 1), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.Validate(tt.password, tt.hash); got != tt.want {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	h := NewHasher(testParams)
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("correct horse battery"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	with := func(change func(*Params)) string {
+		params := testParams
+		change(&params)
+
+		return mustHash(t, NewHasher(params), "correct horse battery")
+	}
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{name: "current", hash: mustHash(t, h, "correct horse battery"), want: false},
+		{name: "bcrypt", hash: string(bcryptHash), want: true},
+		{name: "memory", hash: with(func(p *Params) { p.Memory = 128 }), want: true},
+		{name: "iterations", hash: with(func(p *Params) { p.Iterations = 2 }), want: true},
+		{name: "parallelism", hash: with(func(p *Params) { p.Parallelism = 2 }), want: true},
+		{name: "salt length", hash: with(func(p *Params) { p.SaltLength = 8 }), want: true},
+		{name: "key length", hash: with(func(p *Params) { p.KeyLength = 16 }), want: true},
+		{name: "malformed", hash: "$argon2id$broken", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	previous := defaultHasher.Load()
+	t.Cleanup(func() { SetDefault(previous) })
+
+	SetDefault(NewHasher(testParams))
+
+	hash, err := New("correct horse battery")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if !Validate("correct horse battery", hash) || NeedsRehash(hash) {
+		t.Errorf("hash %q of the default hasher doesn't validate or needs a rehash", hash)
+	}
+
+	SetDefault(NewHasher(Params{Memory: 128, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}))
+
+	if !Validate("correct horse battery", hash) || !NeedsRehash(hash) {
+		t.Errorf("hash %q of the previous default hasher doesn't validate or doesn't need a rehash", hash)
+	}
+}
@@ -0,0 +1,123 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors, "12345678901234567890", base32-encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateRFC6238Vectors(t *testing.T) {
+	// @NOTE: The RFC lists 8-digit codes, these are their last 6 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			step, ok := Validate(tt.code, rfcSecret, time.Unix(tt.unix, 0))
+			if !ok {
+				t.Fatalf("Validate(%q) at %d = false, want true", tt.code, tt.unix)
+			}
+			if want := tt.unix / period; step != want {
+				t.Errorf("Validate(%q) step = %d, want %d", tt.code, step, want)
+			}
+		})
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	key, err := encoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / period
+
+	tests := []struct {
+		name  string
+		steps int64
+		want  bool
+	}{
+		{name: "two periods early", steps: -2, want: false},
+		{name: "previous period", steps: -1, want: true},
+		{name: "current period", steps: 0, want: true},
+		{name: "next period", steps: 1, want: true},
+		{name: "two periods late", steps: 2, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(generate(key, current+tt.steps), rfcSecret, now)
+			if ok != tt.want {
+				t.Fatalf("Validate() = %v, want %v", ok, tt.want)
+			}
+			if ok && step != current+tt.steps {
+				t.Errorf("Validate() step = %d, want %d", step, current+tt.steps)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	tests := []struct {
+		name   string
+		code   string
+		secret string
+		want   bool
+	}{
+		{name: "lowercase secret", code: "287082", secret: strings.ToLower(rfcSecret), want: true},
+		{name: "wrong code", code: "287083", secret: rfcSecret, want: false},
+		{name: "8 digits", code: "94287082", secret: rfcSecret, want: false},
+		{name: "empty code", code: "", secret: rfcSecret, want: false},
+		{name: "invalid secret", code: "287082", secret: "not base32!", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.code, tt.secret, now); ok != tt.want {
+				t.Errorf("Validate(%q, %q) = %v, want %v", tt.code, tt.secret, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSecretRoundTrip(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil || len(key) != secretSize {
+		t.Fatalf("GenerateSecret() = %q, want %d base32-encoded bytes", secret, secretSize)
+	}
+
+	now := time.Now()
+	if _, ok := Validate(generate(key, now.Unix()/period), secret, now); !ok {
+		t.Error("Validate() rejects the current code of a generated secret")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("TextVault", "alice@example.com", rfcSecret)
+
+	for _, want := range []string{"otpauth://totp/TextVault:alice@example.com?", "secret=" + rfcSecret, "issuer=TextVault", "digits=6", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("URI() = %q, want it to contain %q", uri, want)
+		}
+	}
+}